@@ -5,6 +5,7 @@ import (
 	"html/template"
 	"log/slog"
 	"net/http"
+	"slices"
 	"sync"
 
 	"snakelsp/internal/workspace"
@@ -32,12 +33,18 @@ func loadTemplates() {
 	})
 }
 
-func StartHTTPServer(addr string) {
+// StartHTTPServer serves the files and symbols of the workspace current
+// returns, nil until the client initializes the server.
+func StartHTTPServer(addr string, current func() *workspace.Workspace) {
 	loadTemplates()
 
 	// Routes
-	http.HandleFunc("/", handleHome)
-	http.HandleFunc("/file", handleFile)
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		handleHome(w, r, current())
+	})
+	http.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		handleFile(w, r, current())
+	})
 
 	err := http.ListenAndServe(addr, nil)
 	if err != nil {
@@ -45,10 +52,14 @@ func StartHTTPServer(addr string) {
 	}
 }
 
-func handleHome(w http.ResponseWriter, r *http.Request) {
+func handleHome(w http.ResponseWriter, r *http.Request, ws *workspace.Workspace) {
 	var files []*workspace.PythonFile
 
-	workspace.ProjectFiles.Range(func(_, value any) bool {
+	if ws == nil {
+		http.Error(w, "server not initialized", http.StatusServiceUnavailable)
+		return
+	}
+	ws.Files.Range(func(_, value any) bool {
 		if file, ok := value.(*workspace.PythonFile); ok {
 			files = append(files, file)
 		}
@@ -62,15 +73,19 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func handleFile(w http.ResponseWriter, r *http.Request) {
+func handleFile(w http.ResponseWriter, r *http.Request, ws *workspace.Workspace) {
 	url := r.URL.Query().Get("url")
 	if url == "" {
 		http.Error(w, "missing 'url' param", http.StatusBadRequest)
 		return
 	}
+	if ws == nil {
+		http.Error(w, "server not initialized", http.StatusServiceUnavailable)
+		return
+	}
 
 	var pythonFile *workspace.PythonFile
-	workspace.ProjectFiles.Range(func(_, value interface{}) bool {
+	ws.Files.Range(func(_, value interface{}) bool {
 		file := value.(*workspace.PythonFile)
 		if file.Url == url {
 			pythonFile = file
@@ -85,7 +100,7 @@ func handleFile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var flatSymbols []RenderSymbol
-	for _, s := range workspace.FlatSymbols.AllFromBack() {
+	for _, s := range slices.Backward(ws.ProjectSymbols()) {
 		if s.File.Url == pythonFile.Url {
 			flatSymbols = append(flatSymbols, RenderSymbol{
 				Symbol: s,
@@ -95,7 +110,7 @@ func handleFile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var projectSymbols []RenderSymbol
-	fileSymbols, exists := workspace.WorkspaceSymbols.Load(pythonFile)
+	fileSymbols, exists := ws.Symbols.Load(pythonFile)
 	if exists {
 
 		fs, ok := fileSymbols.([]*workspace.Symbol)
@@ -109,6 +124,7 @@ func handleFile(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	imports, _ := pythonFile.GetImports()
 	templates.ExecuteTemplate(w, "file.html", struct {
 		File           *workspace.PythonFile
 		SymbolsFlat    []RenderSymbol
@@ -118,6 +134,6 @@ func handleFile(w http.ResponseWriter, r *http.Request) {
 		File:           pythonFile,
 		SymbolsFlat:    flatSymbols,
 		ProjectSymbols: projectSymbols,
-		Imports:        pythonFile.Imports,
+		Imports:        imports,
 	})
 }
@@ -5,15 +5,15 @@ go 1.24
 toolchain go1.24.3
 
 require (
-	github.com/lithammer/fuzzysearch v1.1.8
+	github.com/BurntSushi/toml v1.5.0
+	github.com/fatih/color v1.18.0
+	github.com/getsentry/sentry-go v0.34.0
 	github.com/sourcegraph/jsonrpc2 v0.2.0
 	github.com/tree-sitter/go-tree-sitter v0.25.0
 	github.com/tree-sitter/tree-sitter-python v0.23.6
 )
 
 require (
-	github.com/fatih/color v1.18.0 // indirect
-	github.com/getsentry/sentry-go v0.34.0 // indirect
 	github.com/goforj/godump v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
@@ -24,7 +24,7 @@ require (
 	github.com/elliotchance/orderedmap/v3 v3.1.0
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/gdamore/tcell/v2 v2.6.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
+	github.com/google/uuid v1.6.0
 	github.com/ktr0731/go-ansisgr v0.1.0 // indirect
 	github.com/ktr0731/go-fuzzyfinder v0.8.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
@@ -34,7 +34,7 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.4.3 // indirect
-	github.com/stretchr/testify v1.10.0 // indirect
+	github.com/stretchr/testify v1.10.0
 	golang.org/x/sys v0.25.0 // indirect
 	golang.org/x/term v0.5.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
@@ -38,6 +38,38 @@ func (t *IntegerOrString) UnmarshalJSON(data []byte) error {
 	}
 }
 
+/**
+ * A type indicating how positions are encoded,
+ * specifically what column offsets mean.
+ *
+ * @since 3.17.0
+ */
+type PositionEncodingKind string
+
+const (
+	/**
+	 * Character offsets count UTF-8 code units (e.g bytes).
+	 */
+	PositionEncodingKindUTF8 PositionEncodingKind = "utf-8"
+
+	/**
+	 * Character offsets count UTF-16 code units.
+	 *
+	 * This is the default and must always be supported
+	 * by servers
+	 */
+	PositionEncodingKindUTF16 PositionEncodingKind = "utf-16"
+
+	/**
+	 * Character offsets count UTF-32 code units.
+	 *
+	 * Implementation note: these are the same as Unicode code points,
+	 * so this `PositionEncodingKind` may also be used for an
+	 * encoding-agnostic representation of character offsets.
+	 */
+	PositionEncodingKindUTF32 PositionEncodingKind = "utf-32"
+)
+
 type Position struct {
 	/**
 	 * Line position in a document (zero-based).
@@ -67,6 +99,27 @@ type Range struct {
 }
 type ProgressToken = IntegerOrString
 
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-16#cancelRequest
+
+type CancelParams struct {
+	/**
+	 * The request id to cancel.
+	 */
+	ID IntegerOrString `json:"id"`
+}
+
+/**
+ * The client has canceled a request and a server has detected
+ * the cancel.
+ */
+const ErrorCodeRequestCancelled = -32800
+
+/**
+ * Error code indicating that a server received a notification or
+ * request before the server has received the `initialize` request.
+ */
+const ErrorCodeServerNotInitialized = -32002
+
 type WorkDoneProgressParams struct {
 	/**
 	 * An optional token that a server can use to report work done progress.
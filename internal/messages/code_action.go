@@ -0,0 +1,140 @@
+package messages
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17#textEdit
+
+type TextEdit struct {
+	/**
+	 * The range of the text document to be manipulated. To insert
+	 * text into a document create a range where start === end.
+	 */
+	Range Range `json:"range"`
+
+	/**
+	 * The string to be inserted. For delete operations use an
+	 * empty string.
+	 */
+	NewText string `json:"newText"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17#workspaceEdit
+
+type WorkspaceEdit struct {
+	/**
+	 * Holds changes to existing resources.
+	 */
+	Changes map[DocumentUri][]TextEdit `json:"changes,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17#textDocument_codeAction
+
+type CodeActionKind string
+
+const (
+	/**
+	 * Base kind for quickfix actions: 'quickfix'.
+	 */
+	CodeActionKindQuickFix = CodeActionKind("quickfix")
+
+	/**
+	 * Base kind for source actions: `source`.
+	 *
+	 * Source code actions apply to the entire file.
+	 */
+	CodeActionKindSource = CodeActionKind("source")
+
+	/**
+	 * Base kind for an organize imports source action:
+	 * `source.organizeImports`.
+	 */
+	CodeActionKindSourceOrganizeImports = CodeActionKind("source.organizeImports")
+)
+
+/**
+ * Contains additional diagnostic information about the context in which
+ * a code action is run.
+ */
+type CodeActionContext struct {
+	/**
+	 * An array of diagnostics known on the client side overlapping the range
+	 * provided to the `textDocument/codeAction` request. They are provided so
+	 * that the server knows which errors are currently presented to the user
+	 * for the given range. There is no guarantee that these accurately reflect
+	 * the error state of the resource. The primary parameter
+	 * to compute code actions is the provided range.
+	 */
+	Diagnostics []Diagnostic `json:"diagnostics"`
+
+	/**
+	 * Requested kind of actions to return.
+	 *
+	 * Actions not of this kind are filtered out by the client before being
+	 * shown. So servers can omit computing them.
+	 */
+	Only []CodeActionKind `json:"only,omitempty"`
+}
+
+type CodeActionParams struct {
+	WorkDoneProgressParams
+	PartialResultParams
+
+	/**
+	 * The document in which the command was invoked.
+	 */
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+
+	/**
+	 * The range for which the command was invoked.
+	 */
+	Range Range `json:"range"`
+
+	/**
+	 * Context carrying additional information.
+	 */
+	Context CodeActionContext `json:"context"`
+}
+
+/**
+ * A code action represents a change that can be performed in code, e.g. to fix
+ * a problem or to refactor code.
+ */
+type CodeAction struct {
+	/**
+	 * A short, human-readable, title for this code action.
+	 */
+	Title string `json:"title"`
+
+	/**
+	 * The kind of the code action.
+	 *
+	 * Used to filter code actions.
+	 */
+	Kind CodeActionKind `json:"kind,omitempty"`
+
+	/**
+	 * The diagnostics that this code action resolves.
+	 */
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+
+	/**
+	 * Marks this as a preferred action. Preferred actions are used by the
+	 * `auto fix` command and can be targeted by keybindings.
+	 *
+	 * @since 3.15.0
+	 */
+	IsPreferred bool `json:"isPreferred,omitempty"`
+
+	/**
+	 * The workspace edit this code action performs.
+	 */
+	Edit *WorkspaceEdit `json:"edit,omitempty"`
+}
+
+type CodeActionOptions struct {
+	/**
+	 * CodeActionKinds that this server may return.
+	 *
+	 * The list of kinds may be generic, such as `CodeActionKind.Refactor`,
+	 * or the server may list out every specific kind they provide.
+	 */
+	CodeActionKinds []CodeActionKind `json:"codeActionKinds,omitempty"`
+}
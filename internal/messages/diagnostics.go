@@ -0,0 +1,361 @@
+package messages
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17#diagnostic
+
+type DiagnosticSeverity Integer
+
+const (
+	/**
+	 * Reports an error.
+	 */
+	DiagnosticSeverityError = DiagnosticSeverity(1)
+
+	/**
+	 * Reports a warning.
+	 */
+	DiagnosticSeverityWarning = DiagnosticSeverity(2)
+
+	/**
+	 * Reports an information.
+	 */
+	DiagnosticSeverityInformation = DiagnosticSeverity(3)
+
+	/**
+	 * Reports a hint.
+	 */
+	DiagnosticSeverityHint = DiagnosticSeverity(4)
+)
+
+/**
+ * The diagnostic tags.
+ *
+ * @since 3.15.0
+ */
+type DiagnosticTag Integer
+
+const (
+	/**
+	 * Unused or unnecessary code.
+	 *
+	 * Clients are allowed to render diagnostics with this tag faded out
+	 * instead of having an error squiggle.
+	 */
+	DiagnosticTagUnnecessary = DiagnosticTag(1)
+
+	/**
+	 * Deprecated or obsolete code.
+	 *
+	 * Clients are allowed to rendered diagnostics with this tag strike through.
+	 */
+	DiagnosticTagDeprecated = DiagnosticTag(2)
+)
+
+/**
+ * Represents a related message and source code location for a diagnostic.
+ * This should be used to point to code locations that cause or are related to
+ * a diagnostics, e.g when duplicating a symbol in a scope.
+ */
+type DiagnosticRelatedInformation struct {
+	/**
+	 * The location of this related diagnostic information.
+	 */
+	Location Location `json:"location"`
+
+	/**
+	 * The message of this related diagnostic information.
+	 */
+	Message string `json:"message"`
+}
+
+type Diagnostic struct {
+	/**
+	 * The range at which the message applies.
+	 */
+	Range Range `json:"range"`
+
+	/**
+	 * The diagnostic's severity. Can be omitted. If omitted it is up to the
+	 * client to interpret diagnostics as error, warning, info or hint.
+	 */
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+
+	/**
+	 * The diagnostic's code, which might appear in the user interface.
+	 */
+	Code string `json:"code,omitempty"`
+
+	/**
+	 * A human-readable string describing the source of this
+	 * diagnostic, e.g. 'typescript' or 'super lint'.
+	 */
+	Source string `json:"source,omitempty"`
+
+	/**
+	 * The diagnostic's message.
+	 */
+	Message string `json:"message"`
+
+	/**
+	 * Additional metadata about the diagnostic.
+	 *
+	 * @since 3.15.0
+	 */
+	Tags []DiagnosticTag `json:"tags,omitempty"`
+
+	/**
+	 * An array of related diagnostic information, e.g. when symbol-names within
+	 * a scope collide all definitions can be marked via this property.
+	 */
+	RelatedInformation []DiagnosticRelatedInformation `json:"relatedInformation,omitempty"`
+
+	/**
+	 * A data entry field that is preserved between a
+	 * `textDocument/publishDiagnostics` notification and
+	 * `textDocument/codeAction` request.
+	 *
+	 * @since 3.16.0
+	 */
+	Data any `json:"data,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17#textDocument_publishDiagnostics
+
+type PublishDiagnosticsParams struct {
+	/**
+	 * The URI for which diagnostic information is reported.
+	 */
+	URI DocumentUri `json:"uri"`
+
+	/**
+	 * Optional the version number of the document the diagnostics are published
+	 * for.
+	 *
+	 * @since 3.15.0
+	 */
+	Version *Integer `json:"version,omitempty"`
+
+	/**
+	 * An array of diagnostic information items.
+	 */
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17#textDocument_pullDiagnostics
+
+/**
+ * Client capabilities specific to diagnostic pull requests.
+ *
+ * @since 3.17.0
+ */
+type DiagnosticClientCapabilities struct {
+	/**
+	 * Whether implementation supports dynamic registration. If this is set to
+	 * `true` the client supports the new
+	 * `(TextDocumentRegistrationOptions & StaticRegistrationOptions)`
+	 * return value for the corresponding server capability as well.
+	 */
+	DynamicRegistration *bool `json:"dynamicRegistration,omitempty"`
+
+	/**
+	 * Whether the clients supports related documents for document diagnostic
+	 * pulls.
+	 */
+	RelatedDocumentSupport *bool `json:"relatedDocumentSupport,omitempty"`
+}
+
+/**
+ * Workspace client capabilities specific to diagnostic pull requests.
+ *
+ * @since 3.17.0
+ */
+type DiagnosticWorkspaceClientCapabilities struct {
+	/**
+	 * Whether the client implementation supports a refresh request sent from
+	 * the server to the client.
+	 *
+	 * Note that this event is global and will force the client to refresh all
+	 * pulled diagnostics currently shown. It should be used with absolute care
+	 * and is useful for situation where a server for example detects a project
+	 * wide change that requires such a calculation.
+	 */
+	RefreshSupport *bool `json:"refreshSupport,omitempty"`
+}
+
+/**
+ * Diagnostic options.
+ *
+ * @since 3.17.0
+ */
+type DiagnosticOptions struct {
+	/**
+	 * An optional identifier under which the diagnostics are
+	 * managed by the client.
+	 */
+	Identifier string `json:"identifier,omitempty"`
+
+	/**
+	 * Whether the language has inter file dependencies meaning that
+	 * editing code in one file can result in a different diagnostic
+	 * set in another file. Inter file dependencies are common for
+	 * most programming languages and typically uncommon for linters.
+	 */
+	InterFileDependencies bool `json:"interFileDependencies"`
+
+	/**
+	 * The server provides support for workspace diagnostics as well.
+	 */
+	WorkspaceDiagnostics bool `json:"workspaceDiagnostics"`
+}
+
+type DocumentDiagnosticParams struct {
+	WorkDoneProgressParams
+	PartialResultParams
+
+	/**
+	 * The text document.
+	 */
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+
+	/**
+	 * The additional identifier  provided during registration.
+	 */
+	Identifier *string `json:"identifier,omitempty"`
+
+	/**
+	 * The result id of a previous response if provided.
+	 */
+	PreviousResultID *string `json:"previousResultId,omitempty"`
+}
+
+type DocumentDiagnosticReportKind string
+
+const (
+	/**
+	 * A diagnostic report with a full
+	 * set of problems.
+	 */
+	DocumentDiagnosticReportKindFull = DocumentDiagnosticReportKind("full")
+
+	/**
+	 * A report indicating that the last
+	 * returned report is still accurate.
+	 */
+	DocumentDiagnosticReportKindUnchanged = DocumentDiagnosticReportKind("unchanged")
+)
+
+/**
+ * A diagnostic report with a full set of problems.
+ */
+type FullDocumentDiagnosticReport struct {
+	/**
+	 * A full document diagnostic report.
+	 */
+	Kind DocumentDiagnosticReportKind `json:"kind"`
+
+	/**
+	 * An optional result id. If provided it will
+	 * be sent on the next diagnostic request for the
+	 * same document.
+	 */
+	ResultID string `json:"resultId,omitempty"`
+
+	/**
+	 * The actual items.
+	 */
+	Items []Diagnostic `json:"items"`
+}
+
+/**
+ * A diagnostic report indicating that the last returned
+ * report is still accurate.
+ */
+type UnchangedDocumentDiagnosticReport struct {
+	/**
+	 * A document diagnostic report indicating
+	 * no changes to the last result. A server can
+	 * only return `unchanged` if result ids are
+	 * provided.
+	 */
+	Kind DocumentDiagnosticReportKind `json:"kind"`
+
+	/**
+	 * A result id which will be sent on the next
+	 * diagnostic request for the same document.
+	 */
+	ResultID string `json:"resultId"`
+}
+
+/**
+ * A previous result id in a workspace pull request.
+ */
+type PreviousResultID struct {
+	/**
+	 * The URI for which the client knows a
+	 * result id.
+	 */
+	URI DocumentUri `json:"uri"`
+
+	/**
+	 * The value of the previous result id.
+	 */
+	Value string `json:"value"`
+}
+
+type WorkspaceDiagnosticParams struct {
+	WorkDoneProgressParams
+	PartialResultParams
+
+	/**
+	 * The additional identifier provided during registration.
+	 */
+	Identifier *string `json:"identifier,omitempty"`
+
+	/**
+	 * The currently known diagnostic reports with their
+	 * previous result ids.
+	 */
+	PreviousResultIDs []PreviousResultID `json:"previousResultIds"`
+}
+
+/**
+ * A full document diagnostic report for a workspace diagnostic result.
+ */
+type WorkspaceFullDocumentDiagnosticReport struct {
+	FullDocumentDiagnosticReport
+
+	/**
+	 * The URI for which diagnostic information is reported.
+	 */
+	URI DocumentUri `json:"uri"`
+
+	/**
+	 * The version number for which the diagnostics are reported.
+	 * If the document is not marked as open `null` can be provided.
+	 */
+	Version *Integer `json:"version"`
+}
+
+/**
+ * An unchanged document diagnostic report for a workspace diagnostic result.
+ */
+type WorkspaceUnchangedDocumentDiagnosticReport struct {
+	UnchangedDocumentDiagnosticReport
+
+	/**
+	 * The URI for which diagnostic information is reported.
+	 */
+	URI DocumentUri `json:"uri"`
+
+	/**
+	 * The version number for which the diagnostics are reported.
+	 * If the document is not marked as open `null` can be provided.
+	 */
+	Version *Integer `json:"version"`
+}
+
+/**
+ * A workspace diagnostic report, also used for partial results.
+ */
+type WorkspaceDiagnosticReport struct {
+	Items []any `json:"items"` // WorkspaceFullDocumentDiagnosticReport | WorkspaceUnchangedDocumentDiagnosticReport
+}
@@ -89,9 +89,22 @@ type DidChangeTextDocumentParams struct {
 	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
 }
 
+type DidSaveTextDocumentParams struct {
+	/**
+	 * The document that was saved.
+	 */
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+
+	/**
+	 * Optional the content when saved. Depends on the includeText value
+	 * when the save notification was requested.
+	 */
+	Text *string `json:"text,omitempty"`
+}
+
 type DidCloseTextDocumentParams struct {
 	/**
 	 * The document that was closed.
 	 */
-	TextDocument TextDocumentIdentifier `json:textDocument`
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
 }
@@ -2,4 +2,6 @@ package messages
 
 type ImplementationParams struct {
 	TextDocumentPositionParams
+	WorkDoneProgressParams
+	PartialResultParams
 }
@@ -1,7 +1,96 @@
 package messages
 
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
 type InitializationOptionsParams struct {
 	VirtualEnvPath string `json:"virtualenv_path,omitempty"`
+	// Virtualenv of each workspace folder, keyed by folder name or path.
+	// Folders without one use VirtualEnvPath, else a `.venv` or `venv`
+	// folder in them.
+	VirtualEnvPaths map[string]string `json:"virtualenv_paths,omitempty"`
+	// Watch files on the server side, by default only when the client can't
+	// register `workspace/didChangeWatchedFiles` watchers.
+	FileWatcher *bool `json:"file_watcher,omitempty"`
+	// "full" to receive the whole document on every change, for clients with
+	// unreliable incremental sync. Full changes are accepted either way.
+	TextDocumentSync string `json:"text_document_sync,omitempty"`
+	// Keep the project index between sessions, true by default.
+	IndexCache *bool `json:"index_cache,omitempty"`
+	// "direct" to list only the direct subclasses and overrides in
+	// implementations, instead of those at every depth.
+	Implementations string `json:"implementations,omitempty"`
+	// Maximum number of workspace symbols returned, 100 by default and no
+	// limit when 0.
+	WorkspaceSymbolLimit *int `json:"workspace_symbol_limit,omitempty"`
+	// "all" to search site-packages and standard library symbols too, not
+	// only the project ones. Queries can pick with `#project` or `#lib`.
+	WorkspaceSymbolScope string `json:"workspace_symbol_scope,omitempty"`
+	// Folders holding the top-level packages of every folder, e.g. `src`.
+	// Relative paths are relative to the folder, as for the options below.
+	// The editor options win over the project config of the folder.
+	SourceRoots []string `json:"source_roots,omitempty"`
+	// Module search paths added to those of every folder, e.g. vendored
+	// libraries.
+	ExtraPaths []string `json:"extra_paths,omitempty"`
+	// Glob patterns of the project Python files indexed, e.g. `src` or
+	// `app/**/*.py`, all of them when empty.
+	Include []string `json:"include,omitempty"`
+	// Glob patterns of project files and folders left out of the index,
+	// relative to the workspace folder, e.g. `migrations` or `gen/**/*.py`.
+	// Files ignored by git, tool caches and virtualenvs are never indexed.
+	Exclude     []string            `json:"exclude,omitempty"`
+	Diagnostics *DiagnosticsOptions `json:"diagnostics,omitempty"`
+	InlayHints  *InlayHintsOptions  `json:"inlay_hints,omitempty"`
+	// "debug", "info", "warn" or "error", "debug" by default.
+	LogLevel string `json:"log_level,omitempty"`
+}
+
+type DiagnosticsOptions struct {
+	// Publish diagnostics at all, true by default.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Codes of the diagnostics never reported, e.g. "unused-import".
+	Disabled []string `json:"disabled,omitempty"`
+}
+
+// InlayHintsOptions are the categories of inlay hints shown, all of them by
+// default.
+type InlayHintsOptions struct {
+	VariableTypes  *bool `json:"variable_types,omitempty"`
+	ReturnTypes    *bool `json:"return_types,omitempty"`
+	ParameterNames *bool `json:"parameter_names,omitempty"`
+}
+
+// Override returns the options with the ones set in settings, the `snakelsp`
+// section of the client configuration, replacing them. Nested options are
+// replaced one by one.
+func (o *InitializationOptionsParams) Override(settings json.RawMessage) (*InitializationOptionsParams, error) {
+	result := &InitializationOptionsParams{}
+	base, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(base, result); err != nil {
+		return nil, err
+	}
+	if len(settings) == 0 {
+		return result, nil
+	}
+	if err := json.Unmarshal(settings, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (o *InitializationOptionsParams) textDocumentSyncKind() TextDocumentSyncKind {
+	if o != nil && o.TextDocumentSync == "full" {
+		return TextDocumentSyncKindFull
+	}
+	return TextDocumentSyncKindIncremental
 }
 
 type InitializeParams struct {
@@ -89,6 +178,22 @@ type InitializeParams struct {
 	WorkspaceFolders []WorkspaceFolder `json:"workspaceFolders,omitempty"`
 }
 
+// Folders returns the workspace folders, the root of clients which don't
+// support them as a single folder. None means no folder is open.
+func (p *InitializeParams) Folders() []WorkspaceFolder {
+	if len(p.WorkspaceFolders) > 0 {
+		return p.WorkspaceFolders
+	}
+	root := p.RootPath
+	if p.RootURI != nil && *p.RootURI != "" {
+		root = strings.TrimPrefix(*p.RootURI, "file://")
+	}
+	if root == "" {
+		return nil
+	}
+	return []WorkspaceFolder{{URI: "file://" + root, Name: filepath.Base(root)}}
+}
+
 type ClientCapabilities struct {
 	/**
 	 * Workspace specific client capabilities.
@@ -158,6 +263,13 @@ type ClientCapabilities struct {
 		 */
 		CodeLens *CodeLensWorkspaceClientCapabilities `json:"codeLens,omitempty"`
 
+		/**
+		 * Client workspace capabilities specific to diagnostics.
+		 *
+		 * @since 3.17.0.
+		 */
+		Diagnostics *DiagnosticWorkspaceClientCapabilities `json:"diagnostics,omitempty"`
+
 		/**
 		 * The client has support for file requests/notifications.
 		 *
@@ -254,6 +366,28 @@ type ClientCapabilities struct {
 		 * @since 3.16.0
 		 */
 		// Markdown *MarkdownClientCapabilities `json:"markdown,omitempty"`
+
+		/**
+		 * The position encodings supported by the client. Client and server
+		 * have to agree on the same position encoding to ensure that offsets
+		 * (e.g. character position in a line) are interpreted the same on both
+		 * side.
+		 *
+		 * To keep the protocol backwards compatible the following applies: if
+		 * the value 'utf-16' is missing from the array of position encodings
+		 * servers can assume that the client supports UTF-16. UTF-16 is
+		 * therefore a mandatory encoding.
+		 *
+		 * If omitted it defaults to ['utf-16'].
+		 *
+		 * Implementation considerations: since the conversion from one encoding
+		 * into another requires the content of the file / line the conversion
+		 * is best done where the file is read which is usually on the server
+		 * side.
+		 *
+		 * @since 3.17.0
+		 */
+		PositionEncodings []PositionEncodingKind `json:"positionEncodings,omitempty"`
 	} `json:"general,omitempty"`
 
 	/**
@@ -496,6 +630,13 @@ type TextDocumentClientCapabilities struct {
 	 * @since 3.16.0
 	 */
 	// Moniker *MonikerClientCapabilities `json:"moniker,omitempty"`
+
+	/**
+	 * Capabilities specific to the diagnostic pull model.
+	 *
+	 * @since 3.17.0
+	 */
+	Diagnostic *DiagnosticClientCapabilities `json:"diagnostic,omitempty"`
 }
 
 // https://microsoft.github.io/language-server-protocol/specifications/specification-3-16#codeLens_refresh
@@ -572,6 +713,21 @@ type WorkspaceSymbolClientCapabilities struct {
 		 */
 		ValueSet []SymbolTag `json:"valueSet"`
 	} `json:"tagSupport,omitempty"`
+
+	/**
+	 * The client support partial workspace symbols. The client will send the
+	 * request `workspaceSymbol/resolve` to the server to resolve additional
+	 * properties.
+	 *
+	 * @since 3.17.0 - proposedState
+	 */
+	ResolveSupport *struct {
+		/**
+		 * The properties that a client can resolve lazily. Usually
+		 * `location.range`
+		 */
+		Properties []string `json:"properties"`
+	} `json:"resolveSupport,omitempty"`
 }
 
 // https://microsoft.github.io/language-server-protocol/specifications/specification-3-16#workspace_didChangeWatchedFiles
@@ -643,3 +799,59 @@ type WorkspaceEditClientCapabilities struct {
 		GroupsOnLabel *bool `json:"groupsOnLabel,omitempty"`
 	} `json:"changeAnnotationSupport,omitempty"`
 }
+
+// SupportsPullDiagnostics reports whether the client pulls diagnostics with
+// `textDocument/diagnostic` instead of waiting for them to be published.
+func (c *ClientCapabilities) SupportsPullDiagnostics() bool {
+	return c.TextDocument != nil && c.TextDocument.Diagnostic != nil
+}
+
+// SupportsDiagnosticsRefresh reports whether the server can ask the client to
+// pull diagnostics again with `workspace/diagnostic/refresh`.
+func (c *ClientCapabilities) SupportsDiagnosticsRefresh() bool {
+	return c.Workspace != nil && c.Workspace.Diagnostics != nil &&
+		c.Workspace.Diagnostics.RefreshSupport != nil && *c.Workspace.Diagnostics.RefreshSupport
+}
+
+// PositionEncoding picks the encoding of positions: utf-8 when the client
+// offers it, the parser works in bytes, utf-16 otherwise.
+func (c *ClientCapabilities) PositionEncoding() PositionEncodingKind {
+	if c.General != nil && slices.Contains(c.General.PositionEncodings, PositionEncodingKindUTF8) {
+		return PositionEncodingKindUTF8
+	}
+	return PositionEncodingKindUTF16
+}
+
+// SupportsWatchedFilesRegistration reports whether file watchers can be
+// registered with `client/registerCapability`.
+func (c *ClientCapabilities) SupportsWatchedFilesRegistration() bool {
+	return c.Workspace != nil && c.Workspace.DidChangeWatchedFiles != nil &&
+		c.Workspace.DidChangeWatchedFiles.DynamicRegistration != nil && *c.Workspace.DidChangeWatchedFiles.DynamicRegistration
+}
+
+// SupportsWorkspaceSymbolResolve reports whether workspace symbols can be sent
+// without a range, resolved later with `workspaceSymbol/resolve`.
+func (c *ClientCapabilities) SupportsWorkspaceSymbolResolve() bool {
+	return c.Workspace != nil && c.Workspace.Symbol != nil && c.Workspace.Symbol.ResolveSupport != nil &&
+		slices.Contains(c.Workspace.Symbol.ResolveSupport.Properties, "location.range")
+}
+
+// SupportsConfiguration reports whether settings can be pulled with
+// `workspace/configuration`.
+func (c *ClientCapabilities) SupportsConfiguration() bool {
+	return c.Workspace != nil && c.Workspace.Configuration != nil && *c.Workspace.Configuration
+}
+
+// SupportsConfigurationRegistration reports whether
+// `workspace/didChangeConfiguration` can be registered with
+// `client/registerCapability`, some clients only send it once registered.
+func (c *ClientCapabilities) SupportsConfigurationRegistration() bool {
+	return c.Workspace != nil && c.Workspace.DidChangeConfiguration != nil &&
+		c.Workspace.DidChangeConfiguration.DynamicRegistration != nil && *c.Workspace.DidChangeConfiguration.DynamicRegistration
+}
+
+// SupportsWorkDoneProgress reports whether the client shows progress created
+// by the server with `window/workDoneProgress/create`.
+func (c *ClientCapabilities) SupportsWorkDoneProgress() bool {
+	return c.Window != nil && c.Window.WorkDoneProgress != nil && *c.Window.WorkDoneProgress
+}
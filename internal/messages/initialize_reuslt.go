@@ -35,19 +35,41 @@ const (
 	TextDocumentSyncKindIncremental = TextDocumentSyncKind(2)
 )
 
+type SaveOptions struct {
+	/**
+	 * The client is supposed to include the content on save.
+	 */
+	IncludeText bool `json:"includeText"`
+}
+
 type textDocumentSyncOptions struct {
 	OpenClose bool                 `json:"openClose"`
 	Change    TextDocumentSyncKind `json:"change"`
+	Save      *SaveOptions         `json:"save,omitempty"`
+}
+
+type fileOperationsServerCapabilities struct {
+	WillRename *FileOperationRegistrationOptions `json:"willRename,omitempty"`
+	DidRename  *FileOperationRegistrationOptions `json:"didRename,omitempty"`
+}
+
+type workspaceServerCapabilities struct {
+	WorkspaceFolders *WorkspaceFoldersServerCapabilities `json:"workspaceFolders,omitempty"`
+	FileOperations   *fileOperationsServerCapabilities   `json:"fileOperations,omitempty"`
 }
 
 type serverCapabilities struct {
-	TextDocumentSync        *textDocumentSyncOptions `json:"textDocumentSync"`
-	DefinitionProvider      bool                     `json:"definitionProvider"`
-	WorkspaceSymbolProvider bool                     `json:"workspaceSymbolProvider"`
-	DocumentSymbolProvider  bool                     `json:"documentSymbolProvider"`
-	TypeHierarchyProvider   bool                     `json:"typeHierarchyProvider"`
-	ImplementationProvider  bool                     `json:"implementationProvider"`
-	DeclarationProvider     bool                     `json:"declarationProvider"`
+	PositionEncoding        PositionEncodingKind         `json:"positionEncoding,omitempty"`
+	TextDocumentSync        *textDocumentSyncOptions     `json:"textDocumentSync"`
+	DefinitionProvider      bool                         `json:"definitionProvider"`
+	WorkspaceSymbolProvider any                          `json:"workspaceSymbolProvider"` // bool or WorkspaceSymbolOptions
+	DocumentSymbolProvider  bool                         `json:"documentSymbolProvider"`
+	TypeHierarchyProvider   bool                         `json:"typeHierarchyProvider"`
+	ImplementationProvider  bool                         `json:"implementationProvider"`
+	DeclarationProvider     bool                         `json:"declarationProvider"`
+	DiagnosticProvider      *DiagnosticOptions           `json:"diagnosticProvider,omitempty"`
+	CodeActionProvider      *CodeActionOptions           `json:"codeActionProvider,omitempty"`
+	Workspace               *workspaceServerCapabilities `json:"workspace,omitempty"`
 }
 
 type InitializeResult struct {
@@ -57,18 +79,49 @@ type InitializeResult struct {
 
 func NewInitializeResult(initializeParam *InitializeParams) *InitializeResult {
 	slog.Debug("Document sync options", slog.Any("capabilities", initializeParam.Capabilities.TextDocument.DocumentSymbol))
+	var diagnosticProvider *DiagnosticOptions
+	if initializeParam.Capabilities.SupportsPullDiagnostics() {
+		diagnosticProvider = &DiagnosticOptions{
+			Identifier:            "snakelsp",
+			InterFileDependencies: true,
+			WorkspaceDiagnostics:  true,
+		}
+	}
+	// Python modules and the folders which can be packages.
+	renameFilters := &FileOperationRegistrationOptions{
+		Filters: []FileOperationFilter{
+			{Scheme: "file", Pattern: FileOperationPattern{Glob: "**/*.{py,pyi}", Matches: FileOperationPatternKindFile}},
+			{Scheme: "file", Pattern: FileOperationPattern{Glob: "**", Matches: FileOperationPatternKindFolder}},
+		},
+	}
 	return &InitializeResult{
 		ServerCapabilities: &serverCapabilities{
+			PositionEncoding: initializeParam.Capabilities.PositionEncoding(),
 			TextDocumentSync: &textDocumentSyncOptions{
 				OpenClose: true,
-				Change:    TextDocumentSyncKindIncremental,
+				Change:    initializeParam.InitializationOptions.textDocumentSyncKind(),
+				Save:      &SaveOptions{IncludeText: true},
 			},
 			DefinitionProvider:      false,
-			WorkspaceSymbolProvider: initializeParam.Capabilities.Workspace.Symbol != nil,
+			WorkspaceSymbolProvider: workspaceSymbolProvider(&initializeParam.Capabilities),
 			DocumentSymbolProvider:  initializeParam.Capabilities.TextDocument.DocumentSymbol != nil,
 			TypeHierarchyProvider:   initializeParam.Capabilities.TextDocument.CallHierarchy != nil,
 			ImplementationProvider:  true,
 			DeclarationProvider:     initializeParam.Capabilities.TextDocument.Declaration != nil,
+			DiagnosticProvider:      diagnosticProvider,
+			CodeActionProvider: &CodeActionOptions{
+				CodeActionKinds: []CodeActionKind{CodeActionKindQuickFix, CodeActionKindSourceOrganizeImports},
+			},
+			Workspace: &workspaceServerCapabilities{
+				WorkspaceFolders: &WorkspaceFoldersServerCapabilities{
+					Supported:           true,
+					ChangeNotifications: true,
+				},
+				FileOperations: &fileOperationsServerCapabilities{
+					WillRename: renameFilters,
+					DidRename:  renameFilters,
+				},
+			},
 		},
 		ServerInfo: &serverInfo{
 			Name:    "SnakeLSP",
@@ -76,3 +129,10 @@ func NewInitializeResult(initializeParam *InitializeParams) *InitializeResult {
 		},
 	}
 }
+
+func workspaceSymbolProvider(capabilities *ClientCapabilities) any {
+	if capabilities.SupportsWorkspaceSymbolResolve() {
+		return &WorkspaceSymbolOptions{ResolveProvider: true}
+	}
+	return capabilities.Workspace.Symbol != nil
+}
@@ -76,6 +76,9 @@ func GetSymbolKind(name string) (SymbolKind, bool) {
 type SymbolTag Integer
 
 type WorkspaceSymbolParams struct {
+	WorkDoneProgressParams
+	PartialResultParams
+
 	/**
 	 * A query string to filter symbols by. Clients may send an empty
 	 * string here to request all symbols.
@@ -109,7 +112,7 @@ type WorkspaceSymbol struct {
 	/**
 	 * Tags for this completion item.
 	 */
-	Tags SymbolTag `json:"tags,omitempty`
+	Tags SymbolTag `json:"tags,omitempty"`
 
 	/**
 	 * The name of the symbol containing this symbol. This information is for
@@ -126,13 +129,35 @@ type WorkspaceSymbol struct {
 	 *
 	 * See also `SymbolInformation.location`.
 	 */
-	Location Location `json:"location"`
+	Location any `json:"location"` // Location or LocationUriOnly
 
 	/**
 	 * A data entry field that is preserved on a workspace symbol between a
 	 * workspace symbol request and a workspace symbol resolve request.
 	 */
-	data any `json:"data,omitempty"`
+	Data any `json:"data,omitempty"`
+}
+
+/**
+ * A location of a workspace symbol to resolve with `workspaceSymbol/resolve`.
+ *
+ * @since 3.17.0
+ */
+type LocationUriOnly struct {
+	URI DocumentUri `json:"uri"`
+}
+
+/**
+ * Server capabilities for a `workspace/symbol` request.
+ */
+type WorkspaceSymbolOptions struct {
+	/**
+	 * The server provides support to resolve additional
+	 * information for a workspace symbol.
+	 *
+	 * @since 3.17.0
+	 */
+	ResolveProvider bool `json:"resolveProvider,omitempty"`
 }
 
 type DocumentSymbol struct {
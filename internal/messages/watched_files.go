@@ -0,0 +1,120 @@
+package messages
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17#client_registerCapability
+
+/**
+ * General parameters to register for a capability.
+ */
+type Registration struct {
+	/**
+	 * The id used to register the request. The id can be used to deregister
+	 * the request again.
+	 */
+	ID string `json:"id"`
+
+	/**
+	 * The method / capability to register for.
+	 */
+	Method string `json:"method"`
+
+	/**
+	 * Options necessary for the registration.
+	 */
+	RegisterOptions any `json:"registerOptions,omitempty"`
+}
+
+type RegistrationParams struct {
+	Registrations []Registration `json:"registrations"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17#workspace_didChangeWatchedFiles
+
+type WatchKind UInteger
+
+const (
+	/**
+	 * Interested in create events.
+	 */
+	WatchKindCreate = WatchKind(1)
+
+	/**
+	 * Interested in change events
+	 */
+	WatchKindChange = WatchKind(2)
+
+	/**
+	 * Interested in delete events
+	 */
+	WatchKindDelete = WatchKind(4)
+)
+
+type FileSystemWatcher struct {
+	/**
+	 * The glob pattern to watch. See {@link GlobPattern glob pattern}
+	 * for more detail.
+	 *
+	 * @since 3.17.0 support for relative patterns.
+	 */
+	GlobPattern string `json:"globPattern"`
+
+	/**
+	 * The kind of events of interest. If omitted it defaults
+	 * to WatchKind.Create | WatchKind.Change | WatchKind.Delete
+	 * which is 7.
+	 */
+	Kind *WatchKind `json:"kind,omitempty"`
+}
+
+/**
+ * Describe options to be used when registering for file system change events.
+ */
+type DidChangeWatchedFilesRegistrationOptions struct {
+	/**
+	 * The watchers to register.
+	 */
+	Watchers []FileSystemWatcher `json:"watchers"`
+}
+
+/**
+ * The file event type.
+ */
+type FileChangeType UInteger
+
+const (
+	/**
+	 * The file got created.
+	 */
+	FileChangeTypeCreated = FileChangeType(1)
+
+	/**
+	 * The file got changed.
+	 */
+	FileChangeTypeChanged = FileChangeType(2)
+
+	/**
+	 * The file got deleted.
+	 */
+	FileChangeTypeDeleted = FileChangeType(3)
+)
+
+/**
+ * An event describing a file change.
+ */
+type FileEvent struct {
+	/**
+	 * The file's URI.
+	 */
+	URI DocumentUri `json:"uri"`
+
+	/**
+	 * The change type.
+	 */
+	Type FileChangeType `json:"type"`
+}
+
+type DidChangeWatchedFilesParams struct {
+	/**
+	 * The actual file events.
+	 */
+	Changes []FileEvent `json:"changes"`
+}
@@ -37,6 +37,31 @@ type WorkDoneProgress struct {
 }
 
 type ServerWorkDoneProgress struct {
-	Token            string           `json:"token"`
+	Token            *ProgressToken   `json:"token"`
 	WorkDoneProgress WorkDoneProgress `json:"value"`
 }
+
+// ProgressParams is the `$/progress` payload used to stream partial results
+// to the token the client provided in `partialResultToken`.
+type ProgressParams struct {
+	Token *ProgressToken `json:"token"`
+	Value any            `json:"value"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17#window_workDoneProgress_create
+
+type WorkDoneProgressCreateParams struct {
+	/**
+	 * The token to be used to report progress.
+	 */
+	Token *ProgressToken `json:"token"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17#window_workDoneProgress_cancel
+
+type WorkDoneProgressCancelParams struct {
+	/**
+	 * The token to be used to report progress.
+	 */
+	Token ProgressToken `json:"token"`
+}
@@ -1,5 +1,7 @@
 package messages
 
+import "encoding/json"
+
 type WorkspaceFolder struct {
 	/**
 	 * The associated URI for this workspace folder.
@@ -12,3 +14,72 @@ type WorkspaceFolder struct {
 	 */
 	Name string `json:"name"`
 }
+
+type WorkspaceFoldersServerCapabilities struct {
+	/**
+	 * The server has support for workspace folders
+	 */
+	Supported bool `json:"supported"`
+
+	/**
+	 * Whether the server wants to receive workspace folder
+	 * change notifications.
+	 *
+	 * If a string is provided, the string is treated as an ID
+	 * under which the notification is registered on the client
+	 * side. The ID can be used to unregister for these events
+	 * using the `client/unregisterCapability` request.
+	 */
+	ChangeNotifications bool `json:"changeNotifications"`
+}
+
+type DidChangeWorkspaceFoldersParams struct {
+	/**
+	 * The actual workspace folder change event.
+	 */
+	Event WorkspaceFoldersChangeEvent `json:"event"`
+}
+
+/**
+ * The workspace folder change event.
+ */
+type WorkspaceFoldersChangeEvent struct {
+	/**
+	 * The array of added workspace folders
+	 */
+	Added []WorkspaceFolder `json:"added"`
+
+	/**
+	 * The array of the removed workspace folders
+	 */
+	Removed []WorkspaceFolder `json:"removed"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/specification-3-17#workspace_configuration
+
+type ConfigurationParams struct {
+	Items []ConfigurationItem `json:"items"`
+}
+
+type ConfigurationItem struct {
+	/**
+	 * The scope to get the configuration section for.
+	 */
+	ScopeURI *DocumentUri `json:"scopeUri,omitempty"`
+
+	/**
+	 * The configuration section asked for.
+	 */
+	Section string `json:"section,omitempty"`
+}
+
+type DidChangeConfigurationParams struct {
+	/**
+	 * The actual changed settings
+	 */
+	Settings json.RawMessage `json:"settings"`
+}
+
+type DidChangeConfigurationRegistrationOptions struct {
+	Section string `json:"section,omitempty"`
+}
@@ -4,34 +4,130 @@
 package progress
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
 	"snakelsp/internal/messages"
-	"snakelsp/internal/request"
 
 	"github.com/google/uuid"
 )
 
+// Tracker holds the progresses created by the server for a client session.
+type Tracker struct {
+	// Whether the client shows progress created by the server, its
+	// `window.workDoneProgress` capability.
+	supported bool
+
+	// Progresses the user can cancel with `window/workDoneProgress/cancel`,
+	// by token.
+	cancellable      map[string]context.CancelFunc
+	cancellableMutex sync.Mutex
+}
+
+func NewTracker(supported bool) *Tracker {
+	return &Tracker{supported: supported, cancellable: map[string]context.CancelFunc{}}
+}
+
+// Client sends the progress requests and notifications, a *request.Client
+// in the server.
+type Client interface {
+	Call(method string, params any) (any, error)
+	Notify(method string, params any)
+}
+
 type WorkDone struct {
-	token     uuid.UUID
-	client    *request.Client
-	isStarted bool
+	token       messages.ProgressToken
+	client      Client
+	tracker     *Tracker // Of the progresses created by the server
+	clientToken bool     // Created by the client and sent in the request params
+	isStarted   bool
+	failed      bool // The client refused the token
+	ctx         context.Context
+	cancel      context.CancelFunc
 }
 
-func NewWorkDone(client *request.Client) *WorkDone {
-	token := uuid.New()
+// NewWorkDone creates a progress the user can cancel, nil when the client
+// doesn't show server progresses.
+func (t *Tracker) NewWorkDone(client Client) *WorkDone {
+	if !t.supported {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WorkDone{
+		token:   messages.ProgressToken{Value: uuid.New().String()},
+		client:  client,
+		tracker: t,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// FromParams reports the progress of a request with the `workDoneToken` the
+// client sent, nil when it didn't. The request itself is canceled with
+// `$/cancelRequest`.
+func FromParams(client Client, params messages.WorkDoneProgressParams) *WorkDone {
+	if params.WorkDoneToken == nil {
+		return nil
+	}
 	return &WorkDone{
-		token:  token,
-		client: client,
+		token:       *params.WorkDoneToken,
+		client:      client,
+		clientToken: true,
+		ctx:         context.Background(),
 	}
 }
 
+func tokenKey(token messages.ProgressToken) string {
+	return fmt.Sprint(token.Value)
+}
+
+// Cancel cancels the context of the progress with the token, false when it's
+// not running or can't be canceled.
+func (t *Tracker) Cancel(token messages.ProgressToken) bool {
+	t.cancellableMutex.Lock()
+	defer t.cancellableMutex.Unlock()
+	cancel, ok := t.cancellable[tokenKey(token)]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// Context is canceled when the user cancels the progress, or once it ended.
+// Methods of a nil
+// WorkDone do nothing, for work that isn't reported, its context is never
+// canceled.
+func (w *WorkDone) Context() context.Context {
+	if w == nil {
+		return context.Background()
+	}
+	return w.ctx
+}
+
+// Start begins the progress, once the client created its token.
 func (w *WorkDone) Start(message string) {
-	w.client.Notify("window/workDoneProgress/create", map[string]any{"token": w.token.String()})
+	if w == nil || w.isStarted || w.failed {
+		return
+	}
+	if !w.clientToken {
+		_, err := w.client.Call("window/workDoneProgress/create", messages.WorkDoneProgressCreateParams{Token: &w.token})
+		if err != nil {
+			slog.Warn("Progress not created", slog.String("title", message), slog.Any("error", err))
+			w.failed = true
+			return
+		}
+		w.tracker.cancellableMutex.Lock()
+		w.tracker.cancellable[tokenKey(w.token)] = w.cancel
+		w.tracker.cancellableMutex.Unlock()
+	}
 	w.client.Notify("$/progress", messages.ServerWorkDoneProgress{
-		Token: w.token.String(),
+		Token: &w.token,
 		WorkDoneProgress: messages.WorkDoneProgress{
 			Kind:        "begin",
 			Title:       message,
-			Cancellable: false,
+			Cancellable: !w.clientToken,
 			Message:     message,
 		},
 	})
@@ -39,11 +135,17 @@ func (w *WorkDone) Start(message string) {
 }
 
 func (w *WorkDone) Report(message string, percentage uint16) {
+	if w == nil {
+		return
+	}
 	if !w.isStarted {
 		w.Start(message)
 	}
+	if !w.isStarted {
+		return
+	}
 	w.client.Notify("$/progress", messages.ServerWorkDoneProgress{
-		Token: w.token.String(),
+		Token: &w.token,
 		WorkDoneProgress: messages.WorkDoneProgress{
 			Kind:       "report",
 			Message:    message,
@@ -52,11 +154,27 @@ func (w *WorkDone) Report(message string, percentage uint16) {
 	})
 }
 
+// End ends the progress and releases its context, which can't be canceled
+// anymore.
 func (w *WorkDone) End(message string) {
+	if w == nil {
+		return
+	}
+	if !w.clientToken {
+		w.tracker.cancellableMutex.Lock()
+		delete(w.tracker.cancellable, tokenKey(w.token))
+		w.tracker.cancellableMutex.Unlock()
+		w.cancel()
+	}
+	if !w.isStarted {
+		return
+	}
 	w.client.Notify("$/progress", messages.ServerWorkDoneProgress{
-		Token: w.token.String(),
+		Token: &w.token,
 		WorkDoneProgress: messages.WorkDoneProgress{
-			Kind: "end",
+			Kind:    "end",
+			Message: message,
 		},
 	})
+	w.isStarted = false
 }
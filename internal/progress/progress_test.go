@@ -0,0 +1,24 @@
+package progress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClient struct{}
+
+func (fakeClient) Call(method string, params any) (any, error) { return nil, nil }
+func (fakeClient) Notify(method string, params any)            {}
+
+func TestEndReleasesProgress(t *testing.T) {
+	tracker := NewTracker(true)
+	pr := tracker.NewWorkDone(fakeClient{})
+	pr.Start("Indexing")
+	assert.Contains(t, tracker.cancellable, tokenKey(pr.token))
+
+	pr.End("Indexed")
+	assert.NotContains(t, tracker.cancellable, tokenKey(pr.token))
+	assert.Error(t, pr.Context().Err())
+	assert.False(t, tracker.Cancel(pr.token))
+}
@@ -0,0 +1,87 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/request"
+	"snakelsp/internal/workspace"
+)
+
+func HandleCodeAction(r *request.Request) (any, error) {
+	var data messages.CodeActionParams
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	actions := []messages.CodeAction{}
+	pythonFile, err := r.Workspace().GetPythonFile(data.TextDocument.URI)
+	if err != nil {
+		return actions, nil
+	}
+	pythonFile.FlushUpdate()
+	if wantsCodeActionKind(data.Context.Only, messages.CodeActionKindQuickFix) {
+		actions = append(actions, removeUnusedImportActions(pythonFile, data.Range)...)
+	}
+	if wantsCodeActionKind(data.Context.Only, messages.CodeActionKindSourceOrganizeImports) {
+		if edits := pythonFile.OrganizeImports(); len(edits) > 0 {
+			actions = append(actions, messages.CodeAction{
+				Title: "Organize imports",
+				Kind:  messages.CodeActionKindSourceOrganizeImports,
+				Edit: &messages.WorkspaceEdit{
+					Changes: map[messages.DocumentUri][]messages.TextEdit{pythonFile.Url: edits},
+				},
+			})
+		}
+	}
+	return actions, nil
+}
+
+// wantsCodeActionKind checks kind against the `only` filter of the request,
+// where `source` also asks for `source.organizeImports`.
+func wantsCodeActionKind(only []messages.CodeActionKind, kind messages.CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, requested := range only {
+		if requested == kind || strings.HasPrefix(string(kind), string(requested)+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func removeUnusedImportActions(pythonFile *workspace.PythonFile, selection messages.Range) []messages.CodeAction {
+	actions := []messages.CodeAction{}
+	for _, unused := range pythonFile.UnusedImports() {
+		if !rangesOverlap(unused.Diagnostic.Range, selection) {
+			continue
+		}
+		actions = append(actions, messages.CodeAction{
+			Title:       fmt.Sprintf("Remove unused import %q", unused.Import.BoundName()),
+			Kind:        messages.CodeActionKindQuickFix,
+			Diagnostics: []messages.Diagnostic{unused.Diagnostic},
+			IsPreferred: true,
+			Edit: &messages.WorkspaceEdit{
+				Changes: map[messages.DocumentUri][]messages.TextEdit{
+					pythonFile.Url: {unused.Edit},
+				},
+			},
+		})
+	}
+	return actions
+}
+
+func positionBefore(a, b messages.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
+// rangesOverlap reports whether the ranges share a position, an empty
+// selection touching a range included.
+func rangesOverlap(a, b messages.Range) bool {
+	return !positionBefore(a.End, b.Start) && !positionBefore(b.End, a.Start)
+}
@@ -0,0 +1,87 @@
+package protocol
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/request"
+	"snakelsp/internal/workspace"
+)
+
+// Section of the client configuration holding the settings.
+const configurationSection = "snakelsp"
+
+// registerConfiguration asks the client to send `workspace/didChangeConfiguration`
+// when the `snakelsp` section changes, some clients don't send it otherwise.
+func registerConfiguration(client *request.Client) {
+	_, err := client.Call("client/registerCapability", messages.RegistrationParams{
+		Registrations: []messages.Registration{
+			{
+				ID:              "snakelsp-configuration",
+				Method:          "workspace/didChangeConfiguration",
+				RegisterOptions: messages.DidChangeConfigurationRegistrationOptions{Section: configurationSection},
+			},
+		},
+	})
+	if err != nil {
+		slog.Error("Unable to register configuration changes", slog.Any("error", err))
+	}
+}
+
+// pullConfiguration asks the client for the `snakelsp` section of its
+// configuration and applies it.
+func pullConfiguration(r *request.Request) {
+	result, err := r.Client.Call("workspace/configuration", messages.ConfigurationParams{
+		Items: []messages.ConfigurationItem{{Section: configurationSection}},
+	})
+	if err != nil {
+		return
+	}
+	items, ok := result.([]any)
+	if !ok || len(items) == 0 {
+		return
+	}
+	section, err := json.Marshal(items[0])
+	if err != nil {
+		return
+	}
+	applyConfiguration(r, section)
+}
+
+// applyConfiguration applies the settings of the configuration section over
+// the initialization options.
+func applyConfiguration(r *request.Request, section json.RawMessage) {
+	options, err := r.Session.InitializationOptions().Override(section)
+	if err != nil {
+		r.Logger.Error("Invalid configuration", slog.Any("error", err))
+		return
+	}
+	settings := workspace.NewSettings(options)
+	r.Session.SetLogLevel(settings.LogLevel)
+	r.Workspace().UpdateSettings(settings)
+	slog.Info("Configuration applied", slog.Any("settings", settings))
+}
+
+func HandleDidChangeConfiguration(r *request.Request) (any, error) {
+	var data messages.DidChangeConfigurationParams
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	if r.Session.Capabilities().SupportsConfiguration() {
+		pullConfiguration(r)
+		return nil, nil
+	}
+	// Pushed by clients which can't be asked, in a `snakelsp` section or not.
+	section := data.Settings
+	var sections map[string]json.RawMessage
+	if json.Unmarshal(data.Settings, &sections) == nil {
+		if snakelsp, ok := sections[configurationSection]; ok {
+			section = snakelsp
+		}
+	}
+	applyConfiguration(r, section)
+	return nil, nil
+}
@@ -3,8 +3,17 @@
 // LSP requests like shutdown, initialization, and other protocol operations.
 package protocol
 
-import "snakelsp/internal/request"
+import (
+	"log/slog"
+
+	"snakelsp/internal/request"
+)
 
 func HandleShutdown(r *request.Request) (interface{}, error) {
+	w := r.Workspace()
+	w.StopFileWatcher()
+	if err := w.SaveIndexCache(); err != nil {
+		r.Logger.Warn("Unable to save index cache", slog.Any("error", err))
+	}
 	return interface{}(nil), nil
 }
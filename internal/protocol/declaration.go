@@ -15,7 +15,7 @@ func HandleSymbolDeclaration(r *request.Request) (any, error) {
 		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
 		return nil, err
 	}
-	workspaceFile, err := workspace.GetPythonFile(data.TextDocument.URI)
+	workspaceFile, err := r.Workspace().GetPythonFile(data.TextDocument.URI)
 	if err != nil {
 		r.Logger.Error("Error getting Python file: %v", slog.Any("error", err))
 		return nil, err
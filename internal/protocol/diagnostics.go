@@ -0,0 +1,129 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/progress"
+	"snakelsp/internal/request"
+	"snakelsp/internal/workspace"
+)
+
+// Number of file reports sent in a single `$/progress` partial result.
+const workspaceDiagnosticsBatchSize = 100
+
+func HandleDocumentDiagnostic(r *request.Request) (any, error) {
+	var data messages.DocumentDiagnosticParams
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	diagnostics := []messages.Diagnostic{}
+	pythonFile, err := r.Workspace().GetPythonFile(data.TextDocument.URI)
+	if err == nil {
+		// Imports and symbols wait for the typing to pause, their ranges
+		// have to match the current text.
+		pythonFile.FlushUpdate()
+		diagnostics = pythonFile.Diagnostics()
+	}
+	resultID := workspace.DiagnosticsResultID(diagnostics)
+	if data.PreviousResultID != nil && *data.PreviousResultID == resultID {
+		return messages.UnchangedDocumentDiagnosticReport{
+			Kind:     messages.DocumentDiagnosticReportKindUnchanged,
+			ResultID: resultID,
+		}, nil
+	}
+	return messages.FullDocumentDiagnosticReport{
+		Kind:     messages.DocumentDiagnosticReportKindFull,
+		ResultID: resultID,
+		Items:    diagnostics,
+	}, nil
+}
+
+func HandleWorkspaceDiagnostic(r *request.Request) (any, error) {
+	var data messages.WorkspaceDiagnosticParams
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	previousResultIDs := map[string]string{}
+	for _, previous := range data.PreviousResultIDs {
+		previousResultIDs[previous.URI] = previous.Value
+	}
+
+	files := []*workspace.PythonFile{}
+	r.Workspace().Files.Range(func(key, value any) bool {
+		if file := value.(*workspace.PythonFile); !file.External {
+			files = append(files, file)
+		}
+		return true
+	})
+	slices.SortFunc(files, func(a, b *workspace.PythonFile) int {
+		return strings.Compare(a.Url, b.Url)
+	})
+
+	pr := progress.FromParams(r.Client, data.WorkDoneProgressParams)
+	pr.Start("Checking project")
+	defer pr.End("")
+
+	streaming := data.PartialResultToken != nil
+	report := messages.WorkspaceDiagnosticReport{Items: []any{}}
+	flush := func() {
+		if len(report.Items) == 0 {
+			return
+		}
+		r.Client.Notify("$/progress", messages.ProgressParams{
+			Token: data.PartialResultToken,
+			Value: report,
+		})
+		report = messages.WorkspaceDiagnosticReport{Items: []any{}}
+	}
+
+	for i, file := range files {
+		if r.Context.Err() != nil {
+			return nil, r.Context.Err()
+		}
+		pr.Report(fmt.Sprintf("%d/%d files", i+1, len(files)), uint16((i+1)*100/len(files)))
+		file.FlushUpdate()
+		diagnostics := file.Diagnostics()
+		previousResultID, known := previousResultIDs[file.Url]
+		// Files the client doesn't know about only matter if they have problems.
+		if !known && len(diagnostics) == 0 {
+			continue
+		}
+		resultID := workspace.DiagnosticsResultID(diagnostics)
+		if known && previousResultID == resultID {
+			report.Items = append(report.Items, messages.WorkspaceUnchangedDocumentDiagnosticReport{
+				UnchangedDocumentDiagnosticReport: messages.UnchangedDocumentDiagnosticReport{
+					Kind:     messages.DocumentDiagnosticReportKindUnchanged,
+					ResultID: resultID,
+				},
+				URI: file.Url,
+			})
+		} else {
+			report.Items = append(report.Items, messages.WorkspaceFullDocumentDiagnosticReport{
+				FullDocumentDiagnosticReport: messages.FullDocumentDiagnosticReport{
+					Kind:     messages.DocumentDiagnosticReportKindFull,
+					ResultID: resultID,
+					Items:    diagnostics,
+				},
+				URI: file.Url,
+			})
+		}
+		if streaming && len(report.Items) >= workspaceDiagnosticsBatchSize {
+			flush()
+		}
+	}
+	if streaming {
+		// Everything went through `$/progress`, the response itself stays empty.
+		flush()
+		return messages.WorkspaceDiagnosticReport{Items: []any{}}, nil
+	}
+	return report, nil
+}
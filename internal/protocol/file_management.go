@@ -3,16 +3,13 @@ package protocol
 import (
 	"encoding/json"
 	"log/slog"
-	"strings"
 
 	"snakelsp/internal/messages"
 	"snakelsp/internal/request"
-	"snakelsp/internal/workspace"
 )
 
 func HandleDidOpen(r *request.Request) (interface{}, error) {
 	var data messages.DidOpenTextDocumentParams
-	var external bool
 	err := json.Unmarshal(r.Params, &data)
 	if err != nil {
 		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
@@ -21,12 +18,9 @@ func HandleDidOpen(r *request.Request) (interface{}, error) {
 	if data.TextDocument.LanguageID != "python" {
 		return nil, nil
 	}
-	if strings.Contains(data.TextDocument.URI, workspace.ClientSettings.WorkspaceRoot) {
-		external = false
-	} else {
-		external = true
-	}
-	workspace.NewPythonFile(data.TextDocument.URI, data.TextDocument.Text, external, true)
+	w := r.Workspace()
+	external := !w.IsProjectFile(data.TextDocument.URI)
+	w.OpenFile(data.TextDocument.URI, data.TextDocument.Text, data.TextDocument.Version, external)
 
 	return interface{}(nil), nil
 }
@@ -38,15 +32,29 @@ func HandleDidChange(r *request.Request) (interface{}, error) {
 		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
 		return nil, err
 	}
-	pythonFile, err := workspace.GetPythonFile(data.TextDocument.URI)
+	pythonFile, err := r.Workspace().GetPythonFile(data.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	err = pythonFile.ApplyChange(data.TextDocument.Version, data.ContentChanges)
 	if err != nil {
+		r.Logger.Warn("Change rejected", slog.Any("error", err))
 		return nil, err
 	}
-	pythonFile.ApplyChange(data.ContentChanges)
 
 	return nil, nil
 }
 
+func HandleDidSave(r *request.Request) (interface{}, error) {
+	var data messages.DidSaveTextDocumentParams
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	return nil, r.Workspace().SaveFile(data.TextDocument.URI, data.Text)
+}
+
 func HandleDidClose(r *request.Request) (interface{}, error) {
 	var data messages.DidCloseTextDocumentParams
 	err := json.Unmarshal(r.Params, &data)
@@ -54,7 +62,7 @@ func HandleDidClose(r *request.Request) (interface{}, error) {
 		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
 		return nil, err
 	}
-	file, err := workspace.GetPythonFile(data.TextDocument.URI)
+	file, err := r.Workspace().GetPythonFile(data.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
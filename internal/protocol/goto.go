@@ -6,7 +6,6 @@ import (
 
 	"snakelsp/internal/messages"
 	"snakelsp/internal/request"
-	"snakelsp/internal/workspace"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 )
@@ -88,13 +87,6 @@ func findDefinition(astRoot *tree_sitter.Node, symbol string, cursorNode *tree_s
 	return nil
 }
 
-func extractTextFromNode(p *workspace.PythonFile, node *tree_sitter.Node) string {
-	startByte := node.StartByte()
-	endByte := node.EndByte()
-
-	return string(p.Text[startByte:endByte])
-}
-
 func HandleGotoDefinition(r *request.Request) (interface{}, error) {
 	var data messages.DefinitionParams
 	err := json.Unmarshal(r.Params, &data)
@@ -102,34 +94,27 @@ func HandleGotoDefinition(r *request.Request) (interface{}, error) {
 		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
 		return nil, err
 	}
-	pythonFile, err := workspace.GetPythonFile(data.TextDocument.URI)
+	pythonFile, err := r.Workspace().GetPythonFile(data.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
-	astRoot := pythonFile.GetOrCreateAst()
-	foundedNode := astRoot.NamedDescendantForPointRange(
-		tree_sitter.Point{Row: uint(data.Position.Line), Column: uint(data.Position.Character)},
-		tree_sitter.Point{Row: uint(data.Position.Line), Column: uint(data.Position.Character)},
-	)
-	nodeText := extractTextFromNode(pythonFile, foundedNode)
-	definitionNode := findDefinition(astRoot, nodeText, foundedNode, []byte(pythonFile.Text), r.Logger)
+	ast := pythonFile.GetOrCreateAst()
+	defer ast.Close()
+	point, ok := pythonFile.LineIndex().Point(data.Position)
+	if !ok {
+		return nil, nil
+	}
+	foundedNode := ast.Root.NamedDescendantForPointRange(point, point)
+	nodeText := foundedNode.Utf8Text(ast.Source)
+	definitionNode := findDefinition(ast.Root, nodeText, foundedNode, ast.Source, r.Logger)
 	if definitionNode == nil {
 		return nil, nil
 	}
 	r.Logger.Debug("Definition found", slog.String("name", definitionNode.ToSexp()))
-	definitionRange := &messages.Range{
-		Start: messages.Position{
-			Line:      uint32(definitionNode.StartPosition().Row),
-			Character: uint32(definitionNode.StartPosition().Column),
-		},
-		End: messages.Position{
-			Line:      uint32(definitionNode.EndPosition().Row),
-			Character: uint32(definitionNode.EndPosition().Column),
-		},
-	}
+	definitionRange := ast.NodeRange(definitionNode)
 	return &messages.LocationLink{
 		TargetURI:            data.TextDocument.URI,
-		TargetRange:          *definitionRange,
-		TargetSelectionRange: *definitionRange,
+		TargetRange:          definitionRange,
+		TargetSelectionRange: definitionRange,
 	}, nil
 }
@@ -5,18 +5,29 @@ import "snakelsp/internal/request"
 type RequestHandler func(r *request.Request) (interface{}, error)
 
 var Handlers = map[string]RequestHandler{
-	"initialize":                        HandleInitialize,
-	"initialized":                       HandleInitialized,
-	"textDocument/didOpen":              HandleDidOpen,
-	"textDocument/didChange":            HandleDidChange,
-	"textDocument/didClose":             HandleDidClose,
-	"shutdown":                          HandleShutdown,
-	"textDocument/definition":           HandleGotoDefinition,
-	"workspace/symbol":                  HandleWorkspaceSymbol,
-	"textDocument/documentSymbol":       HandleDocumentSybmol,
-	"$/cancelRequest":                   HandleCancelRequest,
-	"textDocument/prepareTypeHierarchy": HandlePrepareTypeHierarchy,
-	"typeHierarchy/supertypes":          HandleTypeHierarchySuperTypes,
-	"textDocument/declaration":          HandleSymbolDeclaration,
-	"textDocument/implementation":       HandleSymbolImplementation,
+	"initialize":                          HandleInitialize,
+	"initialized":                         HandleInitialized,
+	"textDocument/didOpen":                HandleDidOpen,
+	"textDocument/didChange":              HandleDidChange,
+	"textDocument/didClose":               HandleDidClose,
+	"textDocument/didSave":                HandleDidSave,
+	"shutdown":                            HandleShutdown,
+	"textDocument/definition":             HandleGotoDefinition,
+	"workspace/symbol":                    HandleWorkspaceSymbol,
+	"workspaceSymbol/resolve":             HandleWorkspaceSymbolResolve,
+	"textDocument/documentSymbol":         HandleDocumentSybmol,
+	"$/cancelRequest":                     HandleCancelRequest,
+	"window/workDoneProgress/cancel":      HandleWorkDoneProgressCancel,
+	"textDocument/prepareTypeHierarchy":   HandlePrepareTypeHierarchy,
+	"typeHierarchy/supertypes":            HandleTypeHierarchySuperTypes,
+	"textDocument/declaration":            HandleSymbolDeclaration,
+	"textDocument/implementation":         HandleSymbolImplementation,
+	"textDocument/diagnostic":             HandleDocumentDiagnostic,
+	"workspace/diagnostic":                HandleWorkspaceDiagnostic,
+	"textDocument/codeAction":             HandleCodeAction,
+	"workspace/didChangeWatchedFiles":     HandleDidChangeWatchedFiles,
+	"workspace/didChangeWorkspaceFolders": HandleDidChangeWorkspaceFolders,
+	"workspace/didChangeConfiguration":    HandleDidChangeConfiguration,
+	"workspace/willRenameFiles":           HandleWillRenameFiles,
+	"workspace/didRenameFiles":            HandleDidRenameFiles,
 }
@@ -3,9 +3,9 @@ package protocol
 import (
 	"encoding/json"
 	"log/slog"
-	"slices"
 
 	"snakelsp/internal/messages"
+	"snakelsp/internal/progress"
 	"snakelsp/internal/request"
 	"snakelsp/internal/workspace"
 )
@@ -17,7 +17,7 @@ func HandleSymbolImplementation(r *request.Request) (any, error) {
 		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
 		return nil, err
 	}
-	workspaceFile, err := workspace.GetPythonFile(data.TextDocument.URI)
+	workspaceFile, err := r.Workspace().GetPythonFile(data.TextDocument.URI)
 	if err != nil {
 		r.Logger.Error("Error getting Python file: %v", slog.Any("error", err))
 		return nil, err
@@ -27,14 +27,19 @@ func HandleSymbolImplementation(r *request.Request) (any, error) {
 		r.Logger.Error("Error finding symbol by position: %v", slog.Any("error", err))
 		return nil, nil
 	}
+	pr := progress.FromParams(r.Client, data.WorkDoneProgressParams)
+	pr.Start("Finding implementations")
+	defer pr.End("")
+	implementations, err := symbol.Implementations(r.Context, r.Workspace().Settings().TransitiveImplementations)
+	if err != nil {
+		return nil, err
+	}
 	var response []messages.Location
-	for _, s := range workspace.FlatSymbols.AllFromFront() {
-		if slices.Contains(s.SuperObjects, symbol) {
-			response = append(response, messages.Location{
-				URI:   s.File.Url,
-				Range: s.NameRange,
-			})
-		}
+	for _, s := range implementations {
+		response = append(response, messages.Location{
+			URI:   s.File.Url,
+			Range: s.NameRange,
+		})
 	}
 	if len(response) == 1 {
 		return response[0], nil
@@ -2,7 +2,6 @@ package protocol
 
 import (
 	"encoding/json"
-	"fmt"
 	"log/slog"
 
 	"snakelsp/internal/messages"
@@ -18,23 +17,75 @@ func HandleInitialize(r *request.Request) (any, error) {
 		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
 		return nil, err
 	}
-	if data.RootPath == "" {
-		return nil, fmt.Errorf("rootPath is required")
+	r.Session.Initialize(data.Capabilities, data.InitializationOptions)
+	settings := workspace.NewSettings(data.InitializationOptions)
+	r.Session.SetLogLevel(settings.LogLevel)
+	w := workspace.NewWorkspace(settings, data.Folders())
+	w.SetPositionEncoding(data.Capabilities.PositionEncoding())
+	if data.InitializationOptions == nil || data.InitializationOptions.IndexCache == nil || *data.InitializationOptions.IndexCache {
+		w.SetIndexCacheDir(workspace.DefaultIndexCacheDir())
 	}
-	workspace.SetClientSettings(data.InitializationOptions.VirtualEnvPath, data.RootPath)
+	tracker := r.Session.Progress()
+	w.SetLibraryIndexProgress(func() *progress.WorkDone {
+		return tracker.NewWorkDone(r.Client)
+	})
+	if data.Capabilities.SupportsPullDiagnostics() {
+		if data.Capabilities.SupportsDiagnosticsRefresh() {
+			w.SetDiagnosticsRefresher(func() {
+				r.Client.Call("workspace/diagnostic/refresh", nil)
+			})
+		}
+	} else {
+		w.SetDiagnosticsPublisher(func(params messages.PublishDiagnosticsParams) {
+			r.Client.Notify("textDocument/publishDiagnostics", params)
+		})
+	}
+	r.Session.SetWorkspace(w)
 
+	initialized := r.Session.Initialized()
 	go func() {
-		filesProgress := progress.NewWorkDone(r.Client)
-		workspace.ParseProjectFiles(data.RootPath, data.InitializationOptions.VirtualEnvPath, filesProgress)
-		importsProgress := progress.NewWorkDone(r.Client)
-		workspace.BulkParseImports(importsProgress)
-		symbolsProgress := progress.NewWorkDone(r.Client)
-		workspace.BulkParseSymbols(symbolsProgress)
+		<-initialized
+		for _, folder := range w.Folders() {
+			indexFolder(r, w, folder)
+		}
+		w.PublishWorkspaceDiagnostics()
+		w.RefreshDiagnostics()
+		if useFileWatcher(&data) {
+			if err := w.StartFileWatcher(); err != nil {
+				slog.Error("Unable to start file watcher", slog.Any("error", err))
+			}
+		}
 	}()
 	initializeResult := messages.NewInitializeResult(&data)
 	return initializeResult, nil
 }
 
+func indexFolder(r *request.Request, w *workspace.Workspace, folder *workspace.WorkspaceFolder) {
+	indexProgress := r.Session.Progress().NewWorkDone(r.Client)
+	if err := w.IndexProject(folder.Root, folder.VirtualEnvPath, indexProgress); err != nil {
+		slog.Error("Unable to index project", slog.String("root", folder.Root), slog.Any("error", err))
+	}
+}
+
+func useFileWatcher(params *messages.InitializeParams) bool {
+	if params.InitializationOptions != nil && params.InitializationOptions.FileWatcher != nil {
+		return *params.InitializationOptions.FileWatcher
+	}
+	return !params.Capabilities.SupportsWatchedFilesRegistration()
+}
+
 func HandleInitialized(r *request.Request) (any, error) {
+	capabilities := r.Session.Capabilities()
+	if capabilities.SupportsConfigurationRegistration() {
+		registerConfiguration(r.Client)
+	}
+	// Pulled before indexing starts, to index with it.
+	if capabilities.SupportsConfiguration() {
+		pullConfiguration(r)
+	}
+	r.Session.SetInitialized()
+	if capabilities.SupportsWatchedFilesRegistration() {
+		registerFileWatchers(r.Client)
+	}
 	return any(nil), nil
 }
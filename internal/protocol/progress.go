@@ -0,0 +1,24 @@
+package protocol
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/request"
+)
+
+// HandleWorkDoneProgressCancel cancels a progress started by the server, e.g.
+// the indexing, when the user asks for it.
+func HandleWorkDoneProgressCancel(r *request.Request) (any, error) {
+	var data messages.WorkDoneProgressCancelParams
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	if !r.Session.Progress().Cancel(data.Token) {
+		r.Logger.Debug("Canceled progress not running", slog.Any("token", data.Token.Value))
+	}
+	return nil, nil
+}
@@ -0,0 +1,34 @@
+package protocol
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/request"
+)
+
+func HandleWillRenameFiles(r *request.Request) (any, error) {
+	var data messages.RenameFilesParams
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	edit := r.Workspace().RenameFilesEdit(data.Files)
+	if len(edit.Changes) == 0 {
+		return nil, nil
+	}
+	return edit, nil
+}
+
+func HandleDidRenameFiles(r *request.Request) (any, error) {
+	var data messages.RenameFilesParams
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	r.Workspace().MoveFiles(data.Files)
+	return nil, nil
+}
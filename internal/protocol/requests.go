@@ -1,7 +1,33 @@
 package protocol
 
-import "snakelsp/internal/request"
+import (
+	"encoding/json"
+	"log/slog"
 
+	"snakelsp/internal/messages"
+	"snakelsp/internal/request"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// HandleCancelRequest cancels the context of a request in flight, which
+// replies with RequestCancelled once its handler returns.
 func HandleCancelRequest(r *request.Request) (interface{}, error) {
+	var data messages.CancelParams
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	var id jsonrpc2.ID
+	switch value := data.ID.Value.(type) {
+	case messages.Integer:
+		id = jsonrpc2.ID{Num: uint64(value)}
+	case string:
+		id = jsonrpc2.ID{Str: value, IsString: true}
+	}
+	if !r.InFlight.Cancel(id) {
+		r.Logger.Debug("Cancelled request not in flight", slog.Any("id", data.ID.Value))
+	}
 	return nil, nil
 }
@@ -2,12 +2,15 @@ package protocol
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 
 	"snakelsp/internal/messages"
+	"snakelsp/internal/progress"
 	"snakelsp/internal/request"
 	"snakelsp/internal/workspace"
 
+	"github.com/google/uuid"
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 )
 
@@ -17,6 +20,9 @@ type caputredSymbol struct {
 	Capture    tree_sitter.QueryCapture
 }
 
+// Number of workspace symbols sent in a single `$/progress` partial result.
+const workspaceSymbolsBatchSize = 100
+
 func HandleWorkspaceSymbol(r *request.Request) (interface{}, error) {
 	response := []messages.WorkspaceSymbol{}
 	var data messages.WorkspaceSymbolParams
@@ -25,29 +31,89 @@ func HandleWorkspaceSymbol(r *request.Request) (interface{}, error) {
 		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
 		return nil, err
 	}
-	symbols, err := workspace.GetWorkspaceSymbols(data.Query)
-	limit := 100
-	if len(symbols) > limit {
-		symbols = symbols[:limit]
-	}
+	pr := progress.FromParams(r.Client, data.WorkDoneProgressParams)
+	pr.Start("Searching symbols")
+	defer pr.End("")
+	symbols, err := r.Workspace().GetWorkspaceSymbols(r.Context, data.Query)
 	if err != nil {
 		return nil, err
 	}
+	if limit := r.Workspace().Settings().WorkspaceSymbolLimit; limit > 0 && len(symbols) > limit {
+		symbols = symbols[:limit]
+	}
 
+	// Ranges are resolved when a symbol is picked, if the client can.
+	resolve := r.Session.Capabilities().SupportsWorkspaceSymbolResolve()
+	streaming := data.PartialResultToken != nil
+	flush := func() {
+		if len(response) == 0 {
+			return
+		}
+		r.Client.Notify("$/progress", messages.ProgressParams{
+			Token: data.PartialResultToken,
+			Value: response,
+		})
+		response = []messages.WorkspaceSymbol{}
+	}
 	for _, symbol := range symbols {
-		response = append(response, messages.WorkspaceSymbol{
+		if r.Context.Err() != nil {
+			return nil, r.Context.Err()
+		}
+		workspaceSymbol := messages.WorkspaceSymbol{
 			Name: symbol.SymbolNameWithParent(),
 			Kind: symbol.Kind,
-			Location: messages.Location{
+		}
+		if resolve {
+			workspaceSymbol.Location = messages.LocationUriOnly{URI: symbol.File.Url}
+			workspaceSymbol.Data = symbol.UUID.String()
+		} else {
+			workspaceSymbol.Location = messages.Location{
 				URI:   symbol.File.Url,
 				Range: symbol.NameRange,
-			},
-		})
+			}
+		}
+		response = append(response, workspaceSymbol)
+		if streaming && len(response) >= workspaceSymbolsBatchSize {
+			flush()
+		}
+	}
+	if streaming {
+		// Everything went through `$/progress`, the response itself stays empty.
+		flush()
 	}
-
 	return response, nil
 }
 
+// HandleWorkspaceSymbolResolve fills in the range of a workspace symbol sent
+// with only its URI.
+func HandleWorkspaceSymbolResolve(r *request.Request) (any, error) {
+	var data messages.WorkspaceSymbol
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	id, ok := data.Data.(string)
+	if !ok {
+		return nil, fmt.Errorf("workspace symbol without data")
+	}
+	symbolId, err := uuid.Parse(id)
+	if err != nil {
+		r.Logger.Error("Error parsing UUID: %v", slog.Any("error", err))
+		return nil, err
+	}
+	symbol, err := r.Workspace().SearchSymbolByUUID(symbolId)
+	if err != nil {
+		r.Logger.Error("Error searching symbol by UUID: %v", slog.Any("error", err))
+		return nil, err
+	}
+	data.Location = messages.Location{
+		URI:   symbol.File.Url,
+		Range: symbol.NameRange,
+	}
+	return data, nil
+}
+
 func HandleDocumentSybmol(r *request.Request) (interface{}, error) {
 	response := []messages.DocumentSymbol{}
 	var data messages.DocumentSymbolParams
@@ -56,7 +122,7 @@ func HandleDocumentSybmol(r *request.Request) (interface{}, error) {
 		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
 		return nil, err
 	}
-	pythonFile, err := workspace.GetPythonFile(data.TextDocument.URI)
+	pythonFile, err := r.Workspace().GetPythonFile(data.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,183 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/request"
+	"snakelsp/internal/workspace"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClient is the client end of the connection of test requests, it
+// records the `$/progress` notifications and replies to requests with null.
+type testClient struct {
+	mutex    sync.Mutex
+	progress []messages.ProgressParams
+}
+
+func (c *testClient) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) {
+	if !req.Notif {
+		conn.Reply(ctx, req.ID, nil)
+		return
+	}
+	if req.Method != "$/progress" {
+		return
+	}
+	var params messages.ProgressParams
+	if err := json.Unmarshal(*req.Params, &params); err == nil {
+		c.mutex.Lock()
+		c.progress = append(c.progress, params)
+		c.mutex.Unlock()
+	}
+}
+
+// wait waits for the client to handle everything the server sent so far.
+func (c *testClient) wait(t *testing.T, r *request.Request) {
+	t.Helper()
+	_, err := r.Client.Call("test/sync", nil)
+	require.NoError(t, err)
+}
+
+// newTestRequest creates a request of a session initialized with the
+// capabilities and the workspace, connected to client.
+func newTestRequest(t *testing.T, w *workspace.Workspace, capabilities string, client *testClient, params any) *request.Request {
+	t.Helper()
+	serverPipe, clientPipe := net.Pipe()
+	ctx := context.Background()
+	serverConn := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(serverPipe, jsonrpc2.VSCodeObjectCodec{}), jsonrpc2.HandlerWithError(func(context.Context, *jsonrpc2.Conn, *jsonrpc2.Request) (any, error) {
+		return nil, nil
+	}))
+	clientConn := jsonrpc2.NewConn(ctx, jsonrpc2.NewBufferedStream(clientPipe, jsonrpc2.VSCodeObjectCodec{}), client)
+	t.Cleanup(func() {
+		serverConn.Close()
+		clientConn.Close()
+	})
+
+	var clientCapabilities messages.ClientCapabilities
+	require.NoError(t, json.Unmarshal([]byte(capabilities), &clientCapabilities))
+	session := request.NewSession(nil)
+	session.Initialize(clientCapabilities, nil)
+	session.SetWorkspace(w)
+	data, err := json.Marshal(params)
+	require.NoError(t, err)
+	return &request.Request{
+		Params:  data,
+		Context: ctx,
+		Client:  &request.Client{Conn: serverConn, Context: ctx},
+		Logger:  slog.Default(),
+		Session: session,
+	}
+}
+
+// newIndexedWorkspace indexes a folder with count classes named Zebra000,
+// Zebra001 and so on.
+func newIndexedWorkspace(t *testing.T, count int, options *messages.InitializationOptionsParams) *workspace.Workspace {
+	t.Helper()
+	root := t.TempDir()
+	for i := range count {
+		content := fmt.Sprintf("class Zebra%03d:\n    pass\n", i)
+		require.NoError(t, os.WriteFile(filepath.Join(root, fmt.Sprintf("zebra%03d.py", i)), []byte(content), 0o644))
+	}
+	w := workspace.NewWorkspace(workspace.NewSettings(options), []messages.WorkspaceFolder{{URI: "file://" + root, Name: "test"}})
+	require.NoError(t, w.IndexProject(root, "", nil))
+	return w
+}
+
+func TestWorkspaceSymbolLimit(t *testing.T) {
+	limit := 3
+	w := newIndexedWorkspace(t, 5, &messages.InitializationOptionsParams{WorkspaceSymbolLimit: &limit})
+	client := &testClient{}
+	r := newTestRequest(t, w, `{}`, client, messages.WorkspaceSymbolParams{Query: "Zebra"})
+
+	response, err := HandleWorkspaceSymbol(r)
+	require.NoError(t, err)
+	symbols := response.([]messages.WorkspaceSymbol)
+	require.Len(t, symbols, 3)
+	assert.Equal(t, "Zebra000", symbols[0].Name)
+	location, ok := symbols[0].Location.(messages.Location)
+	require.True(t, ok)
+	assert.Equal(t, messages.Range{
+		Start: messages.Position{Line: 0, Character: 6},
+		End:   messages.Position{Line: 0, Character: 14},
+	}, location.Range)
+	assert.Nil(t, symbols[0].Data)
+}
+
+func TestWorkspaceSymbolStreaming(t *testing.T) {
+	noLimit := 0
+	w := newIndexedWorkspace(t, 250, &messages.InitializationOptionsParams{WorkspaceSymbolLimit: &noLimit})
+	client := &testClient{}
+	token := messages.ProgressToken{Value: "partial"}
+	params := messages.WorkspaceSymbolParams{Query: "Zebra"}
+	params.PartialResultToken = &token
+	r := newTestRequest(t, w, `{}`, client, params)
+
+	response, err := HandleWorkspaceSymbol(r)
+	require.NoError(t, err)
+	// Everything is sent as partial results
+	assert.Empty(t, response)
+	client.wait(t, r)
+	require.Len(t, client.progress, 3)
+	names := map[string]bool{}
+	for i, batch := range client.progress {
+		assert.Equal(t, "partial", batch.Token.Value)
+		var symbols []messages.WorkspaceSymbol
+		data, err := json.Marshal(batch.Value)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &symbols))
+		assert.Len(t, symbols, []int{workspaceSymbolsBatchSize, workspaceSymbolsBatchSize, 50}[i])
+		for _, symbol := range symbols {
+			names[symbol.Name] = true
+		}
+	}
+	assert.Len(t, names, 250)
+}
+
+func TestWorkspaceSymbolResolve(t *testing.T) {
+	w := newIndexedWorkspace(t, 2, nil)
+	capabilities := `{"workspace": {"symbol": {"resolveSupport": {"properties": ["location.range"]}}}}`
+	r := newTestRequest(t, w, capabilities, &testClient{}, messages.WorkspaceSymbolParams{Query: "Zebra001"})
+
+	response, err := HandleWorkspaceSymbol(r)
+	require.NoError(t, err)
+	symbols := response.([]messages.WorkspaceSymbol)
+	require.NotEmpty(t, symbols)
+	symbol := symbols[0]
+	assert.Equal(t, "Zebra001", symbol.Name)
+	// Only the URI until resolved
+	location, ok := symbol.Location.(messages.LocationUriOnly)
+	require.True(t, ok)
+	assert.Contains(t, location.URI, "zebra001.py")
+	require.IsType(t, "", symbol.Data)
+
+	resolved, err := HandleWorkspaceSymbolResolve(newTestRequest(t, w, capabilities, &testClient{}, symbol))
+	require.NoError(t, err)
+	resolvedSymbol := resolved.(messages.WorkspaceSymbol)
+	assert.Equal(t, "Zebra001", resolvedSymbol.Name)
+	assert.Equal(t, symbol.Data, resolvedSymbol.Data)
+	assert.Equal(t, messages.Location{
+		URI: location.URI,
+		Range: messages.Range{
+			Start: messages.Position{Line: 0, Character: 6},
+			End:   messages.Position{Line: 0, Character: 14},
+		},
+	}, resolvedSymbol.Location)
+
+	for _, data := range []any{nil, 42, "not-a-uuid", "00000000-0000-0000-0000-000000000000"} {
+		symbol.Data = data
+		_, err := HandleWorkspaceSymbolResolve(newTestRequest(t, w, capabilities, &testClient{}, symbol))
+		assert.Error(t, err, "data %v", data)
+	}
+}
@@ -17,7 +17,7 @@ func HandlePrepareTypeHierarchy(r *request.Request) (any, error) {
 		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
 		return nil, err
 	}
-	workspaceFile, err := workspace.GetPythonFile(data.TextDocument.URI)
+	workspaceFile, err := r.Workspace().GetPythonFile(data.TextDocument.URI)
 	if err != nil {
 		r.Logger.Error("Error getting Python file: %v", slog.Any("error", err))
 		return nil, err
@@ -51,7 +51,7 @@ func HandleTypeHierarchySuperTypes(r *request.Request) (any, error) {
 		r.Logger.Error("Error parsing UUID: %v", slog.Any("error", err))
 		return nil, err
 	}
-	symbol, err := workspace.SearchSymbolByUUID(symbolId)
+	symbol, err := r.Workspace().SearchSymbolByUUID(symbolId)
 	if err != nil {
 		r.Logger.Error("Error searching symbol by UUID: %v", slog.Any("error", err))
 		return nil, err
@@ -0,0 +1,45 @@
+package protocol
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/request"
+	"snakelsp/internal/workspace"
+)
+
+// registerFileWatchers asks the client to send `workspace/didChangeWatchedFiles`
+// for Python files and project configs, the protocol has no static
+// registration for it.
+func registerFileWatchers(client *request.Client) {
+	watchers := []messages.FileSystemWatcher{}
+	for _, glob := range workspace.WatchedFilesGlobs {
+		watchers = append(watchers, messages.FileSystemWatcher{GlobPattern: glob})
+	}
+	_, err := client.Call("client/registerCapability", messages.RegistrationParams{
+		Registrations: []messages.Registration{
+			{
+				ID:     "snakelsp-watched-files",
+				Method: "workspace/didChangeWatchedFiles",
+				RegisterOptions: messages.DidChangeWatchedFilesRegistrationOptions{
+					Watchers: watchers,
+				},
+			},
+		},
+	})
+	if err != nil {
+		slog.Error("Unable to register file watchers", slog.Any("error", err))
+	}
+}
+
+func HandleDidChangeWatchedFiles(r *request.Request) (any, error) {
+	var data messages.DidChangeWatchedFilesParams
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	r.Workspace().ApplyFileEvents(data.Changes)
+	return nil, nil
+}
@@ -0,0 +1,43 @@
+package protocol
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/request"
+	"snakelsp/internal/workspace"
+)
+
+func HandleDidChangeWorkspaceFolders(r *request.Request) (any, error) {
+	var data messages.DidChangeWorkspaceFoldersParams
+	err := json.Unmarshal(r.Params, &data)
+	if err != nil {
+		r.Logger.Error("Unmarshalling error: %v", slog.Any("error", err))
+		return nil, err
+	}
+	w := r.Workspace()
+	for _, folder := range data.Event.Removed {
+		w.RemoveWorkspaceFolder(folder.URI)
+	}
+	added := []*workspace.WorkspaceFolder{}
+	for _, folder := range data.Event.Added {
+		if workspaceFolder := w.AddWorkspaceFolder(folder.Name, folder.URI); workspaceFolder != nil {
+			added = append(added, workspaceFolder)
+		}
+	}
+	if err := w.RestartFileWatcher(); err != nil {
+		slog.Error("Unable to restart file watcher", slog.Any("error", err))
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	go func() {
+		for _, folder := range added {
+			indexFolder(r, w, folder)
+		}
+		w.PublishWorkspaceDiagnostics()
+		w.RefreshDiagnostics()
+	}()
+	return nil, nil
+}
@@ -0,0 +1,46 @@
+package request
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// InFlight tracks the requests being handled, so `$/cancelRequest` can
+// cancel their context.
+type InFlight struct {
+	mutex   sync.Mutex
+	cancels map[jsonrpc2.ID]context.CancelFunc
+}
+
+func NewInFlight() *InFlight {
+	return &InFlight{cancels: map[jsonrpc2.ID]context.CancelFunc{}}
+}
+
+// Start registers the request and returns its context, done has to be called
+// once the request is handled.
+func (f *InFlight) Start(ctx context.Context, id jsonrpc2.ID) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	f.mutex.Lock()
+	f.cancels[id] = cancel
+	f.mutex.Unlock()
+	return ctx, func() {
+		f.mutex.Lock()
+		delete(f.cancels, id)
+		f.mutex.Unlock()
+		cancel()
+	}
+}
+
+// Cancel cancels the context of the request, false when it's not in flight
+// anymore.
+func (f *InFlight) Cancel(id jsonrpc2.ID) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	cancel, ok := f.cancels[id]
+	if ok {
+		cancel()
+	}
+	return ok
+}
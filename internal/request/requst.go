@@ -8,6 +8,8 @@ import (
 	"encoding/json"
 	"log/slog"
 
+	"snakelsp/internal/workspace"
+
 	"github.com/sourcegraph/jsonrpc2"
 )
 
@@ -23,11 +25,18 @@ type Client struct {
 }
 
 type Request struct {
-	Method  string
-	Params  json.RawMessage
-	Context context.Context
-	Client  *Client
-	Logger  *slog.Logger
+	Method   string
+	Params   json.RawMessage
+	Context  context.Context // Canceled by `$/cancelRequest`
+	Client   *Client
+	Logger   *slog.Logger
+	InFlight *InFlight
+	Session  *Session
+}
+
+// Workspace is the workspace of the session the request belongs to.
+func (r *Request) Workspace() *workspace.Workspace {
+	return r.Session.Workspace()
 }
 
 func (c *Client) Notify(method string, params any) {
@@ -0,0 +1,100 @@
+package request
+
+import (
+	"log/slog"
+	"sync"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/progress"
+	"snakelsp/internal/workspace"
+)
+
+// Session holds the state of the connection: the workspace and what the
+// client sent with `initialize`, the progresses created by the server and
+// the level of the server logs.
+type Session struct {
+	mutex     sync.RWMutex
+	workspace *workspace.Workspace
+	logLevel  *slog.LevelVar
+
+	capabilities          *messages.ClientCapabilities
+	initializationOptions *messages.InitializationOptionsParams // The `snakelsp` section of the configuration overrides them
+	initialized           chan struct{}                         // Closed on `initialized`, requests can't be sent to the client before
+	progress              *progress.Tracker
+}
+
+func NewSession(logLevel *slog.LevelVar) *Session {
+	return &Session{
+		logLevel:     logLevel,
+		capabilities: &messages.ClientCapabilities{},
+		initialized:  make(chan struct{}),
+		progress:     progress.NewTracker(true),
+	}
+}
+
+func (s *Session) SetLogLevel(level slog.Level) {
+	if s.logLevel != nil {
+		s.logLevel.Set(level)
+	}
+}
+
+// Workspace returns the workspace of the session, nil before `initialize`.
+func (s *Session) Workspace() *workspace.Workspace {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.workspace
+}
+
+func (s *Session) SetWorkspace(w *workspace.Workspace) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.workspace = w
+}
+
+// Initialize records the capabilities and options the client sent with
+// `initialize`, the session waits for `initialized` again.
+func (s *Session) Initialize(capabilities messages.ClientCapabilities, options *messages.InitializationOptionsParams) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.capabilities = &capabilities
+	s.initializationOptions = options
+	s.initialized = make(chan struct{})
+	s.progress = progress.NewTracker(capabilities.SupportsWorkDoneProgress())
+}
+
+// Capabilities are the client capabilities, never modified.
+func (s *Session) Capabilities() *messages.ClientCapabilities {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.capabilities
+}
+
+func (s *Session) InitializationOptions() *messages.InitializationOptionsParams {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.initializationOptions
+}
+
+// Initialized is closed once the client sent `initialized`.
+func (s *Session) Initialized() <-chan struct{} {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.initialized
+}
+
+func (s *Session) SetInitialized() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	select {
+	case <-s.initialized:
+	default:
+		close(s.initialized)
+	}
+}
+
+// Progress tracks the progresses the server creates for the client.
+func (s *Session) Progress() *progress.Tracker {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.progress
+}
@@ -0,0 +1,58 @@
+package workspace
+
+// pythonBuiltins are names available in every module without an import, so
+// base classes like `Exception` or `object` never need to be resolved.
+var pythonBuiltins = map[string]bool{
+	"object": true, "type": true, "int": true, "float": true, "complex": true,
+	"bool": true, "str": true, "bytes": true, "bytearray": true, "memoryview": true,
+	"list": true, "tuple": true, "dict": true, "set": true, "frozenset": true,
+	"range": true, "slice": true, "property": true, "staticmethod": true,
+	"classmethod": true, "super": true, "enumerate": true, "zip": true, "map": true,
+	"filter": true, "reversed": true,
+
+	"BaseException": true, "BaseExceptionGroup": true, "Exception": true,
+	"ExceptionGroup": true, "ArithmeticError": true, "AssertionError": true,
+	"AttributeError": true, "BlockingIOError": true, "BrokenPipeError": true,
+	"BufferError": true, "ChildProcessError": true, "ConnectionAbortedError": true,
+	"ConnectionError": true, "ConnectionRefusedError": true,
+	"ConnectionResetError": true, "EOFError": true, "EnvironmentError": true,
+	"FileExistsError": true, "FileNotFoundError": true, "FloatingPointError": true,
+	"GeneratorExit": true, "IOError": true, "ImportError": true,
+	"IndentationError": true, "IndexError": true, "InterruptedError": true,
+	"IsADirectoryError": true, "KeyError": true, "KeyboardInterrupt": true,
+	"LookupError": true, "MemoryError": true, "ModuleNotFoundError": true,
+	"NameError": true, "NotADirectoryError": true, "NotImplementedError": true,
+	"OSError": true, "OverflowError": true, "PermissionError": true,
+	"ProcessLookupError": true, "RecursionError": true, "ReferenceError": true,
+	"RuntimeError": true, "StopAsyncIteration": true, "StopIteration": true,
+	"SyntaxError": true, "SystemError": true, "SystemExit": true, "TabError": true,
+	"TimeoutError": true, "TypeError": true, "UnboundLocalError": true,
+	"UnicodeDecodeError": true, "UnicodeEncodeError": true, "UnicodeError": true,
+	"UnicodeTranslateError": true, "ValueError": true, "ZeroDivisionError": true,
+
+	"Warning": true, "BytesWarning": true, "DeprecationWarning": true,
+	"EncodingWarning": true, "FutureWarning": true, "ImportWarning": true,
+	"PendingDeprecationWarning": true, "ResourceWarning": true,
+	"RuntimeWarning": true, "SyntaxWarning": true, "UnicodeWarning": true,
+	"UserWarning": true,
+}
+
+// builtinModules are compiled into the interpreter and have no file in any
+// of the ModulesPath entries.
+var builtinModules = map[string]bool{
+	"__future__": true, "_abc": true, "_ast": true, "_codecs": true,
+	"_collections": true, "_functools": true, "_imp": true, "_io": true,
+	"_locale": true, "_operator": true, "_signal": true, "_sre": true,
+	"_stat": true, "_string": true, "_symtable": true, "_thread": true,
+	"_tracemalloc": true, "_warnings": true, "_weakref": true,
+	"atexit": true, "builtins": true, "errno": true, "faulthandler": true,
+	"gc": true, "itertools": true, "marshal": true, "posix": true, "pwd": true,
+	"sys": true, "time": true, "xxsubtype": true,
+	// Usually shipped as lib-dynload extensions, but compiled in on some builds.
+	"math": true, "cmath": true, "select": true, "array": true, "binascii": true,
+	"zlib": true, "_socket": true, "_ssl": true, "_json": true, "_pickle": true,
+	"_datetime": true, "_random": true, "_struct": true, "_bisect": true,
+	"_heapq": true, "fcntl": true, "grp": true, "unicodedata": true,
+	// Aliased at import time rather than resolved through a file.
+	"os.path": true,
+}
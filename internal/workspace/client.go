@@ -9,25 +9,203 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync/atomic"
 )
 
 type ClientSettingsType struct {
+	Settings
+	Folders []*WorkspaceFolder
+}
+
+// WorkspaceFolder is a root of the workspace. Its files are indexed and their
+// imports resolved with the module paths of the folder's own virtualenv.
+type WorkspaceFolder struct {
+	Name           string
+	Root           string
 	VirtualEnvPath string
-	WorkspaceRoot  string
+	SourceRoots    []string // Folders of the project holding top-level packages, besides the root
+	ExtraPaths     []string // Module paths searched besides the virtualenv
+	Include        []string // Glob patterns of the Python files indexed, all when empty
+	Exclude        []string // Glob patterns of the files left out of the index
 	ModulesPath    []string
+
+	indexed   atomic.Bool // Set once IndexProject went through the folder
+	gitignore ignoreFiles
+}
+
+func newWorkspaceFolder(settings *ClientSettingsType, name, root string) *WorkspaceFolder {
+	folder := resolveWorkspaceFolder(settings, name, root)
+	folder.ModulesPath = folder.modulesPath()
+	return folder
+}
+
+// resolveWorkspaceFolder picks the options of the folder from the editor
+// settings and its project config, without computing its module paths.
+func resolveWorkspaceFolder(settings *ClientSettingsType, name, root string) *WorkspaceFolder {
+	root = filepath.Clean(root)
+	config := loadProjectConfig(root)
+	return &WorkspaceFolder{
+		Name:           name,
+		Root:           root,
+		VirtualEnvPath: folderVirtualEnvPath(settings, &config, name, root),
+		SourceRoots:    resolvePaths(root, editorOrProject(settings.SourceRoots, config.SourceRoots)),
+		ExtraPaths:     resolvePaths(root, editorOrProject(settings.ExtraPaths, config.ExtraPaths)),
+		Include:        editorOrProject(settings.Include, config.Include),
+		Exclude:        editorOrProject(settings.Exclude, config.Exclude),
+	}
+}
+
+// modulesPath computes the module paths of the folder: those of its
+// virtualenv, the folder sources and the extra paths.
+func (f *WorkspaceFolder) modulesPath() []string {
+	paths := calculateModulesPath(f.VirtualEnvPath, f.Root)
+	paths = append(paths, f.SourceRoots...)
+	return append(paths, f.ExtraPaths...)
+}
+
+// sameModulesPath reports whether the module paths computed for both folders
+// are the same.
+func (f *WorkspaceFolder) sameModulesPath(other *WorkspaceFolder) bool {
+	return f.VirtualEnvPath == other.VirtualEnvPath &&
+		slices.Equal(f.SourceRoots, other.SourceRoots) &&
+		slices.Equal(f.ExtraPaths, other.ExtraPaths)
+}
+
+// sourceRoots returns the module paths holding project modules: the folder
+// root, its source roots and the extra paths inside the folder.
+func (f *WorkspaceFolder) sourceRoots() []string {
+	roots := append([]string{f.Root}, f.SourceRoots...)
+	for _, path := range f.ExtraPaths {
+		if f.contains(path) {
+			roots = append(roots, path)
+		}
+	}
+	return roots
+}
+
+// folderVirtualEnvPath picks the virtualenv configured in the editor for the
+// folder, the default one, the one of the project config, or else a `.venv`
+// or `venv` one in the folder.
+func folderVirtualEnvPath(settings *ClientSettingsType, config *ProjectConfig, name, root string) string {
+	if path, ok := settings.VirtualEnvPaths[name]; ok {
+		return path
+	}
+	if path, ok := settings.VirtualEnvPaths[root]; ok {
+		return path
+	}
+	if settings.VirtualEnvPath != "" {
+		return settings.VirtualEnvPath
+	}
+	if config.VirtualEnvPath != "" {
+		return resolvePaths(root, []string{config.VirtualEnvPath})[0]
+	}
+	for _, venv := range []string{".venv", "venv"} {
+		if _, err := os.Stat(filepath.Join(root, venv, "pyvenv.cfg")); err == nil {
+			return filepath.Join(root, venv)
+		}
+	}
+	return ""
+}
+
+// AddWorkspaceFolder adds a folder to the workspace, nil when it's already
+// one of its folders.
+func (w *Workspace) AddWorkspaceFolder(name, uri string) *WorkspaceFolder {
+	w.settingsMutex.Lock()
+	defer w.settingsMutex.Unlock()
+	root := filepath.Clean(strings.TrimPrefix(uri, "file://"))
+	if slices.ContainsFunc(w.settings.Folders, func(f *WorkspaceFolder) bool { return f.Root == root }) {
+		return nil
+	}
+	folder := newWorkspaceFolder(&w.settings, name, root)
+	w.settings.Folders = append(slices.Clip(w.settings.Folders), folder)
+	return folder
+}
+
+// removeWorkspaceFolder drops a folder from the workspace, nil when it wasn't
+// one of its folders.
+func (w *Workspace) removeWorkspaceFolder(uri string) *WorkspaceFolder {
+	w.settingsMutex.Lock()
+	defer w.settingsMutex.Unlock()
+	root := filepath.Clean(strings.TrimPrefix(uri, "file://"))
+	i := slices.IndexFunc(w.settings.Folders, func(f *WorkspaceFolder) bool { return f.Root == root })
+	if i < 0 {
+		return nil
+	}
+	folder := w.settings.Folders[i]
+	w.settings.Folders = slices.Delete(slices.Clone(w.settings.Folders), i, i+1)
+	return folder
+}
+
+// Folders returns the folders of the workspace.
+func (w *Workspace) Folders() []*WorkspaceFolder {
+	w.settingsMutex.RLock()
+	defer w.settingsMutex.RUnlock()
+	return w.settings.Folders
+}
+
+// FolderOf returns the folder holding the path, the innermost one for nested
+// folders, nil when the path is outside of the workspace.
+func (w *Workspace) FolderOf(path string) *WorkspaceFolder {
+	var found *WorkspaceFolder
+	for _, folder := range w.Folders() {
+		if folder.contains(path) && (found == nil || len(folder.Root) > len(found.Root)) {
+			found = folder
+		}
+	}
+	return found
+}
+
+func (f *WorkspaceFolder) contains(path string) bool {
+	return path == f.Root || strings.HasPrefix(path, f.Root+"/")
+}
+
+// isFolderRoot reports whether the path is the root of a workspace folder.
+func (w *Workspace) isFolderRoot(path string) bool {
+	return slices.ContainsFunc(w.Folders(), func(f *WorkspaceFolder) bool { return f.Root == path })
 }
 
-var ClientSettings ClientSettingsType
+// isSourceRoot reports whether the path is one of the source roots of a
+// workspace folder, where the project modules are.
+func (w *Workspace) isSourceRoot(path string) bool {
+	return slices.ContainsFunc(w.Folders(), func(f *WorkspaceFolder) bool {
+		return slices.Contains(f.sourceRoots(), path)
+	})
+}
 
-func SetClientSettings(virtualEnvPath, workspaceRoot string) *ClientSettingsType {
-	settings := &ClientSettingsType{
-		VirtualEnvPath: virtualEnvPath,
-		WorkspaceRoot:  workspaceRoot,
-		ModulesPath:    calculateModulesPath(virtualEnvPath, workspaceRoot),
+// modulesPathFor returns the module paths imports of the file at path are
+// resolved with: those of its folder, or for a library file those of the
+// first folder using the library.
+func (w *Workspace) modulesPathFor(path string) []string {
+	if folder := w.FolderOf(path); folder != nil {
+		return folder.ModulesPath
 	}
-	ClientSettings = *settings
-	return settings
+	folders := w.Folders()
+	for _, folder := range folders {
+		for _, modulesPath := range folder.ModulesPath {
+			if strings.HasPrefix(path, modulesPath+"/") {
+				return folder.ModulesPath
+			}
+		}
+	}
+	if len(folders) > 0 {
+		return folders[0].ModulesPath
+	}
+	return nil
+}
+
+// allModulesPaths returns the module paths of every folder, once each.
+func (w *Workspace) allModulesPaths() []string {
+	paths := []string{}
+	for _, folder := range w.Folders() {
+		for _, modulesPath := range folder.ModulesPath {
+			if !slices.Contains(paths, modulesPath) {
+				paths = append(paths, modulesPath)
+			}
+		}
+	}
+	return paths
 }
 
 func calculateModulesPath(virtualEnvPath, worspaceRoot string) []string {
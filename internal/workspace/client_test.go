@@ -0,0 +1,135 @@
+package workspace
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceFolders(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"a/app.py":        "from lib import Thing\n\nprint(Thing)\n",
+		"a/nested/mod.py": "class Nested:\n    pass\n",
+		"a/venv/lib/python3.12/site-packages/lib.py": "class Thing:\n    pass\n",
+		"b/app.py":      "from lib import Thing\n\nprint(Thing)\n",
+		"b-site/lib.py": "class Other:\n    pass\n",
+	})
+	a, b := filepath.Join(root, "a"), filepath.Join(root, "b")
+	sitePackages := filepath.Join(a, "venv/lib/python3.12/site-packages")
+	w.settings.Folders = []*WorkspaceFolder{
+		{Name: "a", Root: a, VirtualEnvPath: filepath.Join(a, "venv"), ModulesPath: []string{sitePackages, a}},
+		{Name: "b", Root: b, ModulesPath: []string{filepath.Join(root, "b-site"), b}},
+		{Name: "nested", Root: filepath.Join(a, "nested"), ModulesPath: []string{filepath.Join(a, "nested")}},
+	}
+	require.NoError(t, w.IndexProject(a, filepath.Join(a, "venv"), nil))
+	require.NoError(t, w.IndexProject(b, "", nil))
+
+	assert.Equal(t, "nested", w.FolderOf(filepath.Join(a, "nested/mod.py")).Name)
+	assert.Equal(t, "a", w.FolderOf(filepath.Join(a, "app.py")).Name)
+	assert.Nil(t, w.FolderOf(filepath.Join(root, "b-site/lib.py")))
+	assert.True(t, w.IsProjectFile("file://"+filepath.Join(a, "app.py")))
+	assert.False(t, w.IsProjectFile("file://"+filepath.Join(sitePackages, "lib.py")))
+	assert.False(t, w.IsProjectFile("file:///elsewhere/app.py"))
+
+	// Nested folders are indexed on their own
+	_, err := w.GetPythonFile("file://" + filepath.Join(a, "nested/mod.py"))
+	assert.Error(t, err)
+
+	// Imports are resolved with the module paths of the folder
+	appA, err := w.GetPythonFile("file://" + filepath.Join(a, "app.py"))
+	require.NoError(t, err)
+	assert.Empty(t, diagnosticCodes(appA))
+	appB, err := w.GetPythonFile("file://" + filepath.Join(b, "app.py"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{DiagnosticCodeUnknownImportSymbol}, diagnosticCodes(appB))
+
+	w.RemoveWorkspaceFolder("file://" + b)
+	assert.Len(t, w.Folders(), 2)
+	_, err = w.GetPythonFile(appB.Url)
+	assert.Error(t, err)
+	assert.False(t, w.IsProjectFile(appB.Url))
+}
+
+func TestCrossFolderImports(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"a/app.py":    "from shared import Thing\n\nprint(Thing)\n",
+		"b/shared.py": "class Thing:\n    pass\n",
+	})
+	a, b := filepath.Join(root, "a"), filepath.Join(root, "b")
+	folderA := &WorkspaceFolder{Name: "a", Root: a, ModulesPath: []string{a, b}}
+	folderB := &WorkspaceFolder{Name: "b", Root: b, ModulesPath: []string{b}}
+	sharedUrl := "file://" + filepath.Join(b, "shared.py")
+	thing := func() *Symbol {
+		for _, symbol := range w.ProjectSymbols() {
+			if symbol.Name == "Thing" {
+				return symbol
+			}
+		}
+		return nil
+	}
+
+	// Files of another folder are project files when imported
+	w.settings.Folders = []*WorkspaceFolder{folderA, folderB}
+	require.NoError(t, w.IndexProject(a, "", nil))
+	shared, err := w.GetPythonFile(sharedUrl)
+	require.NoError(t, err)
+	assert.False(t, shared.External)
+	require.NoError(t, w.IndexProject(b, "", nil))
+	appA, err := w.GetPythonFile("file://" + filepath.Join(a, "app.py"))
+	require.NoError(t, err)
+	require.NotNil(t, thing())
+	assert.Same(t, thing(), appA.Imports[0].Symbol)
+
+	// External files become project files when their folder is added
+	w = newTestWorkspace(folderA)
+	require.NoError(t, w.IndexProject(a, "", nil))
+	shared, err = w.GetPythonFile(sharedUrl)
+	require.NoError(t, err)
+	assert.True(t, shared.External)
+	assert.Nil(t, thing())
+	w.settings.Folders = append(w.settings.Folders, folderB)
+	require.NoError(t, w.IndexProject(b, "", nil))
+	assert.False(t, shared.External)
+	appA, err = w.GetPythonFile("file://" + filepath.Join(a, "app.py"))
+	require.NoError(t, err)
+	require.NotNil(t, thing())
+	assert.Same(t, thing(), appA.Imports[0].Symbol)
+	assert.Empty(t, diagnosticCodes(appA))
+}
+
+func TestRemoveWorkspaceFolderWhileOpening(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{})
+	folder := w.settings.Folders[0]
+	url := "file://" + filepath.Join(root, "app.py")
+	for range 50 {
+		w.settings.Folders = []*WorkspaceFolder{folder}
+		w.NewPythonFile(url, "x = 1\n", false, false)
+		var wg sync.WaitGroup
+		var opened *PythonFile
+		start := make(chan struct{})
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			w.RemoveWorkspaceFolder("file://" + root)
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			opened = w.OpenFile(url, "x = 2\n", 1, true)
+		}()
+		close(start)
+		wg.Wait()
+
+		// The opened file stays, as an external one
+		stored, err := w.GetPythonFile(url)
+		require.NoError(t, err)
+		assert.Same(t, opened, stored)
+		assert.True(t, stored.External)
+		stored.CloseFile()
+		stored.remove()
+	}
+}
@@ -0,0 +1,292 @@
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"snakelsp/internal/messages"
+)
+
+const diagnosticsSource = "snakelsp"
+
+const (
+	DiagnosticCodeUnresolvedImport    = "unresolved-import"
+	DiagnosticCodeUnknownImportSymbol = "unknown-import-symbol"
+	DiagnosticCodeUnresolvedBaseClass = "unresolved-base-class"
+
+	DiagnosticCodeOverrideMethodKind        = "override-method-kind"
+	DiagnosticCodeOverrideAsyncMismatch     = "override-async-mismatch"
+	DiagnosticCodeOverrideMissingParameter  = "override-missing-parameter"
+	DiagnosticCodeOverrideRenamedParameter  = "override-renamed-parameter"
+	DiagnosticCodeOverrideDroppedVariadic   = "override-dropped-variadic"
+	DiagnosticCodeOverrideRequiredParameter = "override-required-parameter"
+
+	DiagnosticCodeUnusedImport        = "unused-import"
+	DiagnosticCodeDuplicateDefinition = "duplicate-definition"
+)
+
+// DiagnosticsPublisher delivers diagnostics of a single file to the client.
+type DiagnosticsPublisher func(params messages.PublishDiagnosticsParams)
+
+// SetDiagnosticsPublisher sets where PublishDiagnostics sends its results.
+// Until it's set diagnostics are computed on request only.
+func (w *Workspace) SetDiagnosticsPublisher(publisher DiagnosticsPublisher) {
+	w.diagnosticsPublisher = publisher
+}
+
+// DiagnosticsRefresher asks a client that pulls diagnostics to pull them
+// again, used when a change in one file affects diagnostics of others.
+type DiagnosticsRefresher func()
+
+// SetDiagnosticsRefresher sets what RefreshDiagnostics calls.
+func (w *Workspace) SetDiagnosticsRefresher(refresher DiagnosticsRefresher) {
+	w.diagnosticsRefresher = refresher
+}
+
+// RefreshDiagnostics asks the client to pull diagnostics again, if it
+// supports it.
+func (w *Workspace) RefreshDiagnostics() {
+	if w.diagnosticsRefresher != nil {
+		w.diagnosticsRefresher()
+	}
+}
+
+// diagnosticPass inspects a single file and reports what it found wrong.
+type diagnosticPass func(f *PythonFile) []messages.Diagnostic
+
+var diagnosticPasses = []diagnosticPass{
+	importDiagnostics,
+	baseClassDiagnostics,
+	overrideDiagnostics,
+	unusedImportDiagnostics,
+	duplicateDefinitionDiagnostics,
+}
+
+// Diagnostics runs every diagnostic pass over the file, keeping the codes
+// the settings don't disable. External files are never checked.
+func (f *PythonFile) Diagnostics() []messages.Diagnostic {
+	diagnostics := []messages.Diagnostic{}
+	settings := f.workspace.Settings().Diagnostics
+	if f.External || !settings.Enabled {
+		return diagnostics
+	}
+	for _, pass := range diagnosticPasses {
+		for _, diagnostic := range pass(f) {
+			if settings.reports(diagnostic.Code) {
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+	}
+	return diagnostics
+}
+
+// PublishDiagnostics sends the file diagnostics to the client, including an
+// empty list so the stale ones get cleared.
+func (f *PythonFile) PublishDiagnostics() {
+	if f.workspace.diagnosticsPublisher == nil || f.External {
+		return
+	}
+	f.workspace.diagnosticsPublisher(messages.PublishDiagnosticsParams{
+		URI:         f.Url,
+		Diagnostics: f.Diagnostics(),
+	})
+}
+
+// PublishWorkspaceDiagnostics publishes diagnostics for every project file
+// that has any. Files without problems are skipped to keep startup quiet on
+// big projects.
+func (w *Workspace) PublishWorkspaceDiagnostics() {
+	if w.diagnosticsPublisher == nil {
+		return
+	}
+	slog.Debug("Publishing workspace diagnostics")
+	w.Files.Range(func(key, value any) bool {
+		file := value.(*PythonFile)
+		if file.External {
+			return true
+		}
+		diagnostics := file.Diagnostics()
+		if len(diagnostics) == 0 {
+			return true
+		}
+		w.diagnosticsPublisher(messages.PublishDiagnosticsParams{
+			URI:         file.Url,
+			Diagnostics: diagnostics,
+		})
+		return true
+	})
+}
+
+// DiagnosticsResultID identifies a set of diagnostics for the pull model:
+// equal sets get equal ids, so an unchanged file can be reported as such.
+func DiagnosticsResultID(diagnostics []messages.Diagnostic) string {
+	data, err := json.Marshal(diagnostics)
+	if err != nil {
+		slog.Error("Unable to marshal diagnostics", slog.Any("error", err))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Dependents returns project files with imports pointing to f, including
+// the ones which failed to find a name in it.
+func (f *PythonFile) Dependents() []*PythonFile {
+	dependents := []*PythonFile{}
+	f.workspace.Files.Range(func(key, value any) bool {
+		file := value.(*PythonFile)
+		if file == f || file.External {
+			return true
+		}
+		for _, imp := range file.loadedImports() {
+			var nameErr *ImportedNameError
+			if imp.PythonFile == f || (imp.Symbol != nil && imp.Symbol.File == f) ||
+				(errors.As(imp.ResolveError, &nameErr) && nameErr.File == f) {
+				dependents = append(dependents, file)
+				break
+			}
+		}
+		return true
+	})
+	slices.SortFunc(dependents, func(a, b *PythonFile) int {
+		return strings.Compare(a.Url, b.Url)
+	})
+	return dependents
+}
+
+// diagnosticsChanged publishes fresh diagnostics of the changed file and its
+// dependents. Pulling clients may have pulled the changed file before its
+// update, they're refreshed when its diagnostics differ from the ones after
+// the previous update, or when other files are affected.
+func diagnosticsChanged(changed *PythonFile, dependents []*PythonFile) {
+	changed.PublishDiagnostics()
+	for _, dependent := range dependents {
+		dependent.PublishDiagnostics()
+	}
+	if changed.workspace.diagnosticsRefresher == nil {
+		return
+	}
+	resultID := DiagnosticsResultID(changed.Diagnostics())
+	refresh := len(dependents) > 0 || resultID != changed.diagnosticsResultID
+	changed.diagnosticsResultID = resultID
+	if refresh {
+		changed.workspace.RefreshDiagnostics()
+	}
+}
+
+func searchPathsInformation(searchPaths []string) []messages.DiagnosticRelatedInformation {
+	information := []messages.DiagnosticRelatedInformation{}
+	for _, path := range searchPaths {
+		information = append(information, messages.DiagnosticRelatedInformation{
+			Location: messages.Location{URI: "file://" + path},
+			Message:  fmt.Sprintf("Searched in %s", path),
+		})
+	}
+	return information
+}
+
+func importDiagnostics(f *PythonFile) []messages.Diagnostic {
+	diagnostics := []messages.Diagnostic{}
+	imports, err := f.GetImports()
+	if err != nil {
+		slog.Warn("Unable to get imports for diagnostics", slog.String("file", f.Url), slog.Any("error", err))
+		return diagnostics
+	}
+	for _, imp := range imports {
+		var moduleErr *ModuleNotFoundError
+		var nameErr *ImportedNameError
+		switch {
+		case errors.As(imp.ResolveError, &moduleErr):
+			diagnostics = append(diagnostics, messages.Diagnostic{
+				Range:              imp.ModuleRange,
+				Severity:           messages.DiagnosticSeverityWarning,
+				Code:               DiagnosticCodeUnresolvedImport,
+				Source:             diagnosticsSource,
+				Message:            fmt.Sprintf("Import %q could not be resolved", moduleErr.Module),
+				RelatedInformation: searchPathsInformation(moduleErr.SearchPaths),
+			})
+		case errors.As(imp.ResolveError, &nameErr):
+			diagnostics = append(diagnostics, messages.Diagnostic{
+				Range:    imp.NameRange,
+				Severity: messages.DiagnosticSeverityWarning,
+				Code:     DiagnosticCodeUnknownImportSymbol,
+				Source:   diagnosticsSource,
+				Message:  fmt.Sprintf("%q is not defined in module %q", nameErr.Name, nameErr.Module),
+				RelatedInformation: []messages.DiagnosticRelatedInformation{
+					{
+						Location: messages.Location{URI: nameErr.File.Url},
+						Message:  fmt.Sprintf("Module %q resolved to this file", nameErr.Module),
+					},
+				},
+			})
+		}
+	}
+	return diagnostics
+}
+
+func baseClassDiagnostics(f *PythonFile) []messages.Diagnostic {
+	diagnostics := []messages.Diagnostic{}
+	symbols, err := f.FileSymbols("")
+	if err != nil {
+		return diagnostics
+	}
+	imports, err := f.GetImports()
+	if err != nil {
+		return diagnostics
+	}
+	names, dynamic := f.ModuleNames()
+	for _, symbol := range symbols {
+		if symbol.Kind != messages.SymbolKindClass {
+			continue
+		}
+		for i, superClassName := range symbol.superObjectsNames {
+			if pythonBuiltins[superClassName] || resolveSuperclassName(f, superClassName) != nil {
+				continue
+			}
+			diagnostic := messages.Diagnostic{
+				Range:    symbol.NameRange,
+				Severity: messages.DiagnosticSeverityWarning,
+				Code:     DiagnosticCodeUnresolvedBaseClass,
+				Source:   diagnosticsSource,
+			}
+			if i < len(symbol.superObjectsRanges) {
+				diagnostic.Range = symbol.superObjectsRanges[i]
+			}
+			var imp *Import
+			for j := range imports {
+				if imports[j].BoundName() == superClassName {
+					imp = &imports[j]
+				}
+			}
+			switch {
+			case imp != nil:
+				// Imported names we resolved to a file but not to a class
+				// (variables, dynamic attributes) are fine.
+				if imp.ResolveError == nil {
+					continue
+				}
+				diagnostic.Message = fmt.Sprintf("Base class %q could not be resolved", superClassName)
+				diagnostic.RelatedInformation = append(diagnostic.RelatedInformation, messages.DiagnosticRelatedInformation{
+					Location: messages.Location{URI: f.Url, Range: imp.ModuleRange},
+					Message:  fmt.Sprintf("Imported from %q", imp.SourceModule),
+				})
+				var moduleErr *ModuleNotFoundError
+				if errors.As(imp.ResolveError, &moduleErr) {
+					diagnostic.RelatedInformation = append(diagnostic.RelatedInformation, searchPathsInformation(moduleErr.SearchPaths)...)
+				}
+			case names[superClassName] || dynamic:
+				// Defined by an assignment, e.g. `Base = declarative_base()`.
+				continue
+			default:
+				diagnostic.Message = fmt.Sprintf("Base class %q is not defined", superClassName)
+			}
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,206 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"snakelsp/internal/messages"
+	"snakelsp/pkg/debounce"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestWorkspace creates a workspace of the folders, without looking for
+// their virtualenv.
+func newTestWorkspace(folders ...*WorkspaceFolder) *Workspace {
+	w := NewWorkspace(NewSettings(nil), nil)
+	w.settings.Folders = folders
+	return w
+}
+
+// setupModulesPath writes the files in a temporary folder and returns a
+// workspace with that folder as its only module path.
+func setupModulesPath(t *testing.T, files map[string]string) (*Workspace, string) {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return newTestWorkspace(&WorkspaceFolder{Root: root, ModulesPath: []string{root}}), root
+}
+
+func diagnosticCodes(f *PythonFile) []string {
+	codes := []string{}
+	for _, diagnostic := range f.Diagnostics() {
+		codes = append(codes, diagnostic.Code)
+	}
+	return codes
+}
+
+func TestImportDiagnostics(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"pkg/mod.py": "class Base:\n    pass\n\nCONST = 1\n",
+	})
+	mockFile := &PythonFile{
+		workspace: w,
+		Url:       "file://" + filepath.Join(root, "main.py"),
+		Text: `import missing_module
+import os.path
+from pkg import mod
+from pkg.mod import Base, CONST, Nope
+
+print(missing_module, os.path, mod, Base, CONST, Nope)
+`,
+	}
+
+	diagnostics := mockFile.Diagnostics()
+	assert.Len(t, diagnostics, 2)
+
+	assert.Equal(t, DiagnosticCodeUnresolvedImport, diagnostics[0].Code)
+	assert.Equal(t, uint32(0), diagnostics[0].Range.Start.Line)
+	assert.Equal(t, uint32(7), diagnostics[0].Range.Start.Character)
+	assert.Len(t, diagnostics[0].RelatedInformation, 1)
+	assert.Equal(t, "file://"+root, diagnostics[0].RelatedInformation[0].Location.URI)
+
+	assert.Equal(t, DiagnosticCodeUnknownImportSymbol, diagnostics[1].Code)
+	assert.Equal(t, uint32(3), diagnostics[1].Range.Start.Line)
+	assert.Contains(t, diagnostics[1].Message, "Nope")
+}
+
+func TestBaseClassDiagnostics(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"pkg/mod.py": "class Base:\n    pass\n\nAlias = Base\n",
+	})
+	mockFile := &PythonFile{
+		workspace: w,
+		Url:       "file://" + filepath.Join(root, "models.py"),
+		Text: `from pkg.mod import Base, Alias
+from missing import Gone
+
+Declarative = make_base()
+
+class Local:
+    pass
+
+class A(Base, Local):
+    pass
+
+class B(Exception, Alias, Declarative):
+    pass
+
+class C(Unknown):
+    pass
+
+class D(Gone):
+    pass
+`,
+	}
+
+	codes := diagnosticCodes(mockFile)
+	assert.ElementsMatch(t, []string{
+		DiagnosticCodeUnresolvedImport,
+		DiagnosticCodeUnresolvedBaseClass,
+		DiagnosticCodeUnresolvedBaseClass,
+	}, codes)
+
+	for _, diagnostic := range mockFile.Diagnostics() {
+		if diagnostic.Code != DiagnosticCodeUnresolvedBaseClass {
+			continue
+		}
+		switch diagnostic.Range.Start.Line {
+		case 14:
+			assert.Contains(t, diagnostic.Message, "is not defined")
+		case 17:
+			assert.Contains(t, diagnostic.Message, "could not be resolved")
+			assert.Len(t, diagnostic.RelatedInformation, 2)
+		default:
+			t.Errorf("unexpected diagnostic at line %d", diagnostic.Range.Start.Line)
+		}
+	}
+}
+
+func TestDiagnosticsOfDependentsOnChange(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"base.py":  "class Base:\n    pass\n",
+		"child.py": "from base import Base\n\nclass Child(Base):\n    pass\n",
+	})
+	base, err := w.ImportPythonFileFromFile(filepath.Join(root, "base.py"), false)
+	require.NoError(t, err)
+	child, err := w.ImportPythonFileFromFile(filepath.Join(root, "child.py"), false)
+	require.NoError(t, err)
+	_, err = child.ParseImports()
+	require.NoError(t, err)
+	_, err = child.parseSymbols()
+	require.NoError(t, err)
+	assert.Empty(t, child.Diagnostics())
+	assert.Equal(t, []*PythonFile{child}, base.Dependents())
+
+	published := map[string]string{}
+	refreshed := 0
+	w.SetDiagnosticsPublisher(func(params messages.PublishDiagnosticsParams) {
+		published[params.URI] = DiagnosticsResultID(params.Diagnostics)
+	})
+	w.SetDiagnosticsRefresher(func() { refreshed++ })
+
+	base.Text = "class Renamed:\n    pass\n"
+	base.parseOnUpdate()
+
+	diagnostics := child.Diagnostics()
+	assert.Equal(t, []string{DiagnosticCodeUnknownImportSymbol, DiagnosticCodeUnresolvedBaseClass}, diagnosticCodes(child))
+	assert.Equal(t, DiagnosticsResultID(diagnostics), published[child.Url])
+	assert.Contains(t, published, base.Url)
+	assert.Equal(t, 1, refreshed)
+	assert.NotEqual(t, DiagnosticsResultID(diagnostics), DiagnosticsResultID(nil))
+}
+
+func TestDiagnosticsAfterFlushUpdate(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{"helpers.py": "", "other.py": ""})
+	file := w.OpenFile("file://"+filepath.Join(root, "main.py"), "import helpers\nimport other\n\nprint(other)\n", 1, false)
+	file.debouncer = debounce.NewDebounce(time.Hour)
+	t.Cleanup(func() { file.CloseFile() })
+	_, err := file.ParseImports()
+	require.NoError(t, err)
+
+	require.NoError(t, file.ApplyChange(2, []messages.TextDocumentContentChangeEvent{textChange(0, 0, 0, 0, "\"\"\"Doc.\"\"\"\n\n")}))
+	// The imports are parsed again once the typing pauses
+	diagnostics := file.Diagnostics()
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, uint32(0), diagnostics[0].Range.Start.Line)
+
+	file.FlushUpdate()
+	diagnostics = file.Diagnostics()
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, DiagnosticCodeUnusedImport, diagnostics[0].Code)
+	assert.Equal(t, messages.Range{
+		Start: messages.Position{Line: 2, Character: 7},
+		End:   messages.Position{Line: 2, Character: 14},
+	}, diagnostics[0].Range)
+	assert.False(t, file.debouncer.Stop())
+}
+
+func TestDiagnosticsRefreshedOnChange(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{"helpers.py": ""})
+	file := w.OpenFile("file://"+filepath.Join(root, "main.py"), "import helpers\n", 1, false)
+	file.debouncer = debounce.NewDebounce(time.Hour)
+	t.Cleanup(func() { file.CloseFile() })
+	refreshed := 0
+	w.SetDiagnosticsRefresher(func() { refreshed++ })
+
+	file.parseOnUpdate()
+	assert.Equal(t, 1, refreshed)
+
+	// Same diagnostics, the client has them already
+	require.NoError(t, file.ApplyChange(2, []messages.TextDocumentContentChangeEvent{textChange(1, 0, 1, 0, "\n")}))
+	file.FlushUpdate()
+	assert.Equal(t, 1, refreshed)
+
+	require.NoError(t, file.ApplyChange(3, []messages.TextDocumentContentChangeEvent{textChange(1, 0, 1, 0, "print(helpers)\n")}))
+	file.FlushUpdate()
+	assert.Empty(t, file.Diagnostics())
+	assert.Equal(t, 2, refreshed)
+}
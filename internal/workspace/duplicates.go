@@ -0,0 +1,110 @@
+package workspace
+
+import (
+	"fmt"
+	"strings"
+
+	"snakelsp/internal/messages"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+type scopeDefinition struct {
+	name       string
+	kind       string
+	nameNode   *tree_sitter.Node
+	decorators []string
+}
+
+func duplicateDefinitionDiagnostics(f *PythonFile) []messages.Diagnostic {
+	diagnostics := []messages.Diagnostic{}
+	ast := f.GetOrCreateAst()
+	defer ast.Close()
+	checkScopeDuplicates(f.Url, ast, ast.Root, false, &diagnostics)
+	return diagnostics
+}
+
+// checkScopeDuplicates reports definitions of the scope shadowed by a later
+// definition with the same name, then descends into nested class and
+// function scopes.
+func checkScopeDuplicates(url string, ast *Ast, scope *tree_sitter.Node, classScope bool, diagnostics *[]messages.Diagnostic) {
+	source := ast.Source
+	definitions := []scopeDefinition{}
+	for i := uint(0); i < scope.NamedChildCount(); i++ {
+		definition := scope.NamedChild(i)
+		if definition.Kind() == "decorated_definition" {
+			definition = definition.ChildByFieldName("definition")
+		}
+		if definition == nil || (definition.Kind() != "function_definition" && definition.Kind() != "class_definition") {
+			continue
+		}
+		nameNode := definition.ChildByFieldName("name")
+		if nameNode == nil {
+			continue
+		}
+		kind := "Class"
+		if definition.Kind() == "function_definition" {
+			kind = "Function"
+			if classScope {
+				kind = "Method"
+			}
+		}
+		definitions = append(definitions, scopeDefinition{
+			name:       nameNode.Utf8Text(source),
+			kind:       kind,
+			nameNode:   nameNode,
+			decorators: functionDecorators(definition, source),
+		})
+		if body := definition.ChildByFieldName("body"); body != nil {
+			checkScopeDuplicates(url, ast, body, definition.Kind() == "class_definition", diagnostics)
+		}
+	}
+
+	for i, shadowed := range definitions {
+		for _, redefinition := range definitions[i+1:] {
+			if redefinition.name != shadowed.name {
+				continue
+			}
+			if !isShadowingDefinition(shadowed, redefinition) {
+				break
+			}
+			redefinitionRange := ast.NodeRange(redefinition.nameNode)
+			*diagnostics = append(*diagnostics, messages.Diagnostic{
+				Range:    ast.NodeRange(shadowed.nameNode),
+				Severity: messages.DiagnosticSeverityWarning,
+				Code:     DiagnosticCodeDuplicateDefinition,
+				Source:   diagnosticsSource,
+				Message:  fmt.Sprintf("%s %q is redefined on line %d", shadowed.kind, shadowed.name, redefinitionRange.Start.Line+1),
+				Tags:     []messages.DiagnosticTag{messages.DiagnosticTagUnnecessary},
+				RelatedInformation: []messages.DiagnosticRelatedInformation{
+					{
+						Location: messages.Location{URI: url, Range: redefinitionRange},
+						Message:  fmt.Sprintf("%q redefined here", shadowed.name),
+					},
+				},
+			})
+			break
+		}
+	}
+}
+
+// isShadowingDefinition filters out the patterns that redefine a name on
+// purpose: overloads, property accessors and `_` placeholders.
+func isShadowingDefinition(shadowed, redefinition scopeDefinition) bool {
+	if shadowed.name == "_" {
+		return false
+	}
+	for _, decorators := range [][]string{shadowed.decorators, redefinition.decorators} {
+		for _, decorator := range decorators {
+			if decorator == "overload" || strings.HasSuffix(decorator, ".overload") {
+				return false
+			}
+		}
+	}
+	for _, decorator := range redefinition.decorators {
+		if strings.HasPrefix(decorator, redefinition.name+".") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,234 @@
+package workspace
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"snakelsp/internal/messages"
+	"snakelsp/pkg/debounce"
+	"snakelsp/pkg/watcher"
+)
+
+const (
+	// Quiet period after the last event before the collected ones are
+	// reindexed in a single batch.
+	fileWatcherDelay = 300 * time.Millisecond
+	// Events buffered between the watcher and the collector.
+	fileWatcherQueueSize = 1024
+	// Events collected for a single batch, above it the watched trees are
+	// rescanned instead.
+	maxPendingFileEvents = 10000
+)
+
+// StartFileWatcher watches the workspace folders and their virtualenv
+// site-packages on the server side, for clients which can't send
+// `workspace/didChangeWatchedFiles`. Changes go through ApplyFileEvents.
+func (w *Workspace) StartFileWatcher() error {
+	w.fileWatcherMutex.Lock()
+	defer w.fileWatcherMutex.Unlock()
+	return w.startFileWatcher()
+}
+
+func (w *Workspace) startFileWatcher() error {
+	fileWatcher, err := watcher.New(w.fileWatcherRoots(), w.skipWatchedDir, fileWatcherQueueSize)
+	if err != nil {
+		return err
+	}
+	w.fileWatcher = fileWatcher
+	w.collectorDone = make(chan struct{})
+	go func() {
+		w.collectFileEvents(fileWatcher.Events, fileWatcherDelay)
+		close(w.collectorDone)
+	}()
+	slog.Info("File watcher started", slog.Any("roots", w.fileWatcherRoots()))
+	return nil
+}
+
+// StopFileWatcher stops watching, reindexes the events collected so far and
+// waits for the reindex.
+func (w *Workspace) StopFileWatcher() {
+	w.fileWatcherMutex.Lock()
+	defer w.fileWatcherMutex.Unlock()
+	w.stopFileWatcher()
+}
+
+func (w *Workspace) stopFileWatcher() {
+	if w.fileWatcher != nil {
+		w.fileWatcher.Close()
+		<-w.collectorDone
+		w.fileWatcher = nil
+	}
+}
+
+// RestartFileWatcher watches the folders of the workspace again after they
+// changed, when the watcher is running.
+func (w *Workspace) RestartFileWatcher() error {
+	w.fileWatcherMutex.Lock()
+	defer w.fileWatcherMutex.Unlock()
+	if w.fileWatcher == nil {
+		return nil
+	}
+	w.stopFileWatcher()
+	return w.startFileWatcher()
+}
+
+func (w *Workspace) fileWatcherRoots() []string {
+	roots := []string{}
+	for _, folder := range w.Folders() {
+		roots = append(roots, folder.Root)
+	}
+	for _, folder := range w.Folders() {
+		venv := folder.VirtualEnvPath
+		for _, modulesPath := range folder.ModulesPath {
+			if venv != "" && strings.HasPrefix(modulesPath, venv+"/") && filepath.Base(modulesPath) == "site-packages" && !slices.Contains(roots, modulesPath) {
+				roots = append(roots, modulesPath)
+			}
+		}
+	}
+	return roots
+}
+
+func (w *Workspace) skipWatchedDir(path string) bool {
+	if slices.ContainsFunc(w.Folders(), func(f *WorkspaceFolder) bool { return path == f.VirtualEnvPath }) {
+		return true
+	}
+	folder := w.FolderOf(path)
+	return folder != nil && folder.ignores(path, true)
+}
+
+// collectFileEvents coalesces watcher events until they stop coming for
+// delay, then reindexes them at once. A file created and written counts
+// as created, one removed and created again as changed. Once events is
+// closed the pending ones are reindexed right away, it returns when no
+// reindex is running.
+func (w *Workspace) collectFileEvents(events <-chan watcher.Event, delay time.Duration) {
+	var mutex sync.Mutex
+	var flushing sync.WaitGroup
+	pending := map[string]messages.FileChangeType{}
+	rescan, stopped := false, false
+	debouncer := debounce.NewDebounce(delay)
+	flush := func() {
+		mutex.Lock()
+		if stopped {
+			mutex.Unlock()
+			return
+		}
+		batch, needsRescan := pending, rescan
+		pending, rescan = map[string]messages.FileChangeType{}, false
+		if len(batch) == 0 && !needsRescan {
+			mutex.Unlock()
+			return
+		}
+		flushing.Add(1)
+		mutex.Unlock()
+		defer flushing.Done()
+
+		if needsRescan {
+			slog.Info("Too many file changes, rescanning watched folders")
+			w.ApplyFileEvents(w.rescanFileEvents())
+			return
+		}
+		fileEvents := []messages.FileEvent{}
+		for path, changeType := range batch {
+			fileEvents = append(fileEvents, messages.FileEvent{URI: "file://" + path, Type: changeType})
+		}
+		sort.Slice(fileEvents, func(i, j int) bool { return fileEvents[i].URI < fileEvents[j].URI })
+		w.ApplyFileEvents(fileEvents)
+	}
+
+	for event := range events {
+		if event.Op != watcher.Overflow && event.Op != watcher.Remove && !isWatchedFile(event.Path) {
+			continue
+		}
+		mutex.Lock()
+		switch {
+		case rescan:
+		case event.Op == watcher.Overflow || len(pending) >= maxPendingFileEvents:
+			rescan = true
+			pending = map[string]messages.FileChangeType{}
+		default:
+			pending[event.Path] = coalesceFileChange(pending[event.Path], event.Op)
+		}
+		mutex.Unlock()
+		debouncer.Debounce(flush)
+	}
+	// Events still waiting for the quiet period aren't seen by the next
+	// watcher, when restarted.
+	flush()
+	mutex.Lock()
+	stopped = true
+	mutex.Unlock()
+	flushing.Wait()
+}
+
+// coalesceFileChange merges a new event into the change pending for a file,
+// zero when there is none.
+func coalesceFileChange(previous messages.FileChangeType, op watcher.Op) messages.FileChangeType {
+	switch op {
+	case watcher.Remove:
+		return messages.FileChangeTypeDeleted
+	case watcher.Create:
+		if previous == messages.FileChangeTypeDeleted {
+			return messages.FileChangeTypeChanged
+		}
+		return messages.FileChangeTypeCreated
+	default:
+		if previous == messages.FileChangeTypeCreated {
+			return previous
+		}
+		return messages.FileChangeTypeChanged
+	}
+}
+
+// rescanFileEvents compares the workspace folders on disk with its files:
+// new files are created, known ones changed (reloading skips equal content)
+// and missing ones deleted.
+func (w *Workspace) rescanFileEvents() []messages.FileEvent {
+	events := []messages.FileEvent{}
+	seen := map[string]bool{}
+	for _, folder := range w.Folders() {
+		root := folder.Root
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if entry.IsDir() {
+				if path != root && w.skipWatchedDir(path) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			url := "file://" + path
+			if !isPythonSource(path) || seen[url] {
+				return nil
+			}
+			seen[url] = true
+			changeType := messages.FileChangeTypeChanged
+			if _, err := w.GetPythonFile(url); err != nil {
+				changeType = messages.FileChangeTypeCreated
+			}
+			events = append(events, messages.FileEvent{URI: url, Type: changeType})
+			return nil
+		})
+	}
+	w.Files.Range(func(key, value any) bool {
+		url := key.(string)
+		if seen[url] {
+			return true
+		}
+		if _, err := os.Stat(strings.TrimPrefix(url, "file://")); err != nil {
+			events = append(events, messages.FileEvent{URI: url, Type: messages.FileChangeTypeDeleted})
+		} else if value.(*PythonFile).External {
+			events = append(events, messages.FileEvent{URI: url, Type: messages.FileChangeTypeChanged})
+		}
+		return true
+	})
+	return events
+}
@@ -0,0 +1,71 @@
+//go:build linux
+
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"snakelsp/internal/messages"
+	"snakelsp/pkg/watcher"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalesceFileChange(t *testing.T) {
+	assert.Equal(t, messages.FileChangeTypeCreated, coalesceFileChange(0, watcher.Create))
+	assert.Equal(t, messages.FileChangeTypeCreated, coalesceFileChange(messages.FileChangeTypeCreated, watcher.Write))
+	assert.Equal(t, messages.FileChangeTypeChanged, coalesceFileChange(messages.FileChangeTypeDeleted, watcher.Create))
+	assert.Equal(t, messages.FileChangeTypeDeleted, coalesceFileChange(messages.FileChangeTypeChanged, watcher.Remove))
+	assert.Equal(t, messages.FileChangeTypeChanged, coalesceFileChange(0, watcher.Write))
+}
+
+func TestFileWatcher(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		".git/HEAD": "ref: refs/heads/main\n",
+	})
+	require.NoError(t, w.StartFileWatcher())
+
+	paths := []string{
+		filepath.Join(root, "pkg", "first.py"),
+		filepath.Join(root, "pkg", "second.py"),
+		filepath.Join(root, ".git", "ignored.py"),
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "pkg"), 0o755))
+	for _, path := range paths {
+		require.NoError(t, os.WriteFile(path, []byte("class Created:\n    pass\n"), 0o644))
+	}
+	t.Cleanup(w.StopFileWatcher)
+
+	assert.Eventually(t, func() bool {
+		_, first := w.GetPythonFile("file://" + paths[0])
+		_, second := w.GetPythonFile("file://" + paths[1])
+		return first == nil && second == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	_, err := w.GetPythonFile("file://" + paths[2])
+	assert.Error(t, err)
+
+	require.NoError(t, os.Remove(paths[0]))
+	assert.Eventually(t, func() bool {
+		_, err := w.GetPythonFile("file://" + paths[0])
+		return err != nil
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestCollectFileEventsFlushesOnClose(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"created.py": "class Created:\n    pass\n",
+	})
+	path := filepath.Join(root, "created.py")
+	events := make(chan watcher.Event, 1)
+	events <- watcher.Event{Path: path, Op: watcher.Create}
+	close(events)
+
+	// Closed long before the quiet period ends, as when restarted
+	w.collectFileEvents(events, time.Hour)
+	_, err := w.GetPythonFile("file://" + path)
+	assert.NoError(t, err)
+}
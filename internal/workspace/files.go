@@ -4,187 +4,322 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
-	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"snakelsp/internal/messages"
-	"snakelsp/internal/progress"
 	"snakelsp/pkg/debounce"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
 )
 
-var ProjectFiles sync.Map // Projects files, also 3rd libraries files
+// Editor changes only edit the syntax tree and mark it stale. Reparsing it
+// and updating the imports, symbols and diagnostics waits for the typing to
+// pause that long, or for a request needing the tree.
+const parseOnUpdateDelay = 250 * time.Millisecond
 
 type PythonFile struct {
-	Url      string
-	Text     string
+	Url       string
+	Text      string
+	workspace *Workspace // Workspace the file was loaded in
+
 	astTree  *tree_sitter.Tree
 	astRoot  *tree_sitter.Node
-	External bool
-	isOpened bool
+	astStale bool // The tree was edited and has to be reparsed
+	astMutex sync.Mutex
 
-	Imports []Import
+	lineIndex *LineIndex
+	External  bool
+	isOpened  bool
+	Version   messages.Integer // Version of the editor content, when opened
+	stamp     fileStamp        // Content on disk, zero when not read from disk
 
-	debouncer debounce.Debouncer
-}
+	// Imports are replaced when the file is reparsed, while other files
+	// look for their dependents. They're read and replaced under
+	// importsMutex outside of tests.
+	Imports      []Import
+	importsMutex sync.RWMutex
 
-func ParseProjectFiles(projectPath string, envPath string, progress *progress.WorkDone) error {
-	progress.Start("Parsing project files")
-	excludedFolders := []string{".git", ".venv", ".mypy_cache"}
-	pythonFiles := []*PythonFile{}
-	filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() && (envPath == path || slices.Contains(excludedFolders, info.Name())) {
-			return filepath.SkipDir
-		}
-		if filepath.Ext(path) != ".py" {
-			return nil
-		}
-		url := "file://" + path
-		file, rfErr := os.ReadFile(path)
-		if rfErr != nil {
-			return err
-		}
-		pythonFile := NewPythonFile(url, string(file), false, false)
-		pythonFiles = append(pythonFiles, pythonFile)
-		return nil
-	})
-	bulkParseAst(pythonFiles, progress)
-	return nil
+	symbolsMutex  sync.Mutex
+	positionIndex *symbolIndex // Built when a position is looked up
+
+	debouncer   debounce.Debouncer
+	updateMutex sync.Mutex // Held while the file is updated after edits
+
+	// Result id of the diagnostics after the last update, under updateMutex
+	diagnosticsResultID string
 }
 
-func GetPythonFile(url string) (*PythonFile, error) {
-	file, ok := ProjectFiles.Load(url)
+func (w *Workspace) GetPythonFile(url string) (*PythonFile, error) {
+	file, ok := w.Files.Load(url)
 	if !ok {
-		return nil, fmt.Errorf("file in the ProjectFiles map not found")
+		return nil, fmt.Errorf("file in the workspace files not found")
 	}
 	return file.(*PythonFile), nil
 }
 
-func NewPythonFile(url string, text string, external, isOpen bool) *PythonFile {
-	pythonFile := &PythonFile{
+func (w *Workspace) NewPythonFile(url string, text string, external, isOpen bool) *PythonFile {
+	actual, _ := w.Files.LoadOrStore(url, w.newPythonFile(url, text, external, isOpen))
+	return actual.(*PythonFile)
+}
+
+// newPythonFile creates a file of the workspace without adding it to its
+// files.
+func (w *Workspace) newPythonFile(url string, text string, external, isOpen bool) *PythonFile {
+	return &PythonFile{
 		Url:       url,
 		Text:      text,
+		workspace: w,
 		External:  external,
 		isOpened:  isOpen,
-		debouncer: debounce.NewDebounce(2 * time.Second),
+		debouncer: debounce.NewDebounce(parseOnUpdateDelay),
+	}
+}
+
+// OpenFile marks the file as opened in the editor with its content, which
+// can differ from the one indexed from disk.
+func (w *Workspace) OpenFile(url, text string, version messages.Integer, external bool) *PythonFile {
+	file, err := w.GetPythonFile(url)
+	if err != nil {
+		file = w.NewPythonFile(url, text, external, true)
+		file.Version = version
+		return file
+	}
+	file.astMutex.Lock()
+	if current, _ := w.Files.Load(url); current != file {
+		// Removed in the meantime, e.g. deleted on disk
+		file.astMutex.Unlock()
+		return w.OpenFile(url, text, version, external)
+	}
+	file.isOpened = true
+	file.Version = version
+	changed := file.Text != text
+	if changed {
+		file.replaceTextLocked(text)
+	}
+	file.astMutex.Unlock()
+	if changed {
+		file.debouncer.Debounce(file.parseOnUpdate)
 	}
-	ProjectFiles.LoadOrStore(url, pythonFile)
-	return pythonFile
+	return file
 }
 
-func ImportPythonFileFromFile(path string, external bool) (*PythonFile, error) {
+func (w *Workspace) ImportPythonFileFromFile(path string, external bool) (*PythonFile, error) {
 	url := "file://" + path
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	return NewPythonFile(url, string(content), external, false), nil
+	return w.NewPythonFile(url, string(content), external, false), nil
 }
 
+// parseAst parses the file text from scratch, dropping the previous tree.
 func (p *PythonFile) parseAst() *tree_sitter.Node {
-	parser := tree_sitter.NewParser()
-	defer parser.Close()
-	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_python.Language()))
-	tree := parser.Parse([]byte(p.Text), nil)
-	root := tree.RootNode()
-	p.astRoot = root
+	p.astMutex.Lock()
+	defer p.astMutex.Unlock()
+	return p.parseAstLocked(false)
+}
+
+// parseAstLocked parses the file text, against the previous tree when
+// incremental so only the parts touched by its edits are parsed again.
+func (p *PythonFile) parseAstLocked(incremental bool) *tree_sitter.Node {
+	oldTree := p.astTree
+	w := p.workspace
+	w.parserMutex.Lock()
+	if w.parser == nil {
+		w.parser = tree_sitter.NewParser()
+		w.parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_python.Language()))
+	}
+	var tree *tree_sitter.Tree
+	if incremental {
+		tree = w.parser.Parse([]byte(p.Text), oldTree)
+	} else {
+		tree = w.parser.Parse([]byte(p.Text), nil)
+	}
+	w.parserMutex.Unlock()
+	oldTree.Close()
 	p.astTree = tree
+	p.astRoot = tree.RootNode()
+	p.astStale = false
 	return p.astRoot
 }
 
-func bulkParseAst(files []*PythonFile, pr *progress.WorkDone) {
-	parser := tree_sitter.NewParser()
-	defer parser.Close()
-	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_python.Language()))
-	totalFiles := len(files)
-	for i, file := range files {
-		pr.Report(fmt.Sprintf("Processing file %d of %d", i+1, totalFiles), uint16(float64(i+1)/float64(totalFiles)*100))
-		tree := parser.Parse([]byte(file.Text), nil)
-		root := tree.RootNode()
-		file.astRoot = root
-	}
-	pr.End("Finished parsing project files")
+// Ast is a syntax tree of a file with the text it was parsed from. It's a
+// copy the file's edits and reparses don't touch, valid until closed.
+type Ast struct {
+	Root     *tree_sitter.Node
+	Source   []byte
+	encoding messages.PositionEncodingKind
+	tree     *tree_sitter.Tree
+}
+
+// Close releases the tree, its nodes must not be used afterwards.
+func (a *Ast) Close() {
+	a.tree.Close()
+}
+
+// NodeRange converts the boundaries of a node of the tree to an LSP range.
+func (a *Ast) NodeRange(node *tree_sitter.Node) messages.Range {
+	return nodeRange(node, a.Source, a.encoding)
 }
 
-func (p *PythonFile) GetOrCreateAst() *tree_sitter.Node {
+// GetOrCreateAst returns the syntax tree of the current text, parsing the
+// file first or reparsing it incrementally after edits. The caller closes
+// the returned tree.
+func (p *PythonFile) GetOrCreateAst() *Ast {
+	p.astMutex.Lock()
+	defer p.astMutex.Unlock()
 	if p.astRoot == nil {
-		return p.parseAst()
-	} else {
-		return p.astRoot
+		p.parseAstLocked(false)
+	} else if p.astStale {
+		p.parseAstLocked(true)
+	}
+	tree := p.astTree.Clone()
+	return &Ast{
+		Root:     tree.RootNode(),
+		Source:   []byte(p.Text),
+		encoding: p.positionEncoding(),
+		tree:     tree,
 	}
 }
 
 func (p *PythonFile) CloseFile() {
+	p.astMutex.Lock()
+	defer p.astMutex.Unlock()
 	p.isOpened = false
 	p.astTree.Close()
+	p.astTree = nil
+	p.astRoot = nil
 }
 
 func (p *PythonFile) parseOnUpdate() {
+	p.updateMutex.Lock()
+	defer p.updateMutex.Unlock()
 	slog.Debug("Parsing file on update", slog.String("file", p.Url))
-	p.parseAst()
+	p.GetOrCreateAst().Close()
 	p.ParseImports()
 	p.parseSymbols()
+	dependents := p.Dependents()
+	for _, dependent := range dependents {
+		dependent.ParseImports()
+		dependent.relinkSymbols()
+	}
+	diagnosticsChanged(p, dependents)
 }
 
-func (f *PythonFile) ApplyChange(contentChanges []messages.TextDocumentContentChangeEvent) {
-	slog.Debug("Applying changes to file", slog.String("file", f.Url))
-	content := f.Text
+// FlushUpdate brings the imports and symbols in line with the current text
+// right away: the update waiting for the typing to pause runs now, or the
+// one running is waited for.
+func (p *PythonFile) FlushUpdate() {
+	if p.debouncer.Stop() {
+		p.parseOnUpdate()
+		return
+	}
+	p.updateMutex.Lock()
+	p.updateMutex.Unlock()
+}
+
+// ApplyChange updates the text with the editor changes of a new version.
+// Changes are received in order, so a version not newer than the current
+// one was applied already and is rejected. Ranged changes are also applied
+// to the syntax tree, which is reparsed incrementally the next time it's
+// needed, changes without a range replace the whole text.
+func (f *PythonFile) ApplyChange(version messages.Integer, contentChanges []messages.TextDocumentContentChangeEvent) error {
+	slog.Debug("Applying changes to file", slog.String("file", f.Url), slog.Int("version", int(version)))
+	f.astMutex.Lock()
+	if f.isOpened && version <= f.Version {
+		current := f.Version
+		f.astMutex.Unlock()
+		return fmt.Errorf("change of %s version %d is out of order, current version is %d", f.Url, version, current)
+	}
+	f.Version = version
 	for _, change := range contentChanges {
-		content = fullContentFromChange(change.Range, content, change.Text)
+		if change.Range == nil {
+			f.replaceTextLocked(change.Text)
+			continue
+		}
+		var edit *tree_sitter.InputEdit
+		f.Text, edit = applyContentChange(f.Text, change.Range, change.Text, f.positionEncoding())
+		if edit != nil && f.astTree != nil {
+			f.astTree.Edit(edit)
+			f.astStale = true
+		}
 	}
-	f.Text = content
+	f.astMutex.Unlock()
 	slog.Debug("Updated file content", slog.String("content", f.Text))
 	f.debouncer.Debounce(f.parseOnUpdate)
+	return nil
 }
 
-func fullContentFromChange(r *messages.Range, content, newText string) string {
-	lines := strings.Split(content, "\n")
-
-	startLine := r.Start.Line
-	endLine := r.End.Line
-	startCharacter := r.Start.Character
-	endCharacter := r.End.Character
-	// Ensure the start and end line indices are within bounds
-	if int(startLine) >= len(lines) || int(endLine) >= len(lines) {
-		slog.Warn("Invalid line numbers")
-		return content
+// SaveFile brings the file in line with the saved content, the text sent
+// with `didSave` or else the file on disk, in case changes got lost.
+func (w *Workspace) SaveFile(url string, text *string) error {
+	file, err := w.GetPythonFile(url)
+	if err != nil {
+		return err
 	}
-
-	// Extract the lines where the range starts and ends
-	startTargetLine := lines[startLine]
-	endTargetLine := lines[endLine]
-
-	// Ensure that character positions are valid within the respective lines
-	if int(startCharacter) > len(startTargetLine) || int(endCharacter) > len(endTargetLine) {
-		slog.Warn("Invalid character positions")
-		return content
+	if text == nil {
+		content, err := os.ReadFile(strings.TrimPrefix(url, "file://"))
+		if err != nil {
+			return err
+		}
+		saved := string(content)
+		text = &saved
 	}
+	file.astMutex.Lock()
+	changed := file.Text != *text
+	if changed {
+		slog.Warn("Saved file differs from the synced content, resyncing", slog.String("file", url))
+		file.replaceTextLocked(*text)
+	}
+	if info, err := os.Stat(strings.TrimPrefix(url, "file://")); err == nil {
+		file.stamp = newFileStamp(info, []byte(*text))
+	}
+	file.astMutex.Unlock()
+	if changed {
+		file.debouncer.Debounce(file.parseOnUpdate)
+	}
+	return nil
+}
 
-	// Handle different cases based on start and end indices
-	if startLine == endLine {
-		// Case 1: Change occurs within a single line
-		// Replace the range directly in the same line
-		updatedLine := startTargetLine[:startCharacter] + newText + startTargetLine[endCharacter:]
-		lines[startLine] = updatedLine
-	} else {
-		// Case 2: Change spans multiple lines
-		// Compose new content from fragments:
-		// - Start of the first line up to `startCharacter`
-		startFragment := startTargetLine[:startCharacter]
-
-		// - End of the last line from `endCharacter`
-		endFragment := endTargetLine[endCharacter:]
+// replaceTextLocked sets a new text the syntax tree can't be edited to, it's
+// parsed again from scratch.
+func (f *PythonFile) replaceTextLocked(text string) {
+	f.Text = text
+	f.astTree.Close()
+	f.astTree = nil
+	f.astRoot = nil
+	f.astStale = false
+}
 
-		// Replace the lines in between with the new text
-		updatedLine := startFragment + newText + endFragment
-		lines = append(lines[:startLine], append([]string{updatedLine}, lines[endLine+1:]...)...)
+// applyContentChange replaces the range of content with newText and returns
+// the matching tree-sitter edit, nil when the range is out of the content.
+func applyContentChange(content string, r *messages.Range, newText string, encoding messages.PositionEncodingKind) (string, *tree_sitter.InputEdit) {
+	lines := NewLineIndex(content, encoding)
+	start, startOk := lines.Offset(r.Start)
+	end, endOk := lines.Offset(r.End)
+	if !startOk || !endOk || end < start {
+		slog.Warn("Invalid change range", slog.Any("range", r))
+		return content, nil
 	}
+	startPoint := lines.offsetPoint(start)
+	return content[:start] + newText + content[end:], &tree_sitter.InputEdit{
+		StartByte:      uint(start),
+		OldEndByte:     uint(end),
+		NewEndByte:     uint(start + len(newText)),
+		StartPosition:  startPoint,
+		OldEndPosition: lines.offsetPoint(end),
+		NewEndPosition: pointAfter(startPoint, newText),
+	}
+}
 
-	// Reassemble the lines back into the full content
-	return strings.Join(lines, "\n")
+// pointAfter is where text inserted at start ends.
+func pointAfter(start tree_sitter.Point, text string) tree_sitter.Point {
+	lines := strings.Count(text, "\n")
+	if lines == 0 {
+		return tree_sitter.Point{Row: start.Row, Column: start.Column + uint(len(text))}
+	}
+	return tree_sitter.Point{Row: start.Row + uint(lines), Column: uint(len(text) - strings.LastIndex(text, "\n") - 1)}
 }
@@ -0,0 +1,192 @@
+package workspace
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"snakelsp/internal/messages"
+	"snakelsp/pkg/debounce"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func textChange(startLine, startCharacter, endLine, endCharacter messages.UInteger, text string) messages.TextDocumentContentChangeEvent {
+	return messages.TextDocumentContentChangeEvent{
+		Range: &messages.Range{
+			Start: messages.Position{Line: startLine, Character: startCharacter},
+			End:   messages.Position{Line: endLine, Character: endCharacter},
+		},
+		Text: text,
+	}
+}
+
+// astSexp is the syntax tree of the current text of the file.
+func astSexp(f *PythonFile) string {
+	ast := f.GetOrCreateAst()
+	defer ast.Close()
+	return ast.Root.ToSexp()
+}
+
+func TestApplyChangeReparsesIncrementally(t *testing.T) {
+	w := newTestWorkspace()
+	file := &PythonFile{
+		workspace: w,
+		Url:       "file:///tmp/incremental.py",
+		Text:      "import os\n\n\ndef foo(a):\n    return a\n\n\nclass Bar:\n    pass\n",
+		debouncer: debounce.NewDebounce(time.Hour),
+	}
+	t.Cleanup(func() { file.CloseFile() })
+	file.GetOrCreateAst().Close()
+
+	file.ApplyChange(1, []messages.TextDocumentContentChangeEvent{
+		textChange(3, 8, 3, 9, "value, other"),
+		textChange(4, 11, 4, 12, "value + other"),
+		textChange(7, 0, 8, 8, "class Baz(Bar):\n    x = 1\n    y = 2"),
+		textChange(0, 9, 0, 9, ", sys"),
+	})
+	expected := "import os, sys\n\n\ndef foo(value, other):\n    return value + other\n\n\nclass Baz(Bar):\n    x = 1\n    y = 2\n"
+	require.Equal(t, expected, file.Text)
+
+	incremental := file.GetOrCreateAst()
+	defer incremental.Close()
+	fresh := &PythonFile{workspace: w, Text: expected}
+	t.Cleanup(func() { fresh.CloseFile() })
+	assert.Equal(t, astSexp(fresh), incremental.Root.ToSexp())
+	class := incremental.Root.NamedChild(2)
+	require.NotNil(t, class)
+	assert.Equal(t, "Baz", class.ChildByFieldName("name").Utf8Text(incremental.Source))
+	assert.Equal(t, messages.Range{
+		Start: messages.Position{Line: 7, Character: 0},
+		End:   messages.Position{Line: 9, Character: 9},
+	}, incremental.NodeRange(class))
+}
+
+func TestApplyChangeIgnoresInvalidRanges(t *testing.T) {
+	content := "a = 1\nb = 2\n"
+	for _, change := range []messages.TextDocumentContentChangeEvent{
+		textChange(5, 0, 5, 0, "x"),
+		textChange(1, 2, 0, 0, "x"),
+	} {
+		updated, edit := applyContentChange(content, change.Range, change.Text, messages.PositionEncodingKindUTF16)
+		assert.Equal(t, content, updated)
+		assert.Nil(t, edit)
+	}
+	// Past the end of the line means its end
+	updated, edit := applyContentChange(content, textChange(0, 10, 0, 10, " + 1").Range, " + 1", messages.PositionEncodingKindUTF16)
+	assert.Equal(t, "a = 1 + 1\nb = 2\n", updated)
+	require.NotNil(t, edit)
+	assert.Equal(t, uint(5), edit.OldEndByte)
+
+	updated, edit = applyContentChange(content, textChange(2, 0, 2, 0, "c = 3\n").Range, "c = 3\n", messages.PositionEncodingKindUTF16)
+	assert.Equal(t, "a = 1\nb = 2\nc = 3\n", updated)
+	require.NotNil(t, edit)
+	assert.Equal(t, uint(12), edit.StartByte)
+	assert.Equal(t, uint(3), edit.NewEndPosition.Row)
+	assert.Equal(t, uint(0), edit.NewEndPosition.Column)
+}
+
+func TestApplyChangeFullAndMixedSync(t *testing.T) {
+	w := newTestWorkspace()
+	file := &PythonFile{
+		workspace: w,
+		Url:       "file:///tmp/full_sync.py",
+		Text:      "def foo():\n    pass\n",
+		isOpened:  true,
+		Version:   1,
+		debouncer: debounce.NewDebounce(time.Hour),
+	}
+	t.Cleanup(func() { file.CloseFile() })
+	file.GetOrCreateAst().Close()
+
+	require.NoError(t, file.ApplyChange(2, []messages.TextDocumentContentChangeEvent{
+		{Text: "class Foo:\n    pass\n"},
+		textChange(0, 6, 0, 9, "Bar"),
+	}))
+	assert.Equal(t, "class Bar:\n    pass\n", file.Text)
+	assert.Equal(t, messages.Integer(2), file.Version)
+	fresh := &PythonFile{workspace: w, Text: file.Text}
+	t.Cleanup(func() { fresh.CloseFile() })
+	assert.Equal(t, astSexp(fresh), astSexp(file))
+
+	// Out of order
+	err := file.ApplyChange(2, []messages.TextDocumentContentChangeEvent{{Text: "x = 1\n"}})
+	assert.Error(t, err)
+	assert.Equal(t, "class Bar:\n    pass\n", file.Text)
+	assert.Equal(t, messages.Integer(2), file.Version)
+}
+
+func TestApplyChangeConcurrentlyWithReaders(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{"pkg/mod.py": "class Base:\n    pass\n"})
+	file := w.OpenFile("file://"+filepath.Join(root, "main.py"), "from pkg.mod import Base\n\nx = 0\n", 1, false)
+	file.debouncer = debounce.NewDebounce(time.Millisecond)
+	t.Cleanup(func() { file.CloseFile() })
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for version := messages.Integer(2); version < 200; version++ {
+			var change messages.TextDocumentContentChangeEvent
+			if version%10 == 0 {
+				change = messages.TextDocumentContentChangeEvent{Text: fmt.Sprintf("from pkg.mod import Base\n\nx = %d\n", version)}
+			} else {
+				change = textChange(2, 4, 3, 0, fmt.Sprintf("%d\nclass C%d(Base):\n    pass\n", version, version))
+			}
+			assert.NoError(t, file.ApplyChange(version, []messages.TextDocumentContentChangeEvent{change}))
+		}
+	}()
+	// Readers keep walking their tree while edits replace the file's one
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				ast := file.GetOrCreateAst()
+				assert.Equal(t, "import_from_statement", ast.Root.NamedChild(0).Kind())
+				assert.Equal(t, string(ast.Source), ast.Root.Utf8Text(ast.Source))
+				ast.Close()
+				file.Diagnostics()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestOpenAndSaveFile(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{"mod.py": "x = 1\n"})
+	path := filepath.Join(root, "mod.py")
+	indexed, err := w.ImportPythonFileFromFile(path, false)
+	require.NoError(t, err)
+	indexed.debouncer = debounce.NewDebounce(time.Hour)
+	t.Cleanup(func() { indexed.remove() })
+	indexed.GetOrCreateAst().Close()
+
+	// The indexed file is the one opened, with the editor content
+	opened := w.OpenFile(indexed.Url, "x = 2\n", 3, false)
+	assert.Same(t, indexed, opened)
+	assert.True(t, opened.isOpened)
+	assert.Equal(t, messages.Integer(3), opened.Version)
+	assert.Equal(t, "x = 2\n", opened.Text)
+	ast := opened.GetOrCreateAst()
+	assert.Equal(t, "2", ast.Root.NamedChild(0).NamedChild(0).ChildByFieldName("right").Utf8Text(ast.Source))
+	ast.Close()
+
+	saved := "x = 3\n"
+	require.NoError(t, w.SaveFile(indexed.Url, &saved))
+	assert.Equal(t, saved, indexed.Text)
+
+	// Without the text, from disk
+	require.NoError(t, w.SaveFile(indexed.Url, nil))
+	assert.Equal(t, "x = 1\n", indexed.Text)
+}
@@ -0,0 +1,50 @@
+package workspace
+
+import (
+	"path"
+	"strings"
+)
+
+// matchGlob reports whether a slash separated path, relative to a workspace
+// folder, matches the pattern. `*` and `?` don't match separators, `**`
+// matches any number of folders. Patterns without a separator match the name
+// of the file or of any folder holding it, as in .gitignore, and a pattern
+// matching a folder matches everything in it.
+func matchGlob(pattern, relative string) bool {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+	parts := strings.Split(relative, "/")
+	if !strings.Contains(pattern, "/") {
+		for _, part := range parts {
+			if matched, _ := path.Match(pattern, part); matched {
+				return true
+			}
+		}
+		return false
+	}
+	patternParts := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	for i := 1; i <= len(parts); i++ {
+		if matchGlobParts(patternParts, parts[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlobParts(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchGlobParts(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], parts[0])
+	return matched && matchGlobParts(pattern[1:], parts[1:])
+}
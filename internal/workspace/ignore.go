@@ -0,0 +1,168 @@
+package workspace
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const gitignoreFile = ".gitignore"
+
+// Files and folders never indexed as project files, on top of the ignored
+// and excluded ones: version control, virtualenvs, caches of Python tools,
+// and build outputs at the folder root.
+var defaultExcludes = []string{
+	".git", ".hg", ".svn",
+	".venv", "__pycache__", "*.egg-info", ".eggs",
+	".mypy_cache", ".pytest_cache", ".ruff_cache", ".hypothesis", ".ipynb_checkpoints",
+	".tox", ".nox", "node_modules",
+	"/build", "/dist",
+}
+
+// ignoreRule is a pattern of a .gitignore file.
+type ignoreRule struct {
+	pattern  []string // Split on separators, a single name when not anchored
+	anchored bool     // Matched against the path from the .gitignore folder, else against the name
+	negate   bool
+	dirOnly  bool
+}
+
+func (r ignoreRule) matches(parts []string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		matched, _ := path.Match(r.pattern[0], parts[len(parts)-1])
+		return matched
+	}
+	return matchGlobParts(r.pattern, parts)
+}
+
+// parseIgnoreFile reads the rules of a .gitignore file, none when it doesn't
+// exist.
+func parseIgnoreFile(filename string) []ignoreRule {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	rules := []ignoreRule{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.anchored = strings.Contains(line, "/")
+		rule.pattern = strings.Split(strings.TrimPrefix(line, "/"), "/")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignoreFiles are the rules of the .gitignore files of a workspace folder
+// and of its .git/info/exclude, read once per folder. The zero value is
+// ready to use.
+type ignoreFiles struct {
+	mutex sync.Mutex
+	rules map[string][]ignoreRule // By folder, relative to the workspace folder
+}
+
+// rulesOf returns the rules applying to the paths in dir, relative to the
+// workspace folder root.
+func (g *ignoreFiles) rulesOf(root, dir string) []ignoreRule {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if rules, ok := g.rules[dir]; ok {
+		return rules
+	}
+	rules := []ignoreRule{}
+	if dir == "" {
+		rules = append(rules, parseIgnoreFile(filepath.Join(root, ".git", "info", "exclude"))...)
+	}
+	rules = append(rules, parseIgnoreFile(filepath.Join(root, dir, gitignoreFile))...)
+	if g.rules == nil {
+		g.rules = map[string][]ignoreRule{}
+	}
+	g.rules[dir] = rules
+	return rules
+}
+
+// ignored reports whether the .gitignore files of the folders holding the
+// path ignore it, the deepest one and the last rule matching it winning.
+// The folders holding it aren't checked.
+func (g *ignoreFiles) ignored(root string, parts []string, isDir bool) bool {
+	ignored := false
+	for i := range parts {
+		for _, rule := range g.rulesOf(root, strings.Join(parts[:i], "/")) {
+			if rule.matches(parts[i:], isDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// ignores reports whether the path is left out of the folder index, itself
+// or one of the folders holding it.
+func (f *WorkspaceFolder) ignores(path string, isDir bool) bool {
+	parts := f.relativeParts(path)
+	if parts == nil {
+		return false
+	}
+	for i := 1; i < len(parts); i++ {
+		if f.ignoresParts(parts[:i], true) {
+			return true
+		}
+	}
+	return f.ignoresParts(parts, isDir)
+}
+
+// ignoresEntry reports whether the path is left out of the folder index,
+// when the folders holding it were checked already, walking the folder.
+func (f *WorkspaceFolder) ignoresEntry(path string, isDir bool) bool {
+	parts := f.relativeParts(path)
+	return parts != nil && f.ignoresParts(parts, isDir)
+}
+
+// ignoresParts checks a path split on separators, relative to the folder
+// root: default exclusions, exclude patterns and .gitignore files drop it,
+// and Python files not matching the include patterns, when there are any.
+func (f *WorkspaceFolder) ignoresParts(parts []string, isDir bool) bool {
+	relative := strings.Join(parts, "/")
+	matches := func(pattern string) bool { return matchGlob(pattern, relative) }
+	if slices.ContainsFunc(defaultExcludes, matches) || slices.ContainsFunc(f.Exclude, matches) {
+		return true
+	}
+	if f.gitignore.ignored(f.Root, parts, isDir) {
+		return true
+	}
+	return !isDir && isPythonSource(relative) && len(f.Include) > 0 && !slices.ContainsFunc(f.Include, matches)
+}
+
+// relativeParts splits the path relative to the folder root, nil for the
+// root itself and paths outside of it.
+func (f *WorkspaceFolder) relativeParts(path string) []string {
+	if !f.contains(path) || path == f.Root {
+		return nil
+	}
+	return strings.Split(filepath.ToSlash(strings.TrimPrefix(path, f.Root+"/")), "/")
+}
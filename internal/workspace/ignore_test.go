@@ -0,0 +1,86 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"snakelsp/internal/messages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func relativePaths(root string, paths []string) []string {
+	relative := []string{}
+	for _, path := range paths {
+		rel, _ := filepath.Rel(root, path)
+		relative = append(relative, rel)
+	}
+	return relative
+}
+
+func TestDiscoverProjectFiles(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		".gitignore":                   "*_pb2.py\n/out/\ngen/*.py\n!gen/keep.py\n",
+		".git/info/exclude":            "scratch.py\n",
+		"app/main.py":                  "",
+		"app/scratch.py":               "",
+		"app/api_pb2.py":               "",
+		"app/build/steps.py":           "",
+		"app/.gitignore":               "local_settings.py\n",
+		"app/local_settings.py":        "",
+		"app/__pycache__/main.py":      "",
+		"gen/models.py":                "",
+		"gen/keep.py":                  "",
+		"out/report.py":                "",
+		"build/lib/app/main.py":        "",
+		"dist/app/main.py":             "",
+		"node_modules/pkg/setup.py":    "",
+		".tox/py312/lib/site.py":       "",
+		"app.egg-info/setup.py":        "",
+		"other/local_settings.py":      "",
+		"other/.mypy_cache/stub.py":    "",
+		"other/.pytest_cache/cache.py": "",
+	})
+	paths := relativePaths(root, w.discoverProjectFiles(root, ""))
+	assert.Equal(t, []string{
+		"app/build/steps.py",
+		"app/main.py",
+		"gen/keep.py",
+		"other/local_settings.py",
+	}, paths)
+
+	folder := w.FolderOf(root)
+	assert.True(t, folder.ignores(filepath.Join(root, "app/local_settings.py"), false))
+	assert.True(t, folder.ignores(filepath.Join(root, "out/new/module.py"), false))
+	assert.False(t, folder.ignores(filepath.Join(root, "gen/keep.py"), false))
+	assert.False(t, folder.ignores(filepath.Join(root, "gen"), true))
+
+	folder.Include = []string{"app"}
+	folder.Exclude = []string{"app/build"}
+	paths = relativePaths(root, w.discoverProjectFiles(root, ""))
+	assert.Equal(t, []string{"app/main.py"}, paths)
+}
+
+func TestGitignoreChange(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"app/models.py":   "class Model:\n    pass\n",
+		"app/fixtures.py": "from app.models import Model\n",
+	})
+	require.NoError(t, w.IndexProject(root, "", nil))
+	fixtures := "file://" + filepath.Join(root, "app/fixtures.py")
+	_, err := w.GetPythonFile(fixtures)
+	require.NoError(t, err)
+
+	gitignore := filepath.Join(root, "app/.gitignore")
+	require.NoError(t, os.WriteFile(gitignore, []byte("fixtures.py\n"), 0o644))
+	w.ApplyFileEvents([]messages.FileEvent{{URI: "file://" + gitignore, Type: messages.FileChangeTypeCreated}})
+	_, err = w.GetPythonFile(fixtures)
+	assert.Error(t, err)
+
+	require.NoError(t, os.Remove(gitignore))
+	w.ApplyFileEvents([]messages.FileEvent{{URI: "file://" + gitignore, Type: messages.FileChangeTypeDeleted}})
+	_, err = w.GetPythonFile(fixtures)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,105 @@
+package workspace
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"snakelsp/internal/messages"
+)
+
+// linkSuperObject records superObject as a base class or overridden method
+// of the symbol.
+func (w *Workspace) linkSuperObject(symbol, superObject *Symbol) {
+	if slices.Contains(symbol.SuperObjects, superObject) {
+		return
+	}
+	symbol.SuperObjects = append(symbol.SuperObjects, superObject)
+	w.subObjectsMutex.Lock()
+	defer w.subObjectsMutex.Unlock()
+	if !slices.Contains(w.subObjects[superObject], symbol) {
+		w.subObjects[superObject] = append(w.subObjects[superObject], symbol)
+	}
+}
+
+// unlinkSuperObjects clears the base classes or overridden methods of the
+// symbol, before they are resolved again or the symbol is dropped.
+func (w *Workspace) unlinkSuperObjects(symbol *Symbol) {
+	w.subObjectsMutex.Lock()
+	defer w.subObjectsMutex.Unlock()
+	for _, superObject := range symbol.SuperObjects {
+		subs := slices.DeleteFunc(w.subObjects[superObject], func(s *Symbol) bool { return s == symbol })
+		if len(subs) == 0 {
+			delete(w.subObjects, superObject)
+		} else {
+			w.subObjects[superObject] = subs
+		}
+	}
+	symbol.SuperObjects = nil
+}
+
+// forgetSubObjects drops the entry of a symbol removed from the index, the
+// symbols deriving from it are unlinked when relinked.
+func (w *Workspace) forgetSubObjects(symbol *Symbol) {
+	w.subObjectsMutex.Lock()
+	defer w.subObjectsMutex.Unlock()
+	delete(w.subObjects, symbol)
+}
+
+// SubObjects returns the classes directly deriving from the class, or the
+// methods directly overriding the method.
+func (s *Symbol) SubObjects() []*Symbol {
+	w := s.File.workspace
+	w.subObjectsMutex.RLock()
+	defer w.subObjectsMutex.RUnlock()
+	return slices.Clone(w.subObjects[s])
+}
+
+// Implementations returns the subclasses of a class or the overrides of a
+// method, sorted by file and line. Transitive ones include those at every
+// depth, a method overridden in a grandchild class through a class not
+// overriding it included. It stops with the context error when canceled.
+func (s *Symbol) Implementations(ctx context.Context, transitive bool) ([]*Symbol, error) {
+	var implementations []*Symbol
+	switch {
+	case !transitive:
+		implementations = s.SubObjects()
+	case s.Kind == messages.SymbolKindMethod && s.Parent != nil:
+		classes, err := s.Parent.Implementations(ctx, true)
+		if err != nil {
+			return nil, err
+		}
+		for _, class := range classes {
+			for _, child := range class.Children {
+				if child.Kind == messages.SymbolKindMethod && child.Name == s.Name {
+					implementations = append(implementations, child)
+				}
+			}
+		}
+	default:
+		seen := map[*Symbol]bool{s: true}
+		queue := []*Symbol{s}
+		for len(queue) > 0 {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			for _, sub := range queue[0].SubObjects() {
+				if !seen[sub] {
+					seen[sub] = true
+					implementations = append(implementations, sub)
+					queue = append(queue, sub)
+				}
+			}
+			queue = queue[1:]
+		}
+	}
+	slices.SortFunc(implementations, compareSymbolLocations)
+	return implementations, nil
+}
+
+func compareSymbolLocations(a, b *Symbol) int {
+	if c := cmp.Compare(a.File.Url, b.File.Url); c != 0 {
+		return c
+	}
+	return comparePositions(a.NameRange.Start, b.NameRange.Start)
+}
@@ -0,0 +1,71 @@
+package workspace
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func symbolNames(symbols []*Symbol) []string {
+	names := []string{}
+	for _, symbol := range symbols {
+		if symbol.Parent != nil {
+			names = append(names, symbol.Parent.Name+"."+symbol.Name)
+		} else {
+			names = append(names, symbol.Name)
+		}
+	}
+	return names
+}
+
+func implementationNames(t *testing.T, symbol *Symbol, transitive bool) []string {
+	implementations, err := symbol.Implementations(context.Background(), transitive)
+	require.NoError(t, err)
+	return symbolNames(implementations)
+}
+
+func TestImplementations(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"app/__init__.py": "",
+		"app/base.py":     "class Base:\n    def run(self):\n        pass\n",
+		"app/mid.py":      "from app.base import Base\n\nclass Mid(Base):\n    pass\n",
+		"app/leaf.py":     "from app.mid import Mid\n\nclass Leaf(Mid):\n    def run(self):\n        pass\n\nclass Other(Mid):\n    pass\n",
+		"app/direct.py":   "from app.base import Base\n\nclass Direct(Base):\n    def run(self):\n        pass\n",
+	})
+	require.NoError(t, w.IndexProject(root, filepath.Join(root, ".venv"), nil))
+
+	base, err := w.GetPythonFile("file://" + filepath.Join(root, "app/base.py"))
+	require.NoError(t, err)
+	baseSymbols, err := base.FileSymbols("")
+	require.NoError(t, err)
+	baseClass, baseRun := baseSymbols[0], baseSymbols[0].Children[0]
+
+	assert.Equal(t, []string{"Direct", "Mid"}, implementationNames(t, baseClass, false))
+	assert.Equal(t, []string{"Direct", "Leaf", "Other", "Mid"}, implementationNames(t, baseClass, true))
+	assert.Equal(t, []string{"Direct.run"}, implementationNames(t, baseRun, false))
+	assert.Equal(t, []string{"Direct.run", "Leaf.run"}, implementationNames(t, baseRun, true))
+
+	// Reparsing a file updates the classes deriving from its classes
+	leaf, err := w.GetPythonFile("file://" + filepath.Join(root, "app/leaf.py"))
+	require.NoError(t, err)
+	leaf.Text = "from app.base import Base\n\nclass Leaf(Base):\n    pass\n"
+	leaf.parseAst()
+	leaf.ParseImports()
+	_, err = leaf.parseSymbols()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Direct", "Leaf", "Mid"}, implementationNames(t, baseClass, false))
+	assert.Equal(t, []string{"Direct.run"}, implementationNames(t, baseRun, true))
+
+	mid, err := w.GetPythonFile("file://" + filepath.Join(root, "app/mid.py"))
+	require.NoError(t, err)
+	mid.forgetSymbols()
+	assert.Equal(t, []string{"Direct", "Leaf"}, implementationNames(t, baseClass, true))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = baseClass.Implementations(ctx, true)
+	assert.ErrorIs(t, err, context.Canceled)
+}
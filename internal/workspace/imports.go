@@ -1,13 +1,15 @@
 package workspace
 
 import (
-	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 
-	"snakelsp/internal/progress"
+	"snakelsp/internal/messages"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
@@ -19,6 +21,27 @@ type Import struct {
 	ImportedName string // Specific name if "from foo import Bar" (it's "Bar"), else empty
 	PythonFile   *PythonFile
 	Symbol       *Symbol
+
+	ModuleRange  messages.Range // Range of SourceModule in the importing file
+	NameRange    messages.Range // Range of ImportedName, zero for plain imports
+	ResolveError error          // Why the import couldn't be resolved, nil if it could
+
+	BindingRange   messages.Range // Range of the name with its alias, e.g. `pandas as pd`
+	StatementRange messages.Range // Range of the whole import statement
+	RemovalRange   messages.Range // What to delete to drop just this import
+	TypeChecking   bool           // Imported under `if TYPE_CHECKING:`
+}
+
+// BoundName is the name the import introduces in the importing file.
+func (i *Import) BoundName() string {
+	if i.Alias != "" {
+		return i.Alias
+	}
+	if i.ImportedName != "" {
+		return i.ImportedName
+	}
+	module, _, _ := strings.Cut(i.SourceModule, ".")
+	return module
 }
 
 func (f *PythonFile) ParseImports() ([]Import, error) {
@@ -26,15 +49,36 @@ func (f *PythonFile) ParseImports() ([]Import, error) {
 	if err != nil {
 		return nil, err
 	}
-	f.Imports = imports
+	f.setImports(imports)
 	return imports, nil
 }
 
+func (f *PythonFile) loadedImports() []Import {
+	f.importsMutex.RLock()
+	defer f.importsMutex.RUnlock()
+	return f.Imports
+}
+
+func (f *PythonFile) setImports(imports []Import) {
+	f.importsMutex.Lock()
+	defer f.importsMutex.Unlock()
+	f.Imports = imports
+}
+
+// importsQuery is compiled once and shared like symbolsQuery.
+var importsQuery = sync.OnceValues(func() (*tree_sitter.Query, error) {
+	language := tree_sitter.NewLanguage(tree_sitter_python.Language())
+	query, err := tree_sitter.NewQuery(language, getTreeSitterImportQuery())
+	if err != nil {
+		return nil, err
+	}
+	return query, nil
+})
+
 func (f *PythonFile) parseImports(withResolvedSymbols bool) ([]Import, error) {
 	qc := tree_sitter.NewQueryCursor()
 	defer qc.Close()
-	language := tree_sitter.NewLanguage(tree_sitter_python.Language())
-	query, err := tree_sitter.NewQuery(language, getTreeSitterImportQuery())
+	query, err := importsQuery()
 	if err != nil {
 		return nil, err
 	}
@@ -43,77 +87,123 @@ func (f *PythonFile) parseImports(withResolvedSymbols bool) ([]Import, error) {
 }
 
 func (f *PythonFile) GetImports() ([]Import, error) {
-	if f.Imports == nil {
-		imports, err := f.ParseImports()
-		if err != nil {
-			return nil, err
-		}
-		f.Imports = imports
+	if imports := f.loadedImports(); imports != nil {
+		return imports, nil
 	}
-	return f.Imports, nil
+	return f.ParseImports()
 }
 
-func BulkParseImports(pr *progress.WorkDone) error {
-	slog.Debug("Parsing imports")
-	pr.Start("Parsing imports")
-	defer pr.End("Parsing import end")
-	qc := tree_sitter.NewQueryCursor()
-	defer qc.Close()
+// ModuleNotFoundError is returned when an imported module can't be found in
+// any of the module paths of the importing file.
+type ModuleNotFoundError struct {
+	Module      string
+	SearchPaths []string
+}
 
-	language := tree_sitter.NewLanguage(tree_sitter_python.Language())
-	query, err := tree_sitter.NewQuery(language, getTreeSitterImportQuery())
-	if err != nil {
-		return err
-	}
-	ProjectFiles.Range(func(key, value any) bool {
-		file := value.(*PythonFile)
-		if file.External {
-			return true
-		}
-		imports := processImports(file, qc, query, true)
-		file.Imports = imports
-		return true
-	})
-	slog.Debug("Imports parsed")
-	return nil
+func (e *ModuleNotFoundError) Error() string {
+	return fmt.Sprintf("module %q not found", e.Module)
 }
 
-func resolveImportSymbol(file *PythonFile, imp *Import) (*Symbol, error) {
-	// slog.Debug("Resolve import symbol for file", slog.String("fileUrl", file.Url), slog.String("importedName", imp.ImportedName), slog.String("sourceModule", imp.SourceModule))
-	module := strings.ReplaceAll(imp.SourceModule, ".", string(filepath.Separator))
-	var moduleFile string
-	for _, workspaceRoot := range ClientSettings.ModulesPath {
+// ImportedNameError is returned for `from x import Name` when module x
+// exists but doesn't bind Name.
+type ImportedNameError struct {
+	Module string
+	Name   string
+	File   *PythonFile
+}
+
+func (e *ImportedNameError) Error() string {
+	return fmt.Sprintf("%q not found in module %q", e.Name, e.Module)
+}
+
+// modulesPath returns the module paths the imports of the file are resolved
+// with, those of its workspace folder.
+func (f *PythonFile) modulesPath() []string {
+	return f.workspace.modulesPathFor(strings.TrimPrefix(f.Url, "file://"))
+}
+
+// findModuleFile looks the module up in modulesPath. An empty path without an
+// error means the module exists but has no Python source we can parse:
+// builtins, compiled extensions and namespace packages.
+func findModuleFile(modulesPath []string, sourceModule string) (string, error) {
+	path, _, err := locateModule(modulesPath, sourceModule)
+	return path, err
+}
+
+// locateModule is findModuleFile also returning the modulesPath entry the
+// module was found in, empty for builtins.
+func locateModule(modulesPath []string, sourceModule string) (string, string, error) {
+	if builtinModules[sourceModule] {
+		return "", "", nil
+	}
+	module := strings.ReplaceAll(sourceModule, ".", string(filepath.Separator))
+	sourcelessIn := ""
+	for _, workspaceRoot := range modulesPath {
 		path := filepath.Join(workspaceRoot, module)
 
 		// Try as a module: foo/bar.py
 		filePath := path + ".py"
 		if _, err := os.Stat(filePath); err == nil {
-			moduleFile = filePath
-			break
+			return filePath, workspaceRoot, nil
 		}
 
 		// Try as a package: foo/bar/__init__.py
 		filePath = filepath.Join(path, "__init__.py")
 		if _, err := os.Stat(filePath); err == nil {
-			moduleFile = filePath
-			break
+			return filePath, workspaceRoot, nil
+		}
+
+		// Namespace package (foo/bar/) or compiled extension (foo/bar.cpython-312-x86_64-linux-gnu.so).
+		// Keep looking, a later path can still hold the sources.
+		if sourcelessIn != "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			sourcelessIn = workspaceRoot
+		} else if extensions, _ := filepath.Glob(path + ".*so"); len(extensions) > 0 {
+			sourcelessIn = workspaceRoot
+		} else if _, err := os.Stat(path + ".pyd"); err == nil {
+			sourcelessIn = workspaceRoot
 		}
 	}
-	if moduleFile == "" {
+	if sourcelessIn != "" {
+		return "", sourcelessIn, nil
+	}
+	return "", "", &ModuleNotFoundError{
+		Module:      sourceModule,
+		SearchPaths: slices.Clone(modulesPath),
+	}
+}
+
+// resolveImportSymbol resolves the file and the symbol the import points to.
+// A nil symbol without an error means the import is valid but doesn't point
+// to a class or a function (plain module imports, variables, submodules).
+func resolveImportSymbol(file *PythonFile, imp *Import) (*Symbol, error) {
+	// slog.Debug("Resolve import symbol for file", slog.String("fileUrl", file.Url), slog.String("importedName", imp.ImportedName), slog.String("sourceModule", imp.SourceModule))
+	moduleFile, err := findModuleFile(file.modulesPath(), imp.SourceModule)
+	if err != nil {
 		slog.Warn("File for module not found", slog.String("module", imp.SourceModule))
-		return nil, errors.New("module file not found")
+		return nil, err
+	}
+	if moduleFile == "" {
+		return nil, nil
 	}
 
-	// Get or create destination pythonFile
+	// Get or create destination pythonFile, a project one when it belongs to
+	// a workspace folder, e.g. another folder of the workspace.
 	fileUrl := "file://" + moduleFile
-	dstFile, err := GetPythonFile(fileUrl)
+	dstFile, err := file.workspace.GetPythonFile(fileUrl)
 	if err != nil {
-		dstFile, err = ImportPythonFileFromFile(moduleFile, true)
+		dstFile, err = file.workspace.ImportPythonFileFromFile(moduleFile, !file.workspace.IsProjectFile(fileUrl))
 		if err != nil {
 			slog.Warn("Error importing file", slog.String("fileUrl", fileUrl), slog.Any("error", err))
 			return nil, err
 		}
 	}
+	imp.PythonFile = dstFile
+	if imp.ImportedName == "" {
+		return nil, nil
+	}
 
 	// Get symbols from the destination file
 	fileSymbols, err := dstFile.FileSymbols("")
@@ -129,43 +219,83 @@ func resolveImportSymbol(file *PythonFile, imp *Import) (*Symbol, error) {
 		}
 	}
 
-	var imports []Import
-	if dstFile.Imports == nil {
+	imports := dstFile.loadedImports()
+	if imports == nil {
 		imports, err = dstFile.parseImports(false)
 		if err != nil {
 			slog.Warn("Error parsing nested imports", slog.String("fileUrl", fileUrl), slog.Any("error", err))
 		}
-	} else {
-		imports = dstFile.Imports
 	}
 	for _, nestedImport := range imports {
 		if nestedImport.ImportedName == imp.ImportedName {
-			return resolveImportSymbol(file, &nestedImport)
+			symbol, err := resolveImportSymbol(file, &nestedImport)
+			if err == nil {
+				return symbol, nil
+			}
+			// The re-export itself is broken, the name is still bound in dstFile.
+			break
 		}
 	}
 
-	return nil, errors.New("symbol not found")
+	// `from package import submodule`
+	if filepath.Base(moduleFile) == "__init__.py" {
+		if _, err := findModuleFile(file.modulesPath(), imp.SourceModule+"."+imp.ImportedName); err == nil {
+			return nil, nil
+		}
+	}
+	if dstFile.DefinesName(imp.ImportedName) {
+		return nil, nil
+	}
+
+	return nil, &ImportedNameError{
+		Module: imp.SourceModule,
+		Name:   imp.ImportedName,
+		File:   dstFile,
+	}
+}
+
+// resolveImport links the import to the file and the symbol it points to,
+// or records why it can't be resolved.
+func resolveImport(file *PythonFile, imp *Import) {
+	symbol, err := resolveImportSymbol(file, imp)
+	if err != nil {
+		imp.ResolveError = err
+	} else if symbol != nil {
+		imp.Symbol = symbol
+		imp.PythonFile = symbol.File
+	}
 }
 
 func processImports(pythonFile *PythonFile, qc *tree_sitter.QueryCursor, query *tree_sitter.Query, withResolvedSymbols bool) []Import {
 	imports := []Import{}
-	matches := qc.Matches(query, pythonFile.GetOrCreateAst(), []byte(pythonFile.Text))
+	ast := pythonFile.GetOrCreateAst()
+	defer ast.Close()
+	source, encoding := ast.Source, pythonFile.positionEncoding()
+	matches := qc.Matches(query, ast.Root, source)
 	for match := matches.Next(); match != nil; match = matches.Next() {
 		var sourceModule string
 		var aliasName string
 		var importedName string
+		var moduleRange, nameRange messages.Range
+		var moduleNode, nameNode *tree_sitter.Node
 
 		for _, capture := range match.Captures {
 			captureName := query.CaptureNames()[capture.Index]
-			captureText := capture.Node.Utf8Text([]byte(pythonFile.Text))
+			captureText := capture.Node.Utf8Text(source)
 
 			switch captureName {
 			case "module":
 				sourceModule = captureText
+				moduleRange = nodeRange(&capture.Node, source, encoding)
+				node := capture.Node
+				moduleNode = &node
 			case "alias":
 				aliasName = captureText
 			case "imported_name":
 				importedName = captureText
+				nameRange = nodeRange(&capture.Node, source, encoding)
+				node := capture.Node
+				nameNode = &node
 			}
 		}
 		if sourceModule != "" {
@@ -173,14 +303,15 @@ func processImports(pythonFile *PythonFile, qc *tree_sitter.QueryCursor, query *
 				Alias:        aliasName,
 				SourceModule: sourceModule,
 				ImportedName: importedName,
+				ModuleRange:  moduleRange,
+				NameRange:    nameRange,
 			}
+			if nameNode == nil {
+				nameNode = moduleNode
+			}
+			setImportRanges(&i, nameNode, source, encoding)
 			if withResolvedSymbols {
-				symbol, err := resolveImportSymbol(pythonFile, &i)
-				if err == nil {
-					i.Symbol = symbol
-					i.PythonFile = symbol.File
-
-				}
+				resolveImport(pythonFile, &i)
 			}
 
 			imports = append(imports, i)
@@ -189,6 +320,91 @@ func processImports(pythonFile *PythonFile, qc *tree_sitter.QueryCursor, query *
 	return imports
 }
 
+// setImportRanges fills the ranges derived from the node of the imported name
+// (`Bar` in `from foo import Bar`, `foo` in `import foo`).
+func setImportRanges(imp *Import, nameNode *tree_sitter.Node, source []byte, encoding messages.PositionEncodingKind) {
+	if parent := nameNode.Parent(); parent != nil && parent.Kind() == "aliased_import" {
+		nameNode = parent
+	}
+	statement := nameNode.Parent()
+	if statement == nil {
+		return
+	}
+	imp.BindingRange = nodeRange(nameNode, source, encoding)
+	imp.StatementRange = nodeRange(statement, source, encoding)
+	imp.TypeChecking = isTypeCheckingBlock(statement, source)
+
+	imp.RemovalRange = importNameRemovalRange(statement, nameNode, source, encoding)
+}
+
+// importNameRemovalRange is what to delete to drop a single imported name
+// (with its alias), the whole statement when it's the only one.
+func importNameRemovalRange(statement, nameNode *tree_sitter.Node, source []byte, encoding messages.PositionEncodingKind) messages.Range {
+	cursor := statement.Walk()
+	defer cursor.Close()
+	names := statement.ChildrenByFieldName("name", cursor)
+	if len(names) <= 1 {
+		return statementRemovalRange(statement, source, encoding)
+	}
+	for i, name := range names {
+		if name.Id() != nameNode.Id() {
+			continue
+		}
+		if i < len(names)-1 {
+			// `a, ` up to the next name
+			return messages.Range{
+				Start: nodeRange(nameNode, source, encoding).Start,
+				End:   nodeRange(&names[i+1], source, encoding).Start,
+			}
+		}
+		// `, a` from the end of the previous name
+		return messages.Range{
+			Start: nodeRange(&names[i-1], source, encoding).End,
+			End:   nodeRange(nameNode, source, encoding).End,
+		}
+	}
+	return nodeRange(nameNode, source, encoding)
+}
+
+// statementRemovalRange covers whole lines when the statement is alone on its
+// lines, so removing it doesn't leave an empty line behind.
+func statementRemovalRange(statement *tree_sitter.Node, source []byte, encoding messages.PositionEncodingKind) messages.Range {
+	rng := nodeRange(statement, source, encoding)
+	start, end := int(statement.StartByte()), int(statement.EndByte())
+	for start > 0 && (source[start-1] == ' ' || source[start-1] == '\t') {
+		start--
+	}
+	for end < len(source) && (source[end] == ' ' || source[end] == '\t') {
+		end++
+	}
+	lineStart := start == 0 || source[start-1] == '\n'
+	lineEnd := end == len(source) || source[end] == '\n' || source[end] == '#'
+	if lineStart && lineEnd {
+		rng.Start.Character = 0
+		rng.End = messages.Position{Line: rng.End.Line + 1, Character: 0}
+	}
+	return rng
+}
+
+// isTypeCheckingBlock reports whether the node is nested in an
+// `if TYPE_CHECKING:` (or `if typing.TYPE_CHECKING:`) block.
+func isTypeCheckingBlock(node *tree_sitter.Node, source []byte) bool {
+	for child, parent := node, node.Parent(); parent != nil; child, parent = parent, parent.Parent() {
+		if parent.Kind() != "if_statement" {
+			continue
+		}
+		condition := parent.ChildByFieldName("condition")
+		consequence := parent.ChildByFieldName("consequence")
+		if condition == nil || consequence == nil || consequence.Id() != child.Id() {
+			continue
+		}
+		if text := condition.Utf8Text(source); text == "TYPE_CHECKING" || strings.HasSuffix(text, ".TYPE_CHECKING") {
+			return true
+		}
+	}
+	return false
+}
+
 func getTreeSitterImportQuery() string {
 	return `
 ;; import pandas
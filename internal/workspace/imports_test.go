@@ -22,8 +22,9 @@ from pandas import (
 
 	// Create a mock PythonFile
 	mockFile := &PythonFile{
-		Text: pythonCode,
-		Url:  "mock_file.py",
+		workspace: newTestWorkspace(),
+		Text:      pythonCode,
+		Url:       "mock_file.py",
 	}
 
 	// Parse imports
@@ -0,0 +1,462 @@
+package workspace
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"snakelsp/internal/messages"
+
+	"github.com/google/uuid"
+)
+
+// Bumped whenever the cached structures change.
+const indexCacheVersion = 1
+
+// DefaultIndexCacheDir is `snakelsp` in the user cache folder, e.g.
+// ~/.cache/snakelsp.
+func DefaultIndexCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "snakelsp")
+}
+
+// SetIndexCacheDir sets where the index cache is written, before the
+// workspace is indexed. Caching is disabled when empty.
+func (w *Workspace) SetIndexCacheDir(dir string) {
+	w.indexCacheDir = dir
+}
+
+// fileStamp identifies the content of a file on disk.
+type fileStamp struct {
+	Size    int64
+	ModTime int64
+	Hash    [sha256.Size]byte
+}
+
+func newFileStamp(info os.FileInfo, content []byte) fileStamp {
+	return fileStamp{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Hash: sha256.Sum256(content)}
+}
+
+// sameFile reports whether the stamp is the one of an unchanged file: the
+// same size and modification time, or else the same content.
+func (s fileStamp) sameFile(info os.FileInfo, content []byte) bool {
+	if s.Size != info.Size() {
+		return false
+	}
+	return s.ModTime == info.ModTime().UnixNano() || s.Hash == sha256.Sum256(content)
+}
+
+type cachedSymbol struct {
+	UUID               uuid.UUID
+	Name               string
+	Kind               messages.SymbolKind
+	Parameters         string
+	ReturnType         string
+	FullName           string
+	Range              messages.Range
+	NameRange          messages.Range
+	Children           []cachedSymbol
+	Params             []Parameter
+	Decorators         []string
+	Async              bool
+	SuperObjectsNames  []string
+	SuperObjectsRanges []messages.Range
+}
+
+// cachedImport is an import before it's resolved, resolving depends on
+// other files.
+type cachedImport struct {
+	Alias          string
+	SourceModule   string
+	ImportedName   string
+	ModuleRange    messages.Range
+	NameRange      messages.Range
+	BindingRange   messages.Range
+	StatementRange messages.Range
+	RemovalRange   messages.Range
+	TypeChecking   bool
+}
+
+type cachedFile struct {
+	Stamp   fileStamp
+	Symbols []cachedSymbol
+	Imports []cachedImport
+}
+
+// indexCache is the cache of a workspace, or of a distribution installed in
+// site-packages, keyed by file path.
+type indexCache struct {
+	Version          int
+	PositionEncoding messages.PositionEncodingKind
+	Files            map[string]cachedFile
+}
+
+func cacheSymbols(symbols []*Symbol) []cachedSymbol {
+	cached := make([]cachedSymbol, 0, len(symbols))
+	for _, symbol := range symbols {
+		cached = append(cached, cachedSymbol{
+			UUID:               symbol.UUID,
+			Name:               symbol.Name,
+			Kind:               symbol.Kind,
+			Parameters:         symbol.Parameters,
+			ReturnType:         symbol.ReturnType,
+			FullName:           symbol.FullName,
+			Range:              symbol.Range,
+			NameRange:          symbol.NameRange,
+			Children:           cacheSymbols(symbol.Children),
+			Params:             symbol.Params,
+			Decorators:         symbol.Decorators,
+			Async:              symbol.Async,
+			SuperObjectsNames:  symbol.superObjectsNames,
+			SuperObjectsRanges: symbol.superObjectsRanges,
+		})
+	}
+	return cached
+}
+
+// restoreSymbols rebuilds the symbols of a file, unlinked from their base
+// classes like freshly parsed ones.
+func restoreSymbols(f *PythonFile, cached []cachedSymbol, parent *Symbol) []*Symbol {
+	symbols := make([]*Symbol, 0, len(cached))
+	for _, c := range cached {
+		symbol := &Symbol{
+			UUID:               c.UUID,
+			Name:               c.Name,
+			Kind:               c.Kind,
+			Parameters:         c.Parameters,
+			ReturnType:         c.ReturnType,
+			FullName:           c.FullName,
+			File:               f,
+			Range:              c.Range,
+			NameRange:          c.NameRange,
+			Parent:             parent,
+			Params:             c.Params,
+			Decorators:         c.Decorators,
+			Async:              c.Async,
+			superObjectsNames:  c.SuperObjectsNames,
+			superObjectsRanges: c.SuperObjectsRanges,
+		}
+		symbol.Children = restoreSymbols(f, c.Children, symbol)
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+func cacheImports(imports []Import) []cachedImport {
+	cached := make([]cachedImport, 0, len(imports))
+	for _, imp := range imports {
+		cached = append(cached, cachedImport{
+			Alias:          imp.Alias,
+			SourceModule:   imp.SourceModule,
+			ImportedName:   imp.ImportedName,
+			ModuleRange:    imp.ModuleRange,
+			NameRange:      imp.NameRange,
+			BindingRange:   imp.BindingRange,
+			StatementRange: imp.StatementRange,
+			RemovalRange:   imp.RemovalRange,
+			TypeChecking:   imp.TypeChecking,
+		})
+	}
+	return cached
+}
+
+func restoreImports(cached []cachedImport) []Import {
+	imports := make([]Import, 0, len(cached))
+	for _, c := range cached {
+		imports = append(imports, Import{
+			Alias:          c.Alias,
+			SourceModule:   c.SourceModule,
+			ImportedName:   c.ImportedName,
+			ModuleRange:    c.ModuleRange,
+			NameRange:      c.NameRange,
+			BindingRange:   c.BindingRange,
+			StatementRange: c.StatementRange,
+			RemovalRange:   c.RemovalRange,
+			TypeChecking:   c.TypeChecking,
+		})
+	}
+	return imports
+}
+
+func pathHash(path string) string {
+	hash := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(hash[:8])
+}
+
+// projectCachePath is the cache file of a workspace folder, e.g.
+// ~/.cache/snakelsp/<folder-hash>/index.gob.
+func projectCachePath(cacheDir, root string) string {
+	return filepath.Join(cacheDir, pathHash(root), "index.gob")
+}
+
+// readIndexCache returns the cached files with ranges in the encoding, nil
+// when there is no usable cache.
+func readIndexCache(path string, encoding messages.PositionEncodingKind) map[string]cachedFile {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	var cache indexCache
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&cache); err != nil {
+		slog.Warn("Unable to read index cache", slog.String("path", path), slog.Any("error", err))
+		return nil
+	}
+	// Ranges are in the encoding of the client which wrote the cache.
+	if cache.Version != indexCacheVersion || cache.PositionEncoding != encoding {
+		return nil
+	}
+	return cache.Files
+}
+
+// writeIndexCache replaces the cache file at once, a concurrent reader never
+// sees it half written.
+func writeIndexCache(path string, encoding messages.PositionEncodingKind, files map[string]cachedFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	writer := bufio.NewWriter(temp)
+	cache := indexCache{Version: indexCacheVersion, PositionEncoding: encoding, Files: files}
+	if err := gob.NewEncoder(writer).Encode(cache); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// SaveIndexCache writes the symbols and imports of the project files whose
+// text is still the one on disk, one cache per workspace folder, and the
+// external files parsed since the last save.
+func (w *Workspace) SaveIndexCache() error {
+	if w.indexCacheDir == "" {
+		return nil
+	}
+	errs := []error{}
+	for _, folder := range w.Folders() {
+		errs = append(errs, w.saveProjectCache(folder))
+	}
+	return errors.Join(append(errs, saveDistributionCaches())...)
+}
+
+// saveProjectCache writes the cache of a single folder, the others may not
+// be indexed yet.
+func (w *Workspace) saveProjectCache(folder *WorkspaceFolder) error {
+	if w.indexCacheDir == "" || folder == nil {
+		return nil
+	}
+	files := map[string]cachedFile{}
+	w.Files.Range(func(key, value any) bool {
+		file := value.(*PythonFile)
+		if file.External || file.stamp.Size == 0 && file.stamp.ModTime == 0 {
+			return true
+		}
+		if path := strings.TrimPrefix(file.Url, "file://"); w.FolderOf(path) != folder {
+			return true
+		}
+		symbols, exists := w.Symbols.Load(file)
+		imports := file.loadedImports()
+		if !exists || imports == nil || sha256.Sum256([]byte(file.Text)) != file.stamp.Hash {
+			return true
+		}
+		files[strings.TrimPrefix(file.Url, "file://")] = cachedFile{
+			Stamp:   file.stamp,
+			Symbols: cacheSymbols(symbols.([]*Symbol)),
+			Imports: cacheImports(imports),
+		}
+		return true
+	})
+	return writeIndexCache(projectCachePath(w.indexCacheDir, folder.Root), w.positionEncoding, files)
+}
+
+// Site-packages files are cached per installed distribution, the same
+// version installs the same files whatever project uses the virtualenv.
+// They are shared by the workspaces.
+
+type sitePackagesIndex struct {
+	owners map[string]string // File path relative to site-packages to `name-version`
+}
+
+type distributionCache struct {
+	path     string
+	encoding messages.PositionEncodingKind
+	files    map[string]cachedFile // Keyed by path relative to site-packages
+	dirty    bool
+}
+
+// Workspaces of clients using another position encoding don't share the
+// cached ranges.
+type distributionCacheKey struct {
+	path     string
+	encoding messages.PositionEncodingKind
+}
+
+var (
+	distributionsMutex sync.Mutex
+	sitePackages       = map[string]*sitePackagesIndex{}
+	distributionCaches = map[distributionCacheKey]*distributionCache{}
+)
+
+// sitePackagesFor returns the site-packages folder of the module paths
+// holding the path.
+func (w *Workspace) sitePackagesFor(path string) (string, bool) {
+	for _, modulesPath := range w.allModulesPaths() {
+		base := filepath.Base(modulesPath)
+		if (base == "site-packages" || base == "dist-packages") && strings.HasPrefix(path, modulesPath+"/") {
+			return modulesPath, true
+		}
+	}
+	return "", false
+}
+
+// readSitePackages maps the installed files to their distribution, from the
+// RECORD files of the `.dist-info` folders.
+func readSitePackages(dir string) *sitePackagesIndex {
+	index := &sitePackagesIndex{owners: map[string]string{}}
+	records, _ := filepath.Glob(filepath.Join(dir, "*.dist-info", "RECORD"))
+	for _, record := range records {
+		distribution := strings.TrimSuffix(filepath.Base(filepath.Dir(record)), ".dist-info")
+		file, err := os.Open(record)
+		if err != nil {
+			continue
+		}
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		for {
+			row, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				break
+			}
+			if len(row) > 0 && isPythonSource(row[0]) {
+				index.owners[filepath.Clean(row[0])] = distribution
+			}
+		}
+		file.Close()
+	}
+	return index
+}
+
+// distributionCacheFor returns the cache of the distribution which installed
+// the file, with the file key in it.
+func (w *Workspace) distributionCacheFor(path string) (*distributionCache, string, bool) {
+	if w.indexCacheDir == "" {
+		return nil, "", false
+	}
+	dir, ok := w.sitePackagesFor(path)
+	if !ok {
+		return nil, "", false
+	}
+	relative, err := filepath.Rel(dir, path)
+	if err != nil {
+		return nil, "", false
+	}
+	distributionsMutex.Lock()
+	defer distributionsMutex.Unlock()
+	index, ok := sitePackages[dir]
+	if !ok {
+		index = readSitePackages(dir)
+		sitePackages[dir] = index
+	}
+	distribution, ok := index.owners[relative]
+	if !ok {
+		return nil, "", false
+	}
+	key := distributionCacheKey{
+		path:     filepath.Join(w.indexCacheDir, "site-packages", pathHash(dir), distribution+".gob"),
+		encoding: w.positionEncoding,
+	}
+	cache, ok := distributionCaches[key]
+	if !ok {
+		files := readIndexCache(key.path, key.encoding)
+		if files == nil {
+			files = map[string]cachedFile{}
+		}
+		cache = &distributionCache{path: key.path, encoding: key.encoding, files: files}
+		distributionCaches[key] = cache
+	}
+	return cache, relative, true
+}
+
+// cachedExternalSymbols returns the cached symbols of a site-packages file.
+func cachedExternalSymbols(f *PythonFile) ([]*Symbol, bool) {
+	path := strings.TrimPrefix(f.Url, "file://")
+	cache, key, ok := f.workspace.distributionCacheFor(path)
+	if !ok {
+		return nil, false
+	}
+	distributionsMutex.Lock()
+	cached, ok := cache.files[key]
+	distributionsMutex.Unlock()
+	if !ok {
+		return nil, false
+	}
+	info, err := os.Stat(path)
+	if err != nil || !cached.Stamp.sameFile(info, []byte(f.Text)) {
+		return nil, false
+	}
+	return restoreSymbols(f, cached.Symbols, nil), true
+}
+
+// rememberExternalSymbols adds the symbols of a site-packages file to the
+// cache of its distribution.
+func rememberExternalSymbols(f *PythonFile, symbols []*Symbol) {
+	path := strings.TrimPrefix(f.Url, "file://")
+	cache, key, ok := f.workspace.distributionCacheFor(path)
+	if !ok {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	distributionsMutex.Lock()
+	defer distributionsMutex.Unlock()
+	cache.files[key] = cachedFile{
+		Stamp:   newFileStamp(info, []byte(f.Text)),
+		Symbols: cacheSymbols(symbols),
+	}
+	cache.dirty = true
+}
+
+func saveDistributionCaches() error {
+	distributionsMutex.Lock()
+	defer distributionsMutex.Unlock()
+	var errs []error
+	for _, cache := range distributionCaches {
+		if !cache.dirty {
+			continue
+		}
+		if err := writeIndexCache(cache.path, cache.encoding, cache.files); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cache.dirty = false
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,125 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexProjectFromCache(t *testing.T) {
+	t.Parallel()
+	w, root := setupModulesPath(t, map[string]string{
+		"app/__init__.py": "",
+		"app/base.py":     "class Base:\n    def run(self):\n        pass\n",
+		"app/child.py":    "from app.base import Base\n\nclass Child(Base):\n    def run(self):\n        pass\n",
+		"app/other.py":    "class Other:\n    pass\n",
+	})
+	cacheDir := t.TempDir()
+	w.SetIndexCacheDir(cacheDir)
+
+	require.NoError(t, w.IndexProject(root, filepath.Join(root, ".venv"), nil))
+	base, err := w.GetPythonFile("file://" + filepath.Join(root, "app/base.py"))
+	require.NoError(t, err)
+	baseSymbols, err := base.FileSymbols("")
+	require.NoError(t, err)
+	baseUUID := baseSymbols[0].UUID
+	_, err = os.Stat(projectCachePath(cacheDir, root))
+	require.NoError(t, err)
+
+	// Restarted with one file changed on disk
+	w = newTestWorkspace(w.Folders()...)
+	w.SetIndexCacheDir(cacheDir)
+	otherPath := filepath.Join(root, "app/other.py")
+	require.NoError(t, os.WriteFile(otherPath, []byte("class Other:\n    pass\n\nclass Added:\n    pass\n"), 0o644))
+	require.NoError(t, w.IndexProject(root, filepath.Join(root, ".venv"), nil))
+
+	base, err = w.GetPythonFile("file://" + filepath.Join(root, "app/base.py"))
+	require.NoError(t, err)
+	assert.Nil(t, base.astRoot)
+	baseSymbols, err = base.FileSymbols("")
+	require.NoError(t, err)
+	require.Len(t, baseSymbols, 1)
+	assert.Equal(t, baseUUID, baseSymbols[0].UUID)
+	assert.Same(t, base, baseSymbols[0].File)
+
+	child, err := w.GetPythonFile("file://" + filepath.Join(root, "app/child.py"))
+	require.NoError(t, err)
+	require.Len(t, child.Imports, 1)
+	assert.Same(t, baseSymbols[0], child.Imports[0].Symbol)
+	childSymbols, err := child.FileSymbols("")
+	require.NoError(t, err)
+	assert.Equal(t, []*Symbol{baseSymbols[0]}, childSymbols[0].SuperObjects)
+	assert.Equal(t, []*Symbol{baseSymbols[0].Children[0]}, childSymbols[0].Children[0].SuperObjects)
+
+	other, err := w.GetPythonFile("file://" + otherPath)
+	require.NoError(t, err)
+	assert.NotNil(t, other.astRoot)
+	otherSymbols, err := other.FileSymbols("")
+	require.NoError(t, err)
+	assert.Len(t, otherSymbols, 2)
+
+	// Cached files are parsed when needed
+	ast := base.GetOrCreateAst()
+	defer ast.Close()
+	assert.Equal(t, "module", ast.Root.Kind())
+}
+
+func TestDistributionCache(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"site-packages/lib/__init__.py":            "",
+		"site-packages/lib/core.py":                "class Core:\n    pass\n",
+		"site-packages/lib-1.0.dist-info/RECORD":   "lib/__init__.py,sha256=x,0\nlib/core.py,sha256=y,21\nlib-1.0.dist-info/RECORD,,\n",
+		"site-packages/lib-1.0.dist-info/METADATA": "Name: lib\n",
+	})
+	sitePackagesPath := filepath.Join(root, "site-packages")
+	w.settings.Folders[0].ModulesPath = []string{sitePackagesPath}
+	cacheDir := t.TempDir()
+	w.SetIndexCacheDir(cacheDir)
+	t.Cleanup(func() {
+		distributionsMutex.Lock()
+		clear(sitePackages)
+		clear(distributionCaches)
+		distributionsMutex.Unlock()
+	})
+
+	file, err := w.ImportPythonFileFromFile(filepath.Join(sitePackagesPath, "lib/core.py"), true)
+	require.NoError(t, err)
+	symbols, err := file.FileSymbols("")
+	require.NoError(t, err)
+	require.Len(t, symbols, 1)
+	require.NoError(t, saveDistributionCaches())
+
+	// Another session reads the symbols from the distribution cache
+	w = newTestWorkspace(w.Folders()...)
+	w.SetIndexCacheDir(cacheDir)
+	distributionsMutex.Lock()
+	clear(sitePackages)
+	clear(distributionCaches)
+	distributionsMutex.Unlock()
+	_, err = os.Stat(filepath.Join(cacheDir, "site-packages", pathHash(sitePackagesPath), "lib-1.0.gob"))
+	require.NoError(t, err)
+
+	file, err = w.ImportPythonFileFromFile(filepath.Join(sitePackagesPath, "lib/core.py"), true)
+	require.NoError(t, err)
+	cached, ok := cachedExternalSymbols(file)
+	require.True(t, ok)
+	require.Len(t, cached, 1)
+	assert.Equal(t, symbols[0].UUID, cached[0].UUID)
+	assert.Equal(t, symbols[0].NameRange, cached[0].NameRange)
+
+	// An upgrade keeping the size of the file isn't read from the cache
+	corePath := filepath.Join(sitePackagesPath, "lib/core.py")
+	require.NoError(t, os.WriteFile(corePath, []byte("class Cora:\n    pass\n"), 0o644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(corePath, later, later))
+	w = newTestWorkspace(w.Folders()...)
+	w.SetIndexCacheDir(cacheDir)
+	file, err = w.ImportPythonFileFromFile(corePath, true)
+	require.NoError(t, err)
+	_, ok = cachedExternalSymbols(file)
+	assert.False(t, ok)
+}
@@ -0,0 +1,348 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/progress"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+)
+
+// indexWorker holds what can't be shared between goroutines, compiled
+// queries are.
+type indexWorker struct {
+	parser *tree_sitter.Parser
+	cursor *tree_sitter.QueryCursor
+}
+
+func newIndexWorker() *indexWorker {
+	parser := tree_sitter.NewParser()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_python.Language()))
+	return &indexWorker{parser: parser, cursor: tree_sitter.NewQueryCursor()}
+}
+
+func (w *indexWorker) close() {
+	w.parser.Close()
+	w.cursor.Close()
+}
+
+// indexProgress reports the stages of the indexing as a single progress,
+// each file counting once per stage.
+type indexProgress struct {
+	mutex   sync.Mutex
+	pr      *progress.WorkDone
+	total   int
+	done    int
+	percent uint16
+}
+
+func (p *indexProgress) step(stage string, count int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.done++
+	percent := uint16(p.done * 100 / max(p.total, 1))
+	if percent != p.percent {
+		p.percent = percent
+		p.pr.Report(fmt.Sprintf("%s (%d files)", stage, count), percent)
+	}
+}
+
+// forEachParallel calls work for every index below count, spread over that
+// many index workers, until the context is canceled.
+func forEachParallel(ctx context.Context, workers, count int, work func(w *indexWorker, i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, max(count, 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker := newIndexWorker()
+			defer worker.close()
+			for i := range indexes {
+				work(worker, i)
+			}
+		}()
+	}
+	for i := range count {
+		if ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// IndexProject indexes the Python files of the project: they are read and
+// parsed, their symbols extracted, their imports resolved and the base
+// classes linked. Every stage runs on a pool of workers, results are stored
+// in the order of the file paths so the index is the same on every run.
+//
+// Files unchanged since the index cache was written aren't parsed, their
+// symbols are available before the changed files are parsed.
+//
+// Canceling the progress stops the indexing after the current stage, with the
+// files indexed so far.
+func (w *Workspace) IndexProject(projectPath string, envPath string, pr *progress.WorkDone) error {
+	started := time.Now()
+	ctx := pr.Context()
+	pr.Start("Indexing project")
+	paths := w.discoverProjectFiles(projectPath, envPath)
+	indexProgress := &indexProgress{pr: pr, total: 4 * len(paths)}
+	var cache map[string]cachedFile
+	if w.indexCacheDir != "" {
+		cache = readIndexCache(projectCachePath(w.indexCacheDir, projectPath), w.positionEncoding)
+	}
+
+	files := make([]*PythonFile, len(paths))
+	cachedFiles := make([]*cachedFile, len(paths))
+	forEachParallel(ctx, w.indexWorkers, len(paths), func(worker *indexWorker, i int) {
+		defer indexProgress.step("Parsing", len(paths))
+		info, err := os.Stat(paths[i])
+		if err != nil {
+			slog.Warn("Unable to read file", slog.String("path", paths[i]), slog.Any("error", err))
+			return
+		}
+		content, err := os.ReadFile(paths[i])
+		if err != nil {
+			slog.Warn("Unable to read file", slog.String("path", paths[i]), slog.Any("error", err))
+			return
+		}
+		file := w.NewPythonFile("file://"+paths[i], string(content), false, false)
+		if !file.needsIndexing() {
+			return
+		}
+		files[i] = file
+		if cached, ok := cache[paths[i]]; ok && cached.Stamp.sameFile(info, content) {
+			file.stamp = cached.Stamp
+			cachedFiles[i] = &cached
+			return
+		}
+		file.stamp = newFileStamp(info, content)
+		file.setTree(worker.parser.Parse([]byte(file.Text), nil))
+	})
+	if ctx.Err() != nil {
+		pr.End("Indexing canceled")
+		return ctx.Err()
+	}
+	// Files loaded as external ones before the folder was indexed, e.g.
+	// imported from another folder, become project files. Files importing
+	// them are relinked once indexed.
+	kept := 0
+	dependents := map[*PythonFile]bool{}
+	for i := range files {
+		if files[i] == nil {
+			continue
+		}
+		if files[i].makeProjectFile() {
+			for _, dependent := range files[i].Dependents() {
+				dependents[dependent] = true
+			}
+		}
+		files[kept], cachedFiles[kept] = files[i], cachedFiles[i]
+		kept++
+	}
+	files, cachedFiles = files[:kept], cachedFiles[:kept]
+	for _, file := range files {
+		delete(dependents, file)
+	}
+	// The other stages only go through the files kept.
+	indexProgress.total = len(paths) + 3*len(files)
+
+	// The ordered index is filled in a single goroutine, cached symbols first
+	// so they can be searched while the others are extracted.
+	restored := 0
+	for i, file := range files {
+		if cachedFiles[i] != nil {
+			storeSymbols(file, restoreSymbols(file, cachedFiles[i].Symbols, nil))
+			indexProgress.step("Loading cached symbols", len(files))
+			restored++
+		}
+	}
+	if cache != nil {
+		slog.Info("Index cache loaded", slog.Int("cached", restored), slog.Int("changed", len(files)-restored))
+	}
+	symbolsQuery, err := symbolsQuery()
+	if err != nil {
+		return err
+	}
+	symbols := make([][]*Symbol, len(files))
+	forEachParallel(ctx, w.indexWorkers, len(files), func(worker *indexWorker, i int) {
+		if cachedFiles[i] != nil {
+			return
+		}
+		defer indexProgress.step("Extracting symbols", len(files))
+		symbols[i] = processSymbols(files[i], worker.cursor, symbolsQuery)
+	})
+	if ctx.Err() != nil {
+		pr.End("Indexing canceled")
+		return ctx.Err()
+	}
+	for i, file := range files {
+		if cachedFiles[i] == nil {
+			storeSymbols(file, symbols[i])
+		}
+	}
+
+	importsQuery, err := importsQuery()
+	if err != nil {
+		return err
+	}
+	// Imports are assigned once all are resolved, so resolving doesn't depend
+	// on which files were done first.
+	imports := make([][]Import, len(files))
+	forEachParallel(ctx, w.indexWorkers, len(files), func(worker *indexWorker, i int) {
+		defer indexProgress.step("Resolving imports", len(files))
+		if cachedFiles[i] == nil {
+			imports[i] = processImports(files[i], worker.cursor, importsQuery, true)
+			return
+		}
+		imports[i] = restoreImports(cachedFiles[i].Imports)
+		for j := range imports[i] {
+			resolveImport(files[i], &imports[i][j])
+		}
+	})
+	for i, file := range files {
+		file.setImports(imports[i])
+	}
+
+	forEachParallel(ctx, w.indexWorkers, len(files), func(worker *indexWorker, i int) {
+		defer indexProgress.step("Linking symbols", len(files))
+		files[i].linkSymbols()
+	})
+	for dependent := range dependents {
+		dependent.ParseImports()
+		dependent.relinkSymbols()
+		dependent.PublishDiagnostics()
+	}
+	if folder := w.FolderOf(projectPath); folder != nil {
+		folder.indexed.Store(true)
+	}
+
+	if ctx.Err() != nil {
+		pr.End("Indexing canceled")
+		return ctx.Err()
+	}
+	slog.Info("Project indexed", slog.Int("files", len(files)), slog.Int("workers", w.indexWorkers), slog.Duration("duration", time.Since(started)))
+	pr.End("Project indexed")
+	if err := errors.Join(w.saveProjectCache(w.FolderOf(projectPath)), saveDistributionCaches()); err != nil {
+		slog.Warn("Unable to save index cache", slog.Any("error", err))
+	}
+	return nil
+}
+
+// discoverProjectFiles lists the Python files of the project, sorted, without
+// the ones its folder ignores. Workspace folders nested in it are indexed on
+// their own.
+func (w *Workspace) discoverProjectFiles(projectPath string, envPath string) []string {
+	paths := []string{}
+	folder := w.FolderOf(projectPath)
+	filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() && (envPath == path || path != projectPath && w.isFolderRoot(path)) {
+			return filepath.SkipDir
+		}
+		if folder != nil && folder.ignoresEntry(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".py" {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	slices.Sort(paths)
+	return paths
+}
+
+// setTree stores a tree parsed by an index worker, unless the file got one in
+// the meantime, e.g. opened in the editor.
+func (f *PythonFile) setTree(tree *tree_sitter.Tree) {
+	f.astMutex.Lock()
+	defer f.astMutex.Unlock()
+	if f.astRoot != nil {
+		tree.Close()
+		return
+	}
+	f.astTree = tree
+	f.astRoot = tree.RootNode()
+}
+
+// needsIndexing reports whether the indexer takes the file over: new files,
+// external ones and files only loaded so far, e.g. imported from another
+// folder. Opened and indexed files are kept in line by edits and file
+// events, opened files loaded as external ones become project files right
+// away.
+func (f *PythonFile) needsIndexing() bool {
+	f.astMutex.Lock()
+	opened := f.isOpened
+	f.astMutex.Unlock()
+	if opened {
+		if f.makeProjectFile() {
+			f.debouncer.Debounce(f.parseOnUpdate)
+		}
+		return false
+	}
+	return f.External || f.loadedImports() == nil
+}
+
+// makeProjectFile turns an external file into a project one, dropping the
+// symbols read as an external file so they are indexed again. It reports
+// whether the file was external.
+func (f *PythonFile) makeProjectFile() bool {
+	f.astMutex.Lock()
+	external := f.External
+	if external {
+		// Project files stay untouched, other files read the flag.
+		f.External = false
+	}
+	f.astMutex.Unlock()
+	if external {
+		f.forgetSymbols()
+	}
+	return external
+}
+
+// storeSymbols adds the symbols of a file to the index. Symbols already
+// there, e.g. of a file imported before its folder got indexed, are kept as
+// they are: other files refer to them and they were read from the same text.
+func storeSymbols(f *PythonFile, symbols []*Symbol) {
+	defer f.invalidateSymbolIndex()
+	w := f.workspace
+	if _, loaded := w.Symbols.LoadOrStore(f, symbols); loaded {
+		return
+	}
+	w.addFlatSymbols(symbols)
+}
+
+// linkSymbols resolves the base classes and overridden methods of the file
+// symbols. Only symbols of the file are modified.
+func (f *PythonFile) linkSymbols() {
+	symbols, err := f.FileSymbols("")
+	if err != nil {
+		return
+	}
+	for _, symbol := range symbols {
+		resolveExternalSuperclassSymbol(f, symbol)
+		for _, child := range symbol.Children {
+			if child.Kind == messages.SymbolKindMethod {
+				resolveExternalSuperMethodSymbol(f, child)
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/progress"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexProject(t *testing.T) {
+	t.Parallel()
+	files := map[string]string{
+		"app/__init__.py":      "",
+		"app/base.py":          "class Base:\n    def run(self):\n        pass\n",
+		".venv/lib/ignored.py": "class Ignored:\n    pass\n",
+	}
+	for i := range 40 {
+		files[fmt.Sprintf("app/mod%02d.py", i)] = fmt.Sprintf(
+			"from app.base import Base\nfrom app.missing import Nope\n\nclass Child%02d(Base):\n    def run(self):\n        pass\n", i)
+	}
+	w, root := setupModulesPath(t, files)
+	w.indexWorkers = 4
+
+	require.NoError(t, w.IndexProject(root, filepath.Join(root, ".venv"), nil))
+
+	_, err := w.GetPythonFile("file://" + filepath.Join(root, ".venv/lib/ignored.py"))
+	assert.Error(t, err)
+	base, err := w.GetPythonFile("file://" + filepath.Join(root, "app/base.py"))
+	require.NoError(t, err)
+	baseSymbols, err := base.FileSymbols("")
+	require.NoError(t, err)
+
+	// Symbols are stored in the order of the file paths
+	names := []string{}
+	for _, symbol := range w.ProjectSymbols() {
+		if strings.HasPrefix(symbol.File.Url, "file://"+root) && symbol.Kind == messages.SymbolKindClass {
+			names = append(names, symbol.Name)
+		}
+	}
+	require.Len(t, names, 41)
+	assert.Equal(t, "Base", names[0])
+	assert.True(t, slices.IsSorted(names[1:]))
+
+	for i := range 40 {
+		file, err := w.GetPythonFile("file://" + filepath.Join(root, fmt.Sprintf("app/mod%02d.py", i)))
+		require.NoError(t, err)
+		require.Len(t, file.Imports, 2)
+		assert.Same(t, baseSymbols[0], file.Imports[0].Symbol)
+		assert.Error(t, file.Imports[1].ResolveError)
+
+		symbols, err := file.FileSymbols("")
+		require.NoError(t, err)
+		require.Len(t, symbols, 1)
+		assert.Equal(t, []*Symbol{baseSymbols[0]}, symbols[0].SuperObjects)
+		assert.Equal(t, []*Symbol{baseSymbols[0].Children[0]}, symbols[0].Children[0].SuperObjects)
+	}
+}
+
+// Run with -race: indexing, reparsing an edited file and searching symbols
+// share the index.
+func TestIndexProjectConcurrently(t *testing.T) {
+	files := map[string]string{"app/edited.py": "class Edited:\n    pass\n"}
+	for i := range 20 {
+		files[fmt.Sprintf("app/mod%02d.py", i)] = fmt.Sprintf("class Mod%02d:\n    def run(self):\n        pass\n", i)
+	}
+	w, root := setupModulesPath(t, files)
+	edited := w.OpenFile("file://"+filepath.Join(root, "app/edited.py"), files["app/edited.py"], 1, false)
+	_, err := edited.parseSymbols()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, w.IndexProject(root, "", nil))
+	}()
+	go func() {
+		defer wg.Done()
+		for i := range 20 {
+			text := fmt.Sprintf("class Edited%02d:\n    pass\n", i)
+			require.NoError(t, edited.ApplyChange(messages.Integer(i+2), []messages.TextDocumentContentChangeEvent{{Text: text}}))
+			edited.parseOnUpdate()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			symbols, err := w.GetWorkspaceSymbols(context.Background(), "Mod")
+			assert.NoError(t, err)
+			for _, symbol := range symbols {
+				_, err := w.SearchSymbolByUUID(symbol.UUID)
+				assert.NoError(t, err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	symbols, err := w.GetWorkspaceSymbols(context.Background(), "")
+	require.NoError(t, err)
+	names := []string{}
+	for _, symbol := range symbols {
+		if symbol.Kind == messages.SymbolKindClass {
+			names = append(names, symbol.Name)
+		}
+	}
+	assert.Len(t, names, 21)
+	assert.Contains(t, names, "Edited19")
+}
+
+// progressClient records the percentages of the progress reports.
+type progressClient struct {
+	mutex       sync.Mutex
+	percentages []uint16
+}
+
+func (c *progressClient) Call(method string, params any) (any, error) { return nil, nil }
+
+func (c *progressClient) Notify(method string, params any) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if report, ok := params.(messages.ServerWorkDoneProgress); ok && report.WorkDoneProgress.Kind == "report" {
+		c.percentages = append(c.percentages, report.WorkDoneProgress.Percentage)
+	}
+}
+
+func TestIndexProjectProgress(t *testing.T) {
+	files := map[string]string{}
+	for i := range 10 {
+		files[fmt.Sprintf("mod%02d.py", i)] = fmt.Sprintf("class Mod%02d:\n    pass\n", i)
+	}
+	w, root := setupModulesPath(t, files)
+	tracker := progress.NewTracker(true)
+	client := &progressClient{}
+	require.NoError(t, w.IndexProject(root, "", tracker.NewWorkDone(client)))
+	require.NotEmpty(t, client.percentages)
+	assert.Equal(t, uint16(100), client.percentages[len(client.percentages)-1])
+
+	// Files indexed already are skipped, the progress still completes
+	require.NoError(t, os.WriteFile(filepath.Join(root, "added.py"), []byte("class Added:\n    pass\n"), 0o644))
+	client = &progressClient{}
+	require.NoError(t, w.IndexProject(root, "", tracker.NewWorkDone(client)))
+	require.NotEmpty(t, client.percentages)
+	assert.Equal(t, uint16(100), client.percentages[len(client.percentages)-1])
+	assert.True(t, slices.IsSorted(client.percentages))
+}
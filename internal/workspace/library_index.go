@@ -0,0 +1,176 @@
+package workspace
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"snakelsp/internal/progress"
+
+	"github.com/google/uuid"
+)
+
+// SymbolScope is what workspace symbol searches go through.
+type SymbolScope string
+
+const (
+	SymbolScopeProject SymbolScope = "project" // Project files only
+	SymbolScopeAll     SymbolScope = "all"     // Project files, site-packages and the standard library
+)
+
+// Folders of the libraries never indexed: tests of the standard library and
+// the site-packages folder nested in it.
+var excludedLibraryFolders = []string{"__pycache__", "test", "tests", "idlelib", "site-packages", "dist-packages"}
+
+// libraryIndex holds the symbols of the installed libraries, built in the
+// background the first time a search needs them, and again after the module
+// paths changed. Its files aren't project
+// files: their text is dropped once parsed, only the search uses them.
+type libraryIndex struct {
+	once    sync.Once
+	mutex   sync.RWMutex
+	symbols []*Symbol
+	byUUID  map[uuid.UUID]*Symbol
+}
+
+// SetLibraryIndexProgress sets how the progress of the library index is
+// reported.
+func (w *Workspace) SetLibraryIndexProgress(newProgress func() *progress.WorkDone) {
+	w.libraryIndexProgress = newProgress
+}
+
+// librarySymbols returns the library symbols indexed so far, starting the
+// index the first time.
+func (w *Workspace) librarySymbols() []*Symbol {
+	libraries := w.libraries.Load()
+	libraries.once.Do(func() {
+		var pr *progress.WorkDone
+		if w.libraryIndexProgress != nil {
+			pr = w.libraryIndexProgress()
+		}
+		go libraries.build(w, pr)
+	})
+	libraries.mutex.RLock()
+	defer libraries.mutex.RUnlock()
+	return slices.Clone(libraries.symbols)
+}
+
+// searchLibrarySymbol finds a symbol of the library index.
+func (w *Workspace) searchLibrarySymbol(id uuid.UUID) (*Symbol, bool) {
+	libraries := w.libraries.Load()
+	libraries.mutex.RLock()
+	defer libraries.mutex.RUnlock()
+	symbol, ok := libraries.byUUID[id]
+	return symbol, ok
+}
+
+// libraryPaths are the module paths of the folders other than their sources.
+func (w *Workspace) libraryPaths() []string {
+	paths := []string{}
+	for _, modulesPath := range w.allModulesPaths() {
+		if !w.isSourceRoot(modulesPath) {
+			paths = append(paths, modulesPath)
+		}
+	}
+	return paths
+}
+
+// discoverLibraryFiles lists the importable Python files of a library path.
+func discoverLibraryFiles(root string) []string {
+	paths := []string{}
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			// Folders which aren't packages, e.g. `.dist-info` ones
+			if path != root && (slices.Contains(excludedLibraryFolders, entry.Name()) || !isIdentifier(entry.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) == ".py" && isIdentifier(strings.TrimSuffix(entry.Name(), ".py")) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths
+}
+
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if r != '_' && !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || i > 0 && '0' <= r && r <= '9' || r > 127) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+func (l *libraryIndex) build(w *Workspace, pr *progress.WorkDone) {
+	started := time.Now()
+	pr.Start("Indexing libraries")
+	paths := []string{}
+	for _, root := range w.libraryPaths() {
+		paths = append(paths, discoverLibraryFiles(root)...)
+	}
+	indexProgress := &indexProgress{pr: pr, total: len(paths)}
+	query, err := symbolsQuery()
+	if err != nil {
+		pr.End("Libraries not indexed")
+		return
+	}
+	forEachParallel(pr.Context(), w.indexWorkers, len(paths), func(worker *indexWorker, i int) {
+		defer indexProgress.step("Indexing libraries", len(paths))
+		var symbols []*Symbol
+		if file, err := w.GetPythonFile("file://" + paths[i]); err == nil {
+			// Already imported by the project
+			symbols, _ = file.FileSymbols("")
+		} else {
+			content, err := os.ReadFile(paths[i])
+			if err != nil {
+				return
+			}
+			file := w.newPythonFile("file://"+paths[i], string(content), true, false)
+			var cached bool
+			if symbols, cached = cachedExternalSymbols(file); !cached {
+				file.setTree(worker.parser.Parse(content, nil))
+				symbols = processSymbols(file, worker.cursor, query)
+				rememberExternalSymbols(file, symbols)
+				file.CloseFile()
+			}
+			file.Text = ""
+		}
+		l.add(symbols)
+	})
+	if pr.Context().Err() != nil {
+		slog.Info("Library indexing canceled")
+		pr.End("Library indexing canceled")
+		return
+	}
+	l.mutex.RLock()
+	count := len(l.symbols)
+	l.mutex.RUnlock()
+	slog.Info("Libraries indexed", slog.Int("files", len(paths)), slog.Int("symbols", count), slog.Duration("duration", time.Since(started)))
+	pr.End("Libraries indexed")
+	if err := saveDistributionCaches(); err != nil {
+		slog.Warn("Unable to save index cache", slog.Any("error", err))
+	}
+}
+
+func (l *libraryIndex) add(symbols []*Symbol) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, symbol := range symbols {
+		l.symbols = append(l.symbols, symbol)
+		l.byUUID[symbol.UUID] = symbol
+		for _, child := range symbol.Children {
+			l.symbols = append(l.symbols, child)
+			l.byUUID[child.UUID] = child
+		}
+	}
+}
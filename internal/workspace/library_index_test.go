@@ -0,0 +1,67 @@
+package workspace
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibraryIndex(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"project/app/__init__.py":                  "",
+		"project/app/views.py":                     "class QuerySetView:\n    pass\n",
+		"site-packages/django/__init__.py":         "",
+		"site-packages/django/db/models/query.py":  "class QuerySet:\n    def filter(self):\n        pass\n",
+		"site-packages/django/tests/test_query.py": "class QuerySetTest:\n    pass\n",
+		"site-packages/django-5.0.dist-info/x.py":  "class Metadata:\n    pass\n",
+		"stdlib/json/__init__.py":                  "def loads(s):\n    pass\n",
+		"stdlib/site-packages/other.py":            "class Other:\n    pass\n",
+	})
+	projectRoot := filepath.Join(root, "project")
+	w.settings.Folders = []*WorkspaceFolder{{
+		Root:        projectRoot,
+		ModulesPath: []string{filepath.Join(root, "site-packages"), filepath.Join(root, "stdlib"), projectRoot},
+	}}
+	require.NoError(t, w.IndexProject(projectRoot, "", nil))
+
+	// Built in the background by the first library search, synchronously here
+	libraries := w.libraries.Load()
+	libraries.once.Do(func() {})
+	libraries.build(w, nil)
+
+	names := []string{}
+	for _, symbol := range libraries.symbols {
+		names = append(names, symbol.Name)
+	}
+	assert.ElementsMatch(t, []string{"QuerySet", "filter", "loads"}, names)
+
+	symbols, err := w.GetWorkspaceSymbols(context.Background(), "QuerySet")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"QuerySetView"}, symbolNames(symbols))
+
+	symbols, err = w.GetWorkspaceSymbols(context.Background(), "#lib QuerySet")
+	require.NoError(t, err)
+	require.Equal(t, []string{"QuerySet", "QuerySetView"}, symbolNames(symbols))
+	assert.True(t, symbols[0].File.External)
+
+	symbols, err = w.GetWorkspaceSymbols(context.Background(), "#lib django.db.models.QuerySet")
+	require.NoError(t, err)
+	require.Equal(t, []string{"QuerySet"}, symbolNames(symbols))
+	found, err := w.SearchSymbolByUUID(symbols[0].UUID)
+	require.NoError(t, err)
+	assert.Same(t, symbols[0], found)
+
+	// Project symbols first among equal matches
+	settings := w.Settings()
+	settings.WorkspaceSymbolScope = SymbolScopeAll
+	w.UpdateSettings(settings)
+	symbols, err = w.GetWorkspaceSymbols(context.Background(), "QSet")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"QuerySetView", "QuerySet"}, symbolNames(symbols))
+	symbols, err = w.GetWorkspaceSymbols(context.Background(), "#project QuerySet")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"QuerySetView"}, symbolNames(symbols))
+}
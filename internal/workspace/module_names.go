@@ -0,0 +1,106 @@
+package workspace
+
+import (
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// ModuleNames returns every name bound at module level: classes, functions,
+// assignments and imports, including the ones nested in module level
+// if/try/with blocks. dynamic is true when the module can bind names we
+// can't see statically (`from x import *` or a module level `__getattr__`).
+func (f *PythonFile) ModuleNames() (names map[string]bool, dynamic bool) {
+	names = map[string]bool{}
+	ast := f.GetOrCreateAst()
+	defer ast.Close()
+	dynamic = collectModuleNames(ast.Root, ast.Source, names)
+	return names, dynamic
+}
+
+// DefinesName reports whether the module binds name at module level.
+func (f *PythonFile) DefinesName(name string) bool {
+	names, dynamic := f.ModuleNames()
+	return dynamic || names[name]
+}
+
+func collectModuleNames(node *tree_sitter.Node, source []byte, names map[string]bool) bool {
+	dynamic := false
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		child := node.NamedChild(i)
+		switch child.Kind() {
+		case "class_definition", "function_definition":
+			if name := child.ChildByFieldName("name"); name != nil {
+				names[name.Utf8Text(source)] = true
+				if name.Utf8Text(source) == "__getattr__" {
+					dynamic = true
+				}
+			}
+		case "decorated_definition":
+			definition := child.ChildByFieldName("definition")
+			if definition == nil {
+				continue
+			}
+			if name := definition.ChildByFieldName("name"); name != nil {
+				names[name.Utf8Text(source)] = true
+			}
+		case "expression_statement":
+			for j := uint(0); j < child.NamedChildCount(); j++ {
+				// Chained assignments (a = b = 1) nest on the right side.
+				for assignment := child.NamedChild(j); assignment != nil && assignment.Kind() == "assignment"; assignment = assignment.ChildByFieldName("right") {
+					collectAssignmentTargets(assignment.ChildByFieldName("left"), source, names)
+				}
+			}
+		case "import_statement", "import_from_statement", "future_import_statement":
+			if child.Kind() == "import_from_statement" {
+				for j := uint(0); j < child.NamedChildCount(); j++ {
+					if child.NamedChild(j).Kind() == "wildcard_import" {
+						dynamic = true
+					}
+				}
+			}
+			cursor := child.Walk()
+			for _, nameNode := range child.ChildrenByFieldName("name", cursor) {
+				if name := importBoundName(&nameNode, child.Kind() == "import_statement", source); name != "" {
+					names[name] = true
+				}
+			}
+			cursor.Close()
+		case "if_statement", "elif_clause", "else_clause", "try_statement", "except_clause",
+			"except_group_clause", "finally_clause", "with_statement", "block":
+			if collectModuleNames(child, source, names) {
+				dynamic = true
+			}
+		}
+	}
+	return dynamic
+}
+
+func collectAssignmentTargets(node *tree_sitter.Node, source []byte, names map[string]bool) {
+	if node == nil {
+		return
+	}
+	switch node.Kind() {
+	case "identifier":
+		names[node.Utf8Text(source)] = true
+	case "pattern_list", "tuple_pattern", "list_pattern", "list_splat_pattern":
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			collectAssignmentTargets(node.NamedChild(i), source, names)
+		}
+	}
+}
+
+// importBoundName returns the name an import binds in the importing module.
+// `import a.b` binds `a`, `from a import b` binds `b` and aliases win over both.
+func importBoundName(node *tree_sitter.Node, plainImport bool, source []byte) string {
+	switch node.Kind() {
+	case "aliased_import":
+		if alias := node.ChildByFieldName("alias"); alias != nil {
+			return alias.Utf8Text(source)
+		}
+	case "dotted_name":
+		if plainImport && node.NamedChildCount() > 0 {
+			return node.NamedChild(0).Utf8Text(source)
+		}
+		return node.Utf8Text(source)
+	}
+	return ""
+}
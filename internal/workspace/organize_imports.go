@@ -0,0 +1,332 @@
+package workspace
+
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"snakelsp/internal/messages"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Longest `from x import a, b` line before it's wrapped in parentheses.
+const organizedImportsLineLength = 88
+
+type importSection int
+
+const (
+	importSectionFuture importSection = iota
+	importSectionStdlib
+	importSectionThirdParty
+	importSectionFirstParty
+	importSectionLocal // Relative imports
+)
+
+type organizedName struct {
+	name  string
+	alias string
+}
+
+func (n organizedName) String() string {
+	if n.alias != "" {
+		return n.name + " as " + n.alias
+	}
+	return n.name
+}
+
+// organizedImport is a single statement of the organized import block.
+type organizedImport struct {
+	section  importSection
+	module   string
+	from     bool
+	alias    string          // `import module as alias`
+	names    []organizedName // `from module import names`
+	verbatim string          // Statement kept as written, e.g. with a trailing comment
+}
+
+func (i organizedImport) String() string {
+	if i.verbatim != "" {
+		return i.verbatim
+	}
+	if !i.from {
+		return "import " + organizedName{name: i.module, alias: i.alias}.String()
+	}
+	names := []string{}
+	for _, name := range i.names {
+		names = append(names, name.String())
+	}
+	line := "from " + i.module + " import " + strings.Join(names, ", ")
+	if len(line) <= organizedImportsLineLength || len(names) == 1 {
+		return line
+	}
+	return "from " + i.module + " import (\n    " + strings.Join(names, ",\n    ") + ",\n)"
+}
+
+// OrganizeImports rewrites the leading import block of the file: imports are
+// grouped into stdlib, third-party, first-party and relative sections,
+// sorted, `from` imports of the same module merged and unused names dropped.
+// Edits cover only the lines that change, nil when the block is already
+// organized.
+func (f *PythonFile) OrganizeImports() []messages.TextEdit {
+	ast := f.GetOrCreateAst()
+	defer ast.Close()
+	source, encoding := ast.Source, f.positionEncoding()
+	statements := importBlock(ast.Root)
+	if len(statements) == 0 {
+		return nil
+	}
+	unused := map[messages.Position]bool{}
+	for _, u := range f.UnusedImports() {
+		unused[u.Import.BindingRange.Start] = true
+	}
+
+	imports := []organizedImport{}
+	merged := map[string]int{}
+	for _, statement := range statements {
+		for _, imp := range f.organizeStatement(statement, source, unused) {
+			index, seen := merged[imp.module]
+			if !imp.from || imp.verbatim != "" || !seen {
+				if imp.from && imp.verbatim == "" {
+					merged[imp.module] = len(imports)
+				}
+				imports = append(imports, imp)
+				continue
+			}
+			for _, name := range imp.names {
+				if !slices.Contains(imports[index].names, name) {
+					imports[index].names = append(imports[index].names, name)
+				}
+			}
+		}
+	}
+	for i := range imports {
+		slices.SortStableFunc(imports[i].names, func(a, b organizedName) int {
+			return compareImportNames(a.String(), b.String())
+		})
+	}
+	slices.SortStableFunc(imports, func(a, b organizedImport) int {
+		if a.section != b.section {
+			return cmp.Compare(a.section, b.section)
+		}
+		// Plain imports go before `from` imports of the same section.
+		if a.from != b.from {
+			if a.from {
+				return 1
+			}
+			return -1
+		}
+		return compareImportNames(a.module, b.module)
+	})
+
+	lines := []string{}
+	for i, imp := range imports {
+		if i > 0 && imp.section != imports[i-1].section {
+			lines = append(lines, "")
+		}
+		lines = append(lines, strings.Split(imp.String(), "\n")...)
+	}
+
+	first, last := statements[0], statements[len(statements)-1]
+	start := messages.Position{Line: uint32(first.StartPosition().Row), Character: 0}
+	end := nodeRange(last, source, encoding).End
+	oldText := string(source[int(first.StartByte())-int(first.StartPosition().Column) : last.EndByte()])
+	return lineEdits(start, end, strings.Split(oldText, "\n"), lines, encoding)
+}
+
+// importBlock returns the module level import statements from the first one
+// up to the first statement that isn't an import. Comments trailing an import
+// on its line are part of the block, any other comment ends it.
+func importBlock(root *tree_sitter.Node) []*tree_sitter.Node {
+	block := []*tree_sitter.Node{}
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		child := root.NamedChild(i)
+		switch child.Kind() {
+		case "import_statement", "import_from_statement", "future_import_statement":
+			block = append(block, child)
+			continue
+		case "comment":
+			if len(block) > 0 && block[len(block)-1].EndPosition().Row == child.StartPosition().Row {
+				block = append(block, child)
+				continue
+			}
+		}
+		if len(block) > 0 {
+			break
+		}
+	}
+	return block
+}
+
+// organizeStatement splits a statement into one organizedImport per plain
+// import, or a single one for `from` imports, leaving out unused names.
+func (f *PythonFile) organizeStatement(statement *tree_sitter.Node, source []byte, unused map[messages.Position]bool) []organizedImport {
+	if statement.Kind() == "comment" {
+		return nil
+	}
+	imports := []organizedImport{}
+	module := ""
+	section := importSectionFuture
+	switch statement.Kind() {
+	case "future_import_statement":
+		module = "__future__"
+	case "import_from_statement":
+		moduleNode := statement.ChildByFieldName("module_name")
+		if moduleNode == nil {
+			return nil
+		}
+		module = moduleNode.Utf8Text(source)
+		if moduleNode.Kind() == "relative_import" {
+			section = importSectionLocal
+		} else {
+			section = f.classifyImport(module)
+		}
+	}
+
+	if comment := statement.NextNamedSibling(); comment != nil && comment.Kind() == "comment" &&
+		comment.StartPosition().Row == statement.EndPosition().Row {
+		verbatim := organizedImport{
+			section:  section,
+			module:   module,
+			from:     statement.Kind() != "import_statement",
+			verbatim: statement.Utf8Text(source) + "  " + comment.Utf8Text(source),
+		}
+		if statement.Kind() == "import_statement" {
+			if name := statement.ChildByFieldName("name"); name != nil {
+				verbatim.module = importedNodeName(name, source).name
+				verbatim.section = f.classifyImport(verbatim.module)
+			}
+		}
+		return []organizedImport{verbatim}
+	}
+
+	cursor := statement.Walk()
+	defer cursor.Close()
+	names := []organizedName{}
+	for _, nameNode := range statement.ChildrenByFieldName("name", cursor) {
+		if unused[nodeRange(&nameNode, source, f.positionEncoding()).Start] {
+			continue
+		}
+		name := importedNodeName(&nameNode, source)
+		if statement.Kind() == "import_statement" {
+			imports = append(imports, organizedImport{
+				section: f.classifyImport(name.name),
+				module:  name.name,
+				alias:   name.alias,
+			})
+			continue
+		}
+		names = append(names, name)
+	}
+	for i := uint(0); i < statement.NamedChildCount(); i++ {
+		if statement.NamedChild(i).Kind() == "wildcard_import" {
+			// Never merged with other names of the module.
+			imports = append(imports, organizedImport{
+				section:  section,
+				module:   module,
+				from:     true,
+				verbatim: "from " + module + " import *",
+			})
+		}
+	}
+	if statement.Kind() != "import_statement" && len(names) > 0 {
+		imports = append(imports, organizedImport{
+			section: section,
+			module:  module,
+			from:    true,
+			names:   names,
+		})
+	}
+	return imports
+}
+
+func importedNodeName(node *tree_sitter.Node, source []byte) organizedName {
+	if node.Kind() != "aliased_import" {
+		return organizedName{name: node.Utf8Text(source)}
+	}
+	name := organizedName{}
+	if nameNode := node.ChildByFieldName("name"); nameNode != nil {
+		name.name = nameNode.Utf8Text(source)
+	}
+	if aliasNode := node.ChildByFieldName("alias"); aliasNode != nil {
+		name.alias = aliasNode.Utf8Text(source)
+	}
+	return name
+}
+
+// classifyImport picks the section of a module by the module path of the
+// file its top level package resolves to. Modules that can't be found are assumed to
+// be third-party.
+func (f *PythonFile) classifyImport(module string) importSection {
+	top, _, _ := strings.Cut(module, ".")
+	if top == "__future__" {
+		return importSectionFuture
+	}
+	if builtinModules[top] || builtinModules[module] {
+		return importSectionStdlib
+	}
+	_, searchPath, err := locateModule(f.modulesPath(), top)
+	if err != nil {
+		return importSectionThirdParty
+	}
+	if f.workspace.isSourceRoot(searchPath) {
+		return importSectionFirstParty
+	}
+	if base := filepath.Base(searchPath); base == "site-packages" || base == "dist-packages" {
+		return importSectionThirdParty
+	}
+	return importSectionStdlib
+}
+
+// compareImportNames sorts case-insensitively, keeping a stable order for
+// names differing in case only.
+func compareImportNames(a, b string) int {
+	if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// lineEdits turns replacing oldLines, spanning start to end, with newLines
+// into a single edit of the lines that differ.
+func lineEdits(start, end messages.Position, oldLines, newLines []string, encoding messages.PositionEncodingKind) []messages.TextEdit {
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	if prefix == len(oldLines) && prefix == len(newLines) {
+		return nil
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	replaced := newLines[prefix : len(newLines)-suffix]
+	edit := messages.TextEdit{
+		Range: messages.Range{
+			Start: messages.Position{Line: start.Line + uint32(prefix), Character: 0},
+			End:   messages.Position{Line: start.Line + uint32(len(oldLines)-suffix), Character: 0},
+		},
+	}
+	if suffix > 0 {
+		for _, line := range replaced {
+			edit.NewText += line + "\n"
+		}
+		return []messages.TextEdit{edit}
+	}
+	// The last line of the block changes, end on the block end rather than
+	// the start of the following line to keep what follows untouched.
+	edit.Range.End = end
+	edit.NewText = strings.Join(replaced, "\n")
+	if prefix > 0 && (len(replaced) == 0 || prefix == len(oldLines)) {
+		// Lines are only dropped or appended, work from the end of the last
+		// line kept so no line break is left over or missing.
+		edit.Range.Start = messages.Position{Line: start.Line + uint32(prefix-1), Character: uint32(encodedLength(oldLines[prefix-1], encoding))}
+		if len(replaced) > 0 {
+			edit.NewText = "\n" + edit.NewText
+		}
+	}
+	return []messages.TextEdit{edit}
+}
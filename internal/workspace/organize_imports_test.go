@@ -0,0 +1,122 @@
+package workspace
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"snakelsp/internal/messages"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// applyTextEdits applies non-overlapping edits, ordered by position, to text.
+func applyTextEdits(text string, edits []messages.TextEdit) string {
+	offset := func(position messages.Position) int {
+		lineStart := 0
+		for line := uint32(0); line < position.Line; line++ {
+			lineStart += strings.Index(text[lineStart:], "\n") + 1
+		}
+		return lineStart + int(position.Character)
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		start, end := offset(edits[i].Range.Start), offset(edits[i].Range.End)
+		text = text[:start] + edits[i].NewText + text[end:]
+	}
+	return text
+}
+
+func setupOrganizeImports(t *testing.T) (*Workspace, string) {
+	w, root := setupModulesPath(t, map[string]string{
+		"lib/python3.12/os/__init__.py":                "",
+		"lib/python3.12/json/__init__.py":              "def dumps(obj): pass\ndef loads(s): pass\n",
+		"lib/python3.12/site-packages/requests.py":     "def get(url): pass\n",
+		"lib/python3.12/site-packages/django/db.py":    "class Model: pass\n",
+		"lib/python3.12/site-packages/django/forms.py": "",
+		"app/models.py": "class User: pass\nclass Group: pass\n",
+	})
+	w.settings.Folders[0].ModulesPath = []string{
+		filepath.Join(root, "lib/python3.12/site-packages"),
+		filepath.Join(root, "lib/python3.12"),
+		root,
+	}
+	return w, root
+}
+
+func TestOrganizeImports(t *testing.T) {
+	w, root := setupOrganizeImports(t)
+	text := `"""Module docstring."""
+from app.models import User
+import requests
+from json import loads
+import sys, os
+from django.db import Model
+from app.models import Group
+from json import dumps
+from . import sibling
+from __future__ import annotations
+
+print(User, Group, requests, loads, dumps, sys, Model, sibling)
+`
+	mockFile := &PythonFile{workspace: w, Url: "file://" + filepath.Join(root, "main.py"), Text: text}
+
+	edits := mockFile.OrganizeImports()
+	assert.Len(t, edits, 1)
+	assert.Equal(t, `"""Module docstring."""
+from __future__ import annotations
+
+import sys
+from json import dumps, loads
+
+import requests
+from django.db import Model
+
+from app.models import Group, User
+
+from . import sibling
+
+print(User, Group, requests, loads, dumps, sys, Model, sibling)
+`, applyTextEdits(text, edits))
+
+	organized := &PythonFile{workspace: w, Url: mockFile.Url, Text: applyTextEdits(text, edits)}
+	assert.Empty(t, organized.OrganizeImports())
+}
+
+func TestOrganizeImportsMinimalEdits(t *testing.T) {
+	w, root := setupOrganizeImports(t)
+	text := `import os
+import sys  # platform checks
+from json import loads
+from json import dumps
+
+import requests
+
+print(os, sys, loads, dumps, requests)
+`
+	mockFile := &PythonFile{workspace: w, Url: "file://" + filepath.Join(root, "main.py"), Text: text}
+
+	edits := mockFile.OrganizeImports()
+	assert.Equal(t, []messages.TextEdit{{
+		Range: messages.Range{
+			Start: messages.Position{Line: 2, Character: 0},
+			End:   messages.Position{Line: 4, Character: 0},
+		},
+		NewText: "from json import dumps, loads\n",
+	}}, edits)
+}
+
+func TestOrganizeImportsWrapsLongLines(t *testing.T) {
+	w, root := setupOrganizeImports(t)
+	names := []string{"first_function_name", "second_function_name", "third_function_name", "fourth_function_name"}
+	text := "from app.models import " + strings.Join(names, ", ") + "\n\nprint(" + strings.Join(names, ", ") + ")\n"
+	mockFile := &PythonFile{workspace: w, Url: "file://" + filepath.Join(root, "main.py"), Text: text}
+
+	result := applyTextEdits(text, mockFile.OrganizeImports())
+	assert.True(t, strings.HasPrefix(result, `from app.models import (
+    first_function_name,
+    fourth_function_name,
+    second_function_name,
+    third_function_name,
+)
+`), result)
+}
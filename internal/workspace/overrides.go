@@ -0,0 +1,195 @@
+package workspace
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"snakelsp/internal/messages"
+)
+
+// Constructors and hooks are expected to change their signature in subclasses.
+var overrideCheckExemptMethods = []string{"__init__", "__new__", "__init_subclass__", "__post_init__", "__class_getitem__"}
+
+type methodKind string
+
+const (
+	instanceMethod methodKind = "instance method"
+	classMethod    methodKind = "classmethod"
+	staticMethod   methodKind = "staticmethod"
+)
+
+func methodKindOf(s *Symbol) methodKind {
+	switch {
+	case s.HasDecorator("staticmethod"):
+		return staticMethod
+	case s.HasDecorator("classmethod"):
+		return classMethod
+	}
+	return instanceMethod
+}
+
+// callableParams returns parameters as seen by a caller, without the bound
+// self/cls parameter.
+func callableParams(s *Symbol) []Parameter {
+	if methodKindOf(s) == staticMethod || len(s.Params) == 0 {
+		return s.Params
+	}
+	if first := s.Params[0]; first.Kind == ParameterPositional || first.Kind == ParameterPositionalOnly {
+		return s.Params[1:]
+	}
+	return s.Params
+}
+
+func qualifiedMethodName(s *Symbol) string {
+	if s.Parent == nil {
+		return s.Name
+	}
+	return s.Parent.Name + "." + s.Name
+}
+
+// isOverrideChecked filters out methods whose signature isn't a contract:
+// constructors, name-mangled privates, overload stubs and properties.
+func isOverrideChecked(s *Symbol) bool {
+	if slices.Contains(overrideCheckExemptMethods, s.Name) {
+		return false
+	}
+	if strings.HasPrefix(s.Name, "__") && !strings.HasSuffix(s.Name, "__") {
+		return false
+	}
+	if s.HasDecorator("overload") || s.HasDecorator("property") {
+		return false
+	}
+	for _, decorator := range s.Decorators {
+		if strings.HasSuffix(decorator, ".setter") || strings.HasSuffix(decorator, ".getter") || strings.HasSuffix(decorator, ".deleter") {
+			return false
+		}
+	}
+	return true
+}
+
+func overrideDiagnostics(f *PythonFile) []messages.Diagnostic {
+	diagnostics := []messages.Diagnostic{}
+	symbols, err := f.FileSymbols("")
+	if err != nil {
+		return diagnostics
+	}
+	for _, symbol := range symbols {
+		for _, method := range symbol.Children {
+			if method.Kind != messages.SymbolKindMethod || !isOverrideChecked(method) {
+				continue
+			}
+			for _, base := range method.SuperObjects {
+				if base.Kind != messages.SymbolKindMethod || !isOverrideChecked(base) {
+					continue
+				}
+				diagnostics = append(diagnostics, compareOverride(method, base)...)
+			}
+		}
+	}
+	return diagnostics
+}
+
+// compareOverride reports the ways method can't be used where base is
+// expected (Liskov substitution).
+func compareOverride(method, base *Symbol) []messages.Diagnostic {
+	diagnostics := []messages.Diagnostic{}
+	baseName := qualifiedMethodName(base)
+	report := func(code string, rng messages.Range, format string, args ...any) {
+		diagnostics = append(diagnostics, messages.Diagnostic{
+			Range:    rng,
+			Severity: messages.DiagnosticSeverityWarning,
+			Code:     code,
+			Source:   diagnosticsSource,
+			Message:  fmt.Sprintf(format, args...),
+			RelatedInformation: []messages.DiagnosticRelatedInformation{
+				{
+					Location: messages.Location{URI: base.File.Url, Range: base.NameRange},
+					Message:  fmt.Sprintf("Overridden method %s", baseName),
+				},
+			},
+		})
+	}
+
+	methodKind, baseKind := methodKindOf(method), methodKindOf(base)
+	if methodKind != baseKind && (methodKind == staticMethod || baseKind == staticMethod) {
+		// Parameter lists aren't comparable once self is gone on one side.
+		report(DiagnosticCodeOverrideMethodKind, method.NameRange, "%q is a %s but overrides %s %s", method.Name, methodKind, baseKind, baseName)
+		return diagnostics
+	}
+	if method.Async != base.Async {
+		report(DiagnosticCodeOverrideAsyncMismatch, method.NameRange, "%q is %s but overrides %s %s", method.Name, asyncLabel(method), asyncLabel(base), baseName)
+	}
+
+	baseParams, methodParams := callableParams(base), callableParams(method)
+	hasKind := func(params []Parameter, kind ParameterKind) bool {
+		return slices.ContainsFunc(params, func(p Parameter) bool { return p.Kind == kind })
+	}
+	acceptsKeyword := func(params []Parameter, name string) bool {
+		return slices.ContainsFunc(params, func(p Parameter) bool {
+			return p.Name == name && (p.Kind == ParameterPositional || p.Kind == ParameterKeywordOnly)
+		})
+	}
+	positional := func(params []Parameter) []Parameter {
+		return slices.DeleteFunc(slices.Clone(params), func(p Parameter) bool {
+			return p.Kind != ParameterPositional && p.Kind != ParameterPositionalOnly
+		})
+	}
+	methodArgs, methodKwargs := hasKind(methodParams, ParameterVarPositional), hasKind(methodParams, ParameterVarKeyword)
+	basePositional, methodPositional := positional(baseParams), positional(methodParams)
+	reported := map[string]bool{}
+
+	for i, param := range basePositional {
+		if i >= len(methodPositional) {
+			if !methodArgs {
+				reported[param.Name] = true
+				report(DiagnosticCodeOverrideMissingParameter, method.NameRange, "Positional parameter %q of %s is missing", param.Name, baseName)
+			}
+			continue
+		}
+		override := methodPositional[i]
+		if param.Kind == ParameterPositional && override.Name != param.Name && !strings.HasPrefix(param.Name, "_") && !acceptsKeyword(methodParams, param.Name) {
+			reported[param.Name] = true
+			reported[override.Name] = true
+			report(DiagnosticCodeOverrideRenamedParameter, override.Range, "Positional parameter %q is named %q in %s", override.Name, param.Name, baseName)
+		}
+	}
+	for _, param := range baseParams {
+		if param.Kind != ParameterPositional && param.Kind != ParameterKeywordOnly || reported[param.Name] {
+			continue
+		}
+		if !methodKwargs && !acceptsKeyword(methodParams, param.Name) {
+			reported[param.Name] = true
+			report(DiagnosticCodeOverrideMissingParameter, method.NameRange, "Parameter %q of %s is missing", param.Name, baseName)
+		}
+	}
+	if hasKind(baseParams, ParameterVarPositional) && !methodArgs {
+		report(DiagnosticCodeOverrideDroppedVariadic, method.NameRange, "*args accepted by %s is dropped", baseName)
+	}
+	if hasKind(baseParams, ParameterVarKeyword) && !methodKwargs {
+		report(DiagnosticCodeOverrideDroppedVariadic, method.NameRange, "**kwargs accepted by %s is dropped", baseName)
+	}
+
+	baseNames := map[string]bool{}
+	for _, param := range baseParams {
+		baseNames[param.Name] = true
+	}
+	for i, param := range methodPositional {
+		if param.IsRequired() && !baseNames[param.Name] && !reported[param.Name] && i >= len(basePositional) {
+			report(DiagnosticCodeOverrideRequiredParameter, param.Range, "Required parameter %q is not accepted by %s", param.Name, baseName)
+		}
+	}
+	for _, param := range methodParams {
+		if param.Kind == ParameterKeywordOnly && param.IsRequired() && !baseNames[param.Name] {
+			report(DiagnosticCodeOverrideRequiredParameter, param.Range, "Required parameter %q is not accepted by %s", param.Name, baseName)
+		}
+	}
+	return diagnostics
+}
+
+func asyncLabel(s *Symbol) string {
+	if s.Async {
+		return "async"
+	}
+	return "sync"
+}
@@ -0,0 +1,128 @@
+package workspace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseParameters(t *testing.T) {
+	mockFile := &PythonFile{
+		workspace: newTestWorkspace(),
+		Text: `
+def func(a, b: int, /, c, d=1, *args: str, e, f: int = 2, **kwargs):
+    pass
+`,
+		Url: "params.py",
+	}
+	symbols, err := mockFile.parseSymbols()
+	require.NoError(t, err)
+	require.Len(t, symbols, 1)
+
+	params := symbols[0].Params
+	require.Len(t, params, 8)
+	expected := []struct {
+		name     string
+		kind     ParameterKind
+		required bool
+	}{
+		{"a", ParameterPositionalOnly, true},
+		{"b", ParameterPositionalOnly, true},
+		{"c", ParameterPositional, true},
+		{"d", ParameterPositional, false},
+		{"args", ParameterVarPositional, false},
+		{"e", ParameterKeywordOnly, true},
+		{"f", ParameterKeywordOnly, false},
+		{"kwargs", ParameterVarKeyword, false},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.name, params[i].Name)
+		assert.Equal(t, e.kind, params[i].Kind, e.name)
+		assert.Equal(t, e.required, params[i].IsRequired(), e.name)
+	}
+}
+
+func TestOverrideDiagnostics(t *testing.T) {
+	mockFile := &PythonFile{
+		workspace: newTestWorkspace(),
+		Text: `
+class Base:
+    def same(self, a, b=1):
+        pass
+
+    def missing(self, a, b):
+        pass
+
+    def renamed(self, a):
+        pass
+
+    def kwargs(self, a, **kwargs):
+        pass
+
+    def instance(self, a):
+        pass
+
+    async def fetch(self):
+        pass
+
+    def extra(self, a):
+        pass
+
+    def __init__(self, a):
+        pass
+
+
+class Child(Base):
+    def same(self, a, b=2, c=None):
+        pass
+
+    def missing(self, a):
+        pass
+
+    def renamed(self, b):
+        pass
+
+    def kwargs(self, a):
+        pass
+
+    @staticmethod
+    def instance(a):
+        pass
+
+    def fetch(self):
+        pass
+
+    def extra(self, a, b):
+        pass
+
+    def __init__(self):
+        pass
+`,
+		Url: "overrides.py",
+	}
+	_, err := mockFile.parseSymbols()
+	require.NoError(t, err)
+
+	codes := map[string]string{}
+	for _, diagnostic := range overrideDiagnostics(mockFile) {
+		for _, line := range []struct {
+			line uint32
+			name string
+		}{{28, "same"}, {31, "missing"}, {34, "renamed"}, {37, "kwargs"}, {41, "instance"}, {44, "fetch"}, {47, "extra"}, {50, "__init__"}} {
+			if diagnostic.Range.Start.Line == line.line {
+				codes[line.name] = diagnostic.Code
+			}
+		}
+		assert.NotEmpty(t, diagnostic.RelatedInformation)
+	}
+
+	assert.Equal(t, map[string]string{
+		"missing":  DiagnosticCodeOverrideMissingParameter,
+		"renamed":  DiagnosticCodeOverrideRenamedParameter,
+		"kwargs":   DiagnosticCodeOverrideDroppedVariadic,
+		"instance": DiagnosticCodeOverrideMethodKind,
+		"fetch":    DiagnosticCodeOverrideAsyncMismatch,
+		"extra":    DiagnosticCodeOverrideRequiredParameter,
+	}, codes)
+}
@@ -0,0 +1,154 @@
+package workspace
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"snakelsp/internal/messages"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// SetPositionEncoding sets the encoding negotiated with the client, before
+// the workspace is used.
+func (w *Workspace) SetPositionEncoding(encoding messages.PositionEncodingKind) {
+	w.positionEncoding = encoding
+}
+
+// encodedLength is the length of text in the units of the encoding.
+func encodedLength(text string, encoding messages.PositionEncodingKind) int {
+	switch encoding {
+	case messages.PositionEncodingKindUTF8:
+		return len(text)
+	case messages.PositionEncodingKindUTF32:
+		return utf8.RuneCountInString(text)
+	}
+	length := 0
+	for _, r := range text {
+		if r >= 0x10000 {
+			length += 2 // Surrogate pair
+		} else {
+			length++
+		}
+	}
+	return length
+}
+
+// encodedPrefix returns the number of bytes of line covered by character
+// units of the encoding, the whole line when it's shorter. A character in
+// the middle of a surrogate pair covers the whole rune.
+func encodedPrefix(line string, character int, encoding messages.PositionEncodingKind) int {
+	if encoding == messages.PositionEncodingKindUTF8 {
+		return min(character, len(line))
+	}
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		if encoding != messages.PositionEncodingKindUTF32 && r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+	}
+	return len(line)
+}
+
+// nodeRange converts tree-sitter node boundaries to an LSP range, source is
+// the text the node was parsed from.
+func nodeRange(node *tree_sitter.Node, source []byte, encoding messages.PositionEncodingKind) messages.Range {
+	return messages.Range{
+		Start: pointPosition(node.StartPosition(), int(node.StartByte()), source, encoding),
+		End:   pointPosition(node.EndPosition(), int(node.EndByte()), source, encoding),
+	}
+}
+
+// pointPosition converts the tree-sitter point at offset in source.
+func pointPosition(point tree_sitter.Point, offset int, source []byte, encoding messages.PositionEncodingKind) messages.Position {
+	lineStart := offset - int(point.Column)
+	if lineStart < 0 || offset > len(source) {
+		return messages.Position{Line: messages.UInteger(point.Row), Character: messages.UInteger(point.Column)}
+	}
+	return messages.Position{
+		Line:      messages.UInteger(point.Row),
+		Character: messages.UInteger(encodedLength(string(source[lineStart:offset]), encoding)),
+	}
+}
+
+// LineIndex converts between byte offsets of a text and positions in the
+// negotiated encoding.
+type LineIndex struct {
+	text       string
+	lineStarts []int
+	encoding   messages.PositionEncodingKind
+}
+
+func NewLineIndex(text string, encoding messages.PositionEncodingKind) *LineIndex {
+	lineStarts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &LineIndex{text: text, lineStarts: lineStarts, encoding: encoding}
+}
+
+func (l *LineIndex) line(line int) string {
+	end := len(l.text)
+	if line+1 < len(l.lineStarts) {
+		end = l.lineStarts[line+1] - 1
+	}
+	return strings.TrimSuffix(l.text[l.lineStarts[line]:end], "\r")
+}
+
+// Offset returns the byte offset of the position, false when the line
+// doesn't exist. Characters past the end of the line mean its end.
+func (l *LineIndex) Offset(position messages.Position) (int, bool) {
+	line := int(position.Line)
+	if line >= len(l.lineStarts) {
+		return 0, false
+	}
+	return l.lineStarts[line] + encodedPrefix(l.line(line), int(position.Character), l.encoding), true
+}
+
+// Point converts the position to a tree-sitter point, with a byte column.
+func (l *LineIndex) Point(position messages.Position) (tree_sitter.Point, bool) {
+	offset, ok := l.Offset(position)
+	if !ok {
+		return tree_sitter.Point{}, false
+	}
+	return l.offsetPoint(offset), true
+}
+
+func (l *LineIndex) offsetPoint(offset int) tree_sitter.Point {
+	line := sort.Search(len(l.lineStarts), func(i int) bool { return l.lineStarts[i] > offset }) - 1
+	return tree_sitter.Point{Row: uint(line), Column: uint(offset - l.lineStarts[line])}
+}
+
+// Position converts a byte offset of the text.
+func (l *LineIndex) Position(offset int) messages.Position {
+	offset = max(0, min(offset, len(l.text)))
+	point := l.offsetPoint(offset)
+	return messages.Position{
+		Line:      messages.UInteger(point.Row),
+		Character: messages.UInteger(encodedLength(l.text[l.lineStarts[point.Row]:offset], l.encoding)),
+	}
+}
+
+// LineIndex returns the index of the current text of the file.
+func (f *PythonFile) LineIndex() *LineIndex {
+	f.astMutex.Lock()
+	defer f.astMutex.Unlock()
+	if f.lineIndex == nil || f.lineIndex.text != f.Text {
+		f.lineIndex = NewLineIndex(f.Text, f.positionEncoding())
+	}
+	return f.lineIndex
+}
+
+// positionEncoding is how the client of the workspace of the file counts
+// characters in positions.
+func (f *PythonFile) positionEncoding() messages.PositionEncodingKind {
+	return f.workspace.positionEncoding
+}
@@ -0,0 +1,74 @@
+package workspace
+
+import (
+	"testing"
+	"time"
+
+	"snakelsp/internal/messages"
+	"snakelsp/pkg/debounce"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineIndex(t *testing.T) {
+	t.Parallel()
+	// "привет" is 12 bytes and 6 UTF-16 units, "🐍" 4 bytes and 2 units.
+	text := "x = 1\ns = \"привет 🐍\" + y\n"
+	yOffset := len("x = 1\ns = \"привет 🐍\" + ")
+	for _, tc := range []struct {
+		encoding  messages.PositionEncodingKind
+		character messages.UInteger
+	}{
+		{messages.PositionEncodingKindUTF8, 26},
+		{messages.PositionEncodingKindUTF16, 18},
+		{messages.PositionEncodingKindUTF32, 17},
+	} {
+		t.Run(string(tc.encoding), func(t *testing.T) {
+			t.Parallel()
+			lines := NewLineIndex(text, tc.encoding)
+			position := messages.Position{Line: 1, Character: tc.character}
+			assert.Equal(t, position, lines.Position(yOffset))
+			offset, ok := lines.Offset(position)
+			require.True(t, ok)
+			assert.Equal(t, yOffset, offset)
+			point, ok := lines.Point(position)
+			require.True(t, ok)
+			assert.Equal(t, uint(26), point.Column)
+		})
+	}
+
+	lines := NewLineIndex(text, messages.PositionEncodingKindUTF16)
+	// Inside the surrogate pair of the emoji, rounded to its end
+	offset, _ := lines.Offset(messages.Position{Line: 1, Character: 13})
+	assert.Equal(t, len("x = 1\ns = \"привет 🐍"), offset)
+	// Past the end of the line
+	offset, _ = lines.Offset(messages.Position{Line: 0, Character: 100})
+	assert.Equal(t, 5, offset)
+	_, ok := lines.Offset(messages.Position{Line: 5, Character: 0})
+	assert.False(t, ok)
+}
+
+func TestNonAsciiPositions(t *testing.T) {
+	t.Parallel()
+	file := &PythonFile{
+		workspace: newTestWorkspace(),
+		Url:       "file:///tmp/non_ascii.py",
+		Text:      "# Комментарий\nclass Привет: pass\n\ns = \"🐍\"\n",
+		debouncer: debounce.NewDebounce(time.Hour),
+	}
+	symbols, err := file.parseSymbols()
+	require.NoError(t, err)
+	require.Len(t, symbols, 1)
+	assert.Equal(t, messages.Range{
+		Start: messages.Position{Line: 1, Character: 6},
+		End:   messages.Position{Line: 1, Character: 12},
+	}, symbols[0].NameRange)
+
+	file.ApplyChange(1, []messages.TextDocumentContentChangeEvent{textChange(1, 12, 1, 12, "Мир")})
+	assert.Equal(t, "# Комментарий\nclass ПриветМир: pass\n\ns = \"🐍\"\n", file.Text)
+	symbols, err = file.parseSymbols()
+	require.NoError(t, err)
+	assert.Equal(t, "ПриветМир", symbols[0].Name)
+	assert.Equal(t, messages.UInteger(15), symbols[0].NameRange.End.Character)
+}
@@ -0,0 +1,91 @@
+package workspace
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Files at the root of a workspace folder holding its project config.
+const (
+	projectConfigFile = ".snakelsp.toml"
+	pyprojectFile     = "pyproject.toml"
+)
+
+// ProjectConfig is the configuration a repository keeps in the `[tool.snakelsp]`
+// table of its pyproject.toml or in a .snakelsp.toml file, which wins over
+// pyproject.toml for the keys both set. Paths are relative to the folder
+// root. Settings sent by the editor take precedence over it.
+type ProjectConfig struct {
+	// Folders holding the top-level packages of the project, e.g. `src`.
+	SourceRoots []string `toml:"source_roots"`
+	// Glob patterns of the Python files indexed, all of them when empty.
+	Include []string `toml:"include"`
+	// Glob patterns of the files and folders left out of the index.
+	Exclude []string `toml:"exclude"`
+	// Module paths searched besides the virtualenv, e.g. vendored libraries.
+	ExtraPaths     []string `toml:"extra_paths"`
+	VirtualEnvPath string   `toml:"virtualenv_path"`
+}
+
+// isProjectConfigFile reports whether the file holds the project config of
+// one of the workspace folders.
+func (w *Workspace) isProjectConfigFile(path string) bool {
+	name := filepath.Base(path)
+	return (name == projectConfigFile || name == pyprojectFile) && w.isFolderRoot(filepath.Dir(path))
+}
+
+// loadProjectConfig reads the project config of the folder, empty when it
+// has none. Files which can't be parsed are skipped.
+func loadProjectConfig(root string) ProjectConfig {
+	config := ProjectConfig{}
+	var pyproject struct {
+		Tool struct {
+			Snakelsp ProjectConfig `toml:"snakelsp"`
+		} `toml:"tool"`
+	}
+	if decodeProjectConfig(filepath.Join(root, pyprojectFile), &pyproject) {
+		config = pyproject.Tool.Snakelsp
+	}
+	// Keys missing from .snakelsp.toml keep the pyproject.toml values.
+	decodeProjectConfig(filepath.Join(root, projectConfigFile), &config)
+	return config
+}
+
+func decodeProjectConfig(path string, value any) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if _, err := toml.Decode(string(content), value); err != nil {
+		slog.Warn("Unable to parse project config", slog.String("path", path), slog.Any("error", err))
+		return false
+	}
+	return true
+}
+
+// editorOrProject picks the paths set in the editor settings, the project
+// config ones when the editor didn't set any.
+func editorOrProject(editor, project []string) []string {
+	if editor != nil {
+		return editor
+	}
+	return project
+}
+
+// resolvePaths makes paths relative to the root absolute.
+func resolvePaths(root string, paths []string) []string {
+	resolved := []string{}
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		if path = filepath.Clean(path); !slices.Contains(resolved, path) {
+			resolved = append(resolved, path)
+		}
+	}
+	return resolved
+}
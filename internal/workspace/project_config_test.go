@@ -0,0 +1,90 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"snakelsp/internal/messages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadProjectConfig(t *testing.T) {
+	_, root := setupModulesPath(t, map[string]string{
+		"pyproject.toml": `[project]
+name = "app"
+
+[tool.snakelsp]
+source_roots = ["src"]
+exclude = ["migrations"]
+virtualenv_path = ".env"
+`,
+		".snakelsp.toml": `exclude = ["generated", "**/*_pb2.py"]
+extra_paths = ["vendor"]
+`,
+	})
+	config := loadProjectConfig(root)
+	assert.Equal(t, []string{"src"}, config.SourceRoots)
+	assert.Equal(t, []string{"generated", "**/*_pb2.py"}, config.Exclude)
+	assert.Equal(t, []string{"vendor"}, config.ExtraPaths)
+	assert.Equal(t, ".env", config.VirtualEnvPath)
+
+	// Editor settings win over the project config
+	settings := &ClientSettingsType{Settings: NewSettings(nil)}
+	folder := resolveWorkspaceFolder(settings, "app", root)
+	assert.Equal(t, filepath.Join(root, ".env"), folder.VirtualEnvPath)
+	assert.Equal(t, []string{filepath.Join(root, "src")}, folder.SourceRoots)
+	assert.Equal(t, []string{filepath.Join(root, "vendor")}, folder.ExtraPaths)
+	assert.True(t, folder.ignores(filepath.Join(root, "generated/app.py"), false))
+
+	settings.VirtualEnvPath = "/venv"
+	settings.Exclude = []string{}
+	folder = resolveWorkspaceFolder(settings, "app", root)
+	assert.Equal(t, "/venv", folder.VirtualEnvPath)
+	assert.False(t, folder.ignores(filepath.Join(root, "generated/app.py"), false))
+
+	assert.Equal(t, ProjectConfig{}, loadProjectConfig(filepath.Join(root, "missing")))
+}
+
+func TestProjectConfigSourceRoots(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"src/app/__init__.py": "",
+		"src/app/models.py":   "class Model:\n    pass\n",
+		"src/app/views.py":    "from app.models import Model\n\nprint(Model)\n",
+		".snakelsp.toml":      "source_roots = [\"src\"]\n",
+	})
+	w = newTestWorkspace(newWorkspaceFolder(&ClientSettingsType{Settings: NewSettings(nil)}, "app", root))
+	require.NoError(t, w.IndexProject(root, "", nil))
+
+	views, err := w.GetPythonFile("file://" + filepath.Join(root, "src/app/views.py"))
+	require.NoError(t, err)
+	require.Len(t, views.Imports, 1)
+	assert.NoError(t, views.Imports[0].ResolveError)
+	module, ok := w.moduleNameForPath(filepath.Join(root, "src/app/models.py"))
+	assert.True(t, ok)
+	assert.Equal(t, "app.models", module)
+}
+
+func TestProjectConfigChange(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"app/models.py":          "class Model:\n    pass\n",
+		"app/migrations/0001.py": "from app.models import Model\n",
+	})
+	require.NoError(t, w.IndexProject(root, "", nil))
+	migration := "file://" + filepath.Join(root, "app/migrations/0001.py")
+	_, err := w.GetPythonFile(migration)
+	require.NoError(t, err)
+
+	config := filepath.Join(root, "pyproject.toml")
+	require.NoError(t, os.WriteFile(config, []byte("[tool.snakelsp]\nexclude = [\"migrations\"]\n"), 0o644))
+	w.ApplyFileEvents([]messages.FileEvent{{URI: "file://" + config, Type: messages.FileChangeTypeCreated}})
+	_, err = w.GetPythonFile(migration)
+	assert.Error(t, err)
+
+	require.NoError(t, os.Remove(config))
+	w.ApplyFileEvents([]messages.FileEvent{{URI: "file://" + config, Type: messages.FileChangeTypeDeleted}})
+	_, err = w.GetPythonFile(migration)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,426 @@
+package workspace
+
+import (
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"snakelsp/internal/messages"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// moduleRename is a file or folder rename translated to module names.
+type moduleRename struct {
+	oldPath, newPath     string
+	oldModule, newModule string
+}
+
+// moduleNameForPath returns the dotted module name of a file or a package
+// folder, relative to the innermost source root of its workspace folder.
+func (w *Workspace) moduleNameForPath(path string) (string, bool) {
+	folder := w.FolderOf(path)
+	if folder == nil {
+		return "", false
+	}
+	root := folder.Root
+	for _, sourceRoot := range folder.sourceRoots() {
+		if strings.HasPrefix(path, sourceRoot+"/") && len(sourceRoot) > len(root) {
+			root = sourceRoot
+		}
+	}
+	relative, err := filepath.Rel(root, path)
+	if err != nil || relative == "." {
+		return "", false
+	}
+	if ext := filepath.Ext(relative); ext == ".py" || ext == ".pyi" {
+		relative = strings.TrimSuffix(relative, ext)
+	}
+	if filepath.Base(relative) == "__init__" {
+		relative = filepath.Dir(relative)
+		if relative == "." {
+			return "", false
+		}
+	}
+	return strings.ReplaceAll(relative, string(filepath.Separator), "."), true
+}
+
+// packageForPath is the package relative imports of the file are resolved
+// against, empty for files in the workspace root.
+func (w *Workspace) packageForPath(path string) string {
+	module, ok := w.moduleNameForPath(filepath.Dir(path))
+	if !ok {
+		return ""
+	}
+	return module
+}
+
+func (w *Workspace) moduleRenames(renames []messages.FileRename) []moduleRename {
+	result := []moduleRename{}
+	for _, rename := range renames {
+		oldPath := strings.TrimPrefix(rename.OldURI, "file://")
+		newPath := strings.TrimPrefix(rename.NewURI, "file://")
+		oldModule, oldOk := w.moduleNameForPath(oldPath)
+		newModule, newOk := w.moduleNameForPath(newPath)
+		if !oldOk || !newOk || oldModule == newModule {
+			continue
+		}
+		result = append(result, moduleRename{oldPath, newPath, oldModule, newModule})
+	}
+	return result
+}
+
+// renameModule maps a module name through the renames, including modules
+// nested in a renamed package.
+func renameModule(module string, renames []moduleRename) (string, bool) {
+	for _, rename := range renames {
+		if module == rename.oldModule {
+			return rename.newModule, true
+		}
+		if rest, found := strings.CutPrefix(module, rename.oldModule+"."); found {
+			return rename.newModule + "." + rest, true
+		}
+	}
+	return module, false
+}
+
+// renamePath maps a file path through the renames.
+func renamePath(path string, renames []moduleRename) string {
+	for _, rename := range renames {
+		if path == rename.oldPath {
+			return rename.newPath
+		}
+		if rest, found := strings.CutPrefix(path, rename.oldPath+"/"); found {
+			return rename.newPath + "/" + rest
+		}
+	}
+	return path
+}
+
+// absoluteModule resolves a relative module, e.g. `..models`, against the
+// package of the importing file.
+func absoluteModule(pkg, relative string) string {
+	module := strings.TrimLeft(relative, ".")
+	parts := []string{}
+	if pkg != "" {
+		parts = strings.Split(pkg, ".")
+	}
+	levels := len(relative) - len(module) - 1
+	if levels > len(parts) {
+		levels = len(parts)
+	}
+	parts = parts[:len(parts)-levels]
+	if module != "" {
+		parts = append(parts, module)
+	}
+	return strings.Join(parts, ".")
+}
+
+func joinModule(module, name string) string {
+	if module == "" {
+		return name
+	}
+	return module + "." + name
+}
+
+// relativeModule writes module relative to the package of the importing
+// file, the inverse of absoluteModule.
+func relativeModule(pkg, module string) string {
+	pkgParts, moduleParts := []string{}, []string{}
+	if pkg != "" {
+		pkgParts = strings.Split(pkg, ".")
+	}
+	if module != "" {
+		moduleParts = strings.Split(module, ".")
+	}
+	common := 0
+	for common < len(pkgParts) && common < len(moduleParts) && pkgParts[common] == moduleParts[common] {
+		common++
+	}
+	return strings.Repeat(".", len(pkgParts)-common+1) + strings.Join(moduleParts[common:], ".")
+}
+
+// RenameFilesEdit rewrites imports of the project pointing to renamed
+// modules and packages: `import a.b` (with its usages), `from a.b import c`
+// and relative imports, including the ones of the renamed files themselves.
+// Edits refer to the files before the rename, as `workspace/willRenameFiles`
+// requires.
+func (w *Workspace) RenameFilesEdit(renames []messages.FileRename) messages.WorkspaceEdit {
+	edit := messages.WorkspaceEdit{Changes: map[messages.DocumentUri][]messages.TextEdit{}}
+	modules := w.moduleRenames(renames)
+	if len(modules) == 0 {
+		return edit
+	}
+	w.Files.Range(func(key, value any) bool {
+		file := value.(*PythonFile)
+		if file.External {
+			return true
+		}
+		if edits := file.renameImportEdits(modules); len(edits) > 0 {
+			edit.Changes[file.Url] = edits
+		}
+		return true
+	})
+	return edit
+}
+
+func (f *PythonFile) renameImportEdits(renames []moduleRename) []messages.TextEdit {
+	path := strings.TrimPrefix(f.Url, "file://")
+	oldPkg, newPkg := f.workspace.packageForPath(path), f.workspace.packageForPath(renamePath(path, renames))
+	ast := f.GetOrCreateAst()
+	defer ast.Close()
+	source, encoding := ast.Source, f.positionEncoding()
+	edits := []messages.TextEdit{}
+	usages := map[string]string{}
+
+	var walk func(node *tree_sitter.Node)
+	walk = func(node *tree_sitter.Node) {
+		switch node.Kind() {
+		case "import_statement":
+			edits = append(edits, renamePlainImport(node, source, encoding, renames, usages)...)
+			return
+		case "import_from_statement":
+			edits = append(edits, renameFromImport(node, source, encoding, renames, oldPkg, newPkg)...)
+			return
+		}
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			walk(node.NamedChild(i))
+		}
+	}
+	root := ast.Root
+	walk(root)
+	if len(usages) > 0 {
+		edits = append(edits, renameModuleUsages(root, source, encoding, usages)...)
+	}
+	slices.SortStableFunc(edits, func(a, b messages.TextEdit) int {
+		if a.Range.Start.Line != b.Range.Start.Line {
+			return int(a.Range.Start.Line) - int(b.Range.Start.Line)
+		}
+		return int(a.Range.Start.Character) - int(b.Range.Start.Character)
+	})
+	return edits
+}
+
+// renamePlainImport rewrites `import a.b`. Without an alias the module is
+// also how the file refers to it, so usages are collected for renaming.
+func renamePlainImport(statement *tree_sitter.Node, source []byte, encoding messages.PositionEncodingKind, renames []moduleRename, usages map[string]string) []messages.TextEdit {
+	edits := []messages.TextEdit{}
+	cursor := statement.Walk()
+	defer cursor.Close()
+	for _, name := range statement.ChildrenByFieldName("name", cursor) {
+		moduleNode := &name
+		aliased := name.Kind() == "aliased_import"
+		if aliased {
+			moduleNode = name.ChildByFieldName("name")
+		}
+		if moduleNode == nil {
+			continue
+		}
+		module := moduleNode.Utf8Text(source)
+		newModule, renamed := renameModule(module, renames)
+		if !renamed {
+			continue
+		}
+		edits = append(edits, messages.TextEdit{Range: nodeRange(moduleNode, source, encoding), NewText: newModule})
+		if !aliased {
+			usages[module] = newModule
+		}
+	}
+	return edits
+}
+
+// renameFromImport rewrites `from a.b import c` when a.b, or c being a
+// module itself, is renamed. Relative imports keep being relative, to the
+// new location of the file when it moves too. A renamed module keeps its
+// old name in the file through an alias, so its usages stay valid.
+func renameFromImport(statement *tree_sitter.Node, source []byte, encoding messages.PositionEncodingKind, renames []moduleRename, oldPkg, newPkg string) []messages.TextEdit {
+	moduleNode := statement.ChildByFieldName("module_name")
+	if moduleNode == nil {
+		return nil
+	}
+	text := moduleNode.Utf8Text(source)
+	relative := moduleNode.Kind() == "relative_import"
+	module := text
+	if relative {
+		module = absoluteModule(oldPkg, text)
+	}
+	render := func(module string) string {
+		if relative {
+			return relativeModule(newPkg, module)
+		}
+		return module
+	}
+
+	if newModule, renamed := renameModule(module, renames); renamed || (relative && oldPkg != newPkg) {
+		if rendered := render(newModule); rendered != text {
+			return []messages.TextEdit{{Range: nodeRange(moduleNode, source, encoding), NewText: rendered}}
+		}
+		return nil
+	}
+
+	edits, kept, split := []messages.TextEdit{}, []string{}, []string{}
+	cursor := statement.Walk()
+	defer cursor.Close()
+	names := statement.ChildrenByFieldName("name", cursor)
+	for i := range names {
+		nameNode, alias := &names[i], ""
+		if nameNode.Kind() == "aliased_import" {
+			if aliasNode := nameNode.ChildByFieldName("alias"); aliasNode != nil {
+				alias = aliasNode.Utf8Text(source)
+			}
+			nameNode = nameNode.ChildByFieldName("name")
+		}
+		if nameNode == nil {
+			kept = append(kept, names[i].Utf8Text(source))
+			continue
+		}
+		name := nameNode.Utf8Text(source)
+		newModule, renamed := renameModule(joinModule(module, name), renames)
+		if !renamed {
+			kept = append(kept, names[i].Utf8Text(source))
+			continue
+		}
+		newParent, newName := "", newModule
+		if dot := strings.LastIndex(newModule, "."); dot >= 0 {
+			newParent, newName = newModule[:dot], newModule[dot+1:]
+		}
+		if alias == "" && newName != name {
+			alias = name
+		}
+		imported := newName
+		if alias != "" {
+			imported += " as " + alias
+		}
+		if newParent == module {
+			kept = append(kept, imported)
+			edits = append(edits, messages.TextEdit{Range: nodeRange(&names[i], source, encoding), NewText: imported})
+			continue
+		}
+		if newParent == "" && !relative {
+			split = append(split, "import "+imported)
+		} else {
+			split = append(split, "from "+render(newParent)+" import "+imported)
+		}
+	}
+	if len(split) == 0 {
+		return edits
+	}
+
+	// Names moving to another module get their own statements, right after
+	// this one. Edits of a statement would overlap on the separators, so the
+	// whole statement is rewritten at once, or replaced when no name is left.
+	indent := string(source[statement.StartByte()-statement.StartPosition().Column : statement.StartByte()])
+	statementText := strings.Join(split, "\n"+indent)
+	if len(kept) > 0 {
+		first, last := names[0], names[len(names)-1]
+		statementText = string(source[statement.StartByte():first.StartByte()]) +
+			strings.Join(kept, ", ") +
+			string(source[last.EndByte():statement.EndByte()]) +
+			"\n" + indent + statementText
+	}
+	return []messages.TextEdit{{Range: nodeRange(statement, source, encoding), NewText: statementText}}
+}
+
+// renameModuleUsages renames references like `a.b.func()` of modules bound
+// by `import a.b`.
+func renameModuleUsages(node *tree_sitter.Node, source []byte, encoding messages.PositionEncodingKind, usages map[string]string) []messages.TextEdit {
+	switch node.Kind() {
+	case "import_statement", "import_from_statement":
+		return nil
+	case "identifier", "attribute":
+		if newModule, found := usages[node.Utf8Text(source)]; found {
+			return []messages.TextEdit{{Range: nodeRange(node, source, encoding), NewText: newModule}}
+		}
+	}
+	edits := []messages.TextEdit{}
+	if node.Kind() == "attribute" {
+		// `os` in `self.os` isn't a module reference.
+		if object := node.ChildByFieldName("object"); object != nil {
+			edits = append(edits, renameModuleUsages(object, source, encoding, usages)...)
+		}
+		return edits
+	}
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		edits = append(edits, renameModuleUsages(node.NamedChild(i), source, encoding, usages)...)
+	}
+	return edits
+}
+
+// MoveFiles moves renamed files, and files in renamed folders, to their new
+// URLs. URLs of files never change: a moved file is a copy stored under its
+// new URL before the old one is dropped, so readers always find one of them.
+// Its symbols keep their UUIDs. Imports of the moved files and of the files
+// importing them are resolved again.
+func (w *Workspace) MoveFiles(renames []messages.FileRename) {
+	w.fileEventsMutex.Lock()
+	defer w.fileEventsMutex.Unlock()
+	moved := []*PythonFile{}
+	oldUrls := []string{}
+	affected := map[*PythonFile]bool{}
+	for _, rename := range renames {
+		for _, file := range w.filesUnderPath(strings.TrimPrefix(rename.OldURI, "file://")) {
+			newUrl := rename.NewURI + strings.TrimPrefix(file.Url, rename.OldURI)
+			if existing, err := w.GetPythonFile(newUrl); err == nil {
+				// Already picked up by a file watcher as a new file.
+				existing.remove()
+			}
+			for _, dependent := range file.Dependents() {
+				affected[dependent] = true
+			}
+			movedFile := file.movedTo(newUrl)
+			w.Files.Store(newUrl, movedFile)
+			file.remove()
+			oldUrls = append(oldUrls, file.Url)
+			moved = append(moved, movedFile)
+		}
+	}
+	if len(moved) == 0 {
+		return
+	}
+	slog.Debug("Moved files", slog.Int("files", len(moved)))
+
+	for _, file := range w.filesWithUnresolvedImports() {
+		affected[file] = true
+	}
+	for _, file := range moved {
+		delete(affected, file)
+		if file.External {
+			continue
+		}
+		file.ParseImports()
+		file.relinkSymbols()
+	}
+	for file := range affected {
+		if _, err := w.GetPythonFile(file.Url); err != nil {
+			continue
+		}
+		file.ParseImports()
+		file.relinkSymbols()
+	}
+
+	for _, url := range oldUrls {
+		w.clearDiagnostics(url)
+	}
+	for _, file := range moved {
+		file.PublishDiagnostics()
+	}
+	for file := range affected {
+		file.PublishDiagnostics()
+	}
+	w.RefreshDiagnostics()
+}
+
+// movedTo copies the file to a new URL, with its content and copies of its
+// symbols keeping their UUIDs. The copy isn't stored in the workspace files,
+// its imports are resolved by the caller.
+func (f *PythonFile) movedTo(url string) *PythonFile {
+	f.astMutex.Lock()
+	moved := f.workspace.newPythonFile(url, f.Text, f.External, f.isOpened)
+	moved.Version, moved.stamp = f.Version, f.stamp
+	f.astMutex.Unlock()
+	if value, ok := f.workspace.Symbols.Load(f); ok && !f.External {
+		storeSymbols(moved, restoreSymbols(moved, cacheSymbols(value.([]*Symbol)), nil))
+	}
+	return moved
+}
@@ -0,0 +1,134 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"snakelsp/internal/messages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelativeModules(t *testing.T) {
+	assert.Equal(t, "app.models", absoluteModule("app", ".models"))
+	assert.Equal(t, "models", absoluteModule("app.api", "...models"))
+	assert.Equal(t, "app", absoluteModule("app.api", ".."))
+	assert.Equal(t, "core.models", absoluteModule("", ".core.models"))
+
+	assert.Equal(t, ".models", relativeModule("app", "app.models"))
+	assert.Equal(t, "..core", relativeModule("app.api", "app.core"))
+	assert.Equal(t, "...lib", relativeModule("app.api", "lib"))
+	assert.Equal(t, ".", relativeModule("app", "app"))
+}
+
+func loadRenameFiles(t *testing.T, files map[string]string) (*Workspace, string, map[string]*PythonFile) {
+	w, root := setupModulesPath(t, files)
+	loaded := map[string]*PythonFile{}
+	for name := range files {
+		file, err := w.ImportPythonFileFromFile(filepath.Join(root, name), false)
+		require.NoError(t, err)
+		loaded[name] = file
+	}
+	for _, file := range loaded {
+		_, err := file.parseSymbols()
+		require.NoError(t, err)
+	}
+	for _, file := range loaded {
+		_, err := file.ParseImports()
+		require.NoError(t, err)
+	}
+	return w, root, loaded
+}
+
+func TestRenameFilesEdit(t *testing.T) {
+	w, root, files := loadRenameFiles(t, map[string]string{
+		"app/__init__.py":        "",
+		"app/utils.py":           "def helper(): pass\n",
+		"app/api/__init__.py":    "",
+		"app/api/views.py":       "from ..utils import helper\nfrom . import serializers\n",
+		"app/api/serializers.py": "",
+		"lib/__init__.py":        "",
+		"main.py": "import app.utils\nfrom app import utils, api\nfrom app.utils import helper\n\n" +
+			"app.utils.helper()\nself.app.utils = 1\n",
+	})
+	rename := func(oldName, newName string) messages.WorkspaceEdit {
+		return w.RenameFilesEdit([]messages.FileRename{{
+			OldURI: "file://" + filepath.Join(root, oldName),
+			NewURI: "file://" + filepath.Join(root, newName),
+		}})
+	}
+	applied := func(edit messages.WorkspaceEdit, name string) string {
+		return applyTextEdits(files[name].Text, edit.Changes[files[name].Url])
+	}
+
+	// Module renamed in place
+	edit := rename("app/utils.py", "app/tools.py")
+	assert.Equal(t, "import app.tools\nfrom app import tools as utils, api\nfrom app.tools import helper\n\n"+
+		"app.tools.helper()\nself.app.utils = 1\n", applied(edit, "main.py"))
+	assert.Equal(t, "from ..tools import helper\nfrom . import serializers\n", applied(edit, "app/api/views.py"))
+
+	// Module moved to another package
+	edit = rename("app/utils.py", "lib/utils.py")
+	assert.Equal(t, "import lib.utils\nfrom app import api\nfrom lib import utils\nfrom lib.utils import helper\n\n"+
+		"lib.utils.helper()\nself.app.utils = 1\n", applied(edit, "main.py"))
+	assert.Equal(t, "from ...lib.utils import helper\nfrom . import serializers\n", applied(edit, "app/api/views.py"))
+
+	// Importing file moved, its relative imports follow
+	edit = rename("app/api/views.py", "app/views.py")
+	assert.Equal(t, "from .utils import helper\nfrom .api import serializers\n", applied(edit, "app/api/views.py"))
+	assert.NotContains(t, edit.Changes, files["main.py"].Url)
+
+	// Package renamed
+	edit = rename("app/api", "app/rest")
+	assert.Equal(t, "import app.utils\nfrom app import utils, rest as api\nfrom app.utils import helper\n\n"+
+		"app.utils.helper()\nself.app.utils = 1\n", applied(edit, "main.py"))
+	assert.NotContains(t, edit.Changes, files["app/api/views.py"].Url)
+
+	// Every name of the statement moved
+	edit = w.RenameFilesEdit([]messages.FileRename{
+		{OldURI: "file://" + filepath.Join(root, "app/utils.py"), NewURI: "file://" + filepath.Join(root, "lib/utils.py")},
+		{OldURI: "file://" + filepath.Join(root, "app/api"), NewURI: "file://" + filepath.Join(root, "lib/api")},
+	})
+	assert.Len(t, edit.Changes[files["main.py"].Url], 4)
+	assert.Equal(t, "import lib.utils\nfrom lib import utils\nfrom lib import api\nfrom lib.utils import helper\n\n"+
+		"lib.utils.helper()\nself.app.utils = 1\n", applied(edit, "main.py"))
+
+	// Not a module rename
+	assert.Empty(t, rename("app/utils.py", "app/utils.py").Changes)
+}
+
+func TestMoveFiles(t *testing.T) {
+	w, root, files := loadRenameFiles(t, map[string]string{
+		"app/__init__.py": "",
+		"app/models.py":   "class User:\n    pass\n",
+		"main.py":         "from lib.models import User\n\nclass Admin(User):\n    pass\n",
+	})
+	models := files["app/models.py"]
+	symbols, err := models.FileSymbols("")
+	require.NoError(t, err)
+	require.Equal(t, []string{DiagnosticCodeUnresolvedImport, DiagnosticCodeUnresolvedBaseClass}, diagnosticCodes(files["main.py"]))
+
+	oldUrl := models.Url
+	newUrl := "file://" + filepath.Join(root, "lib/models.py")
+	require.NoError(t, os.Rename(filepath.Join(root, "app"), filepath.Join(root, "lib")))
+	w.MoveFiles([]messages.FileRename{{
+		OldURI: "file://" + filepath.Join(root, "app"),
+		NewURI: "file://" + filepath.Join(root, "lib"),
+	}})
+
+	_, err = w.GetPythonFile(oldUrl)
+	assert.Error(t, err)
+	assert.Equal(t, oldUrl, models.Url)
+	moved, err := w.GetPythonFile(newUrl)
+	require.NoError(t, err)
+	movedSymbols, err := moved.FileSymbols("")
+	require.NoError(t, err)
+	assert.Equal(t, symbols[0].UUID, movedSymbols[0].UUID)
+	found, exists := w.flatSymbol(symbols[0].UUID)
+	assert.True(t, exists)
+	assert.Same(t, movedSymbols[0], found)
+	assert.Empty(t, diagnosticCodes(files["main.py"]))
+	assert.Same(t, movedSymbols[0], files["main.py"].Imports[0].Symbol)
+}
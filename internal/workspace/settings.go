@@ -0,0 +1,265 @@
+package workspace
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+
+	"snakelsp/internal/messages"
+
+	"github.com/google/uuid"
+)
+
+// Settings are the options of the workspace, from `initializationOptions`
+// and the `snakelsp` section of the client configuration.
+type Settings struct {
+	// Virtualenv of the folders without one of their own.
+	VirtualEnvPath string
+	// Virtualenv per folder, keyed by folder name or root path.
+	VirtualEnvPaths map[string]string
+	// Options of every folder, relative to the folder. Nil when not set,
+	// the project config of the folder applies then.
+	SourceRoots []string
+	ExtraPaths  []string
+	Include     []string
+	Exclude     []string
+
+	WorkspaceSymbolLimit      int // No limit when 0
+	WorkspaceSymbolScope      SymbolScope
+	TransitiveImplementations bool
+
+	Diagnostics DiagnosticsSettings
+	InlayHints  InlayHintsSettings
+	LogLevel    slog.Level
+}
+
+type DiagnosticsSettings struct {
+	Enabled  bool
+	Disabled []string // Codes never reported
+}
+
+func (d DiagnosticsSettings) reports(code string) bool {
+	return d.Enabled && !slices.Contains(d.Disabled, code)
+}
+
+// InlayHintsSettings are the categories of inlay hints the client wants.
+// Nothing reads them yet, the server doesn't provide inlay hints.
+type InlayHintsSettings struct {
+	VariableTypes  bool
+	ReturnTypes    bool
+	ParameterNames bool
+}
+
+// NewSettings reads the options sent by the client, with the defaults of the
+// ones it didn't set.
+func NewSettings(options *messages.InitializationOptionsParams) Settings {
+	settings := Settings{
+		WorkspaceSymbolLimit:      100,
+		WorkspaceSymbolScope:      SymbolScopeProject,
+		TransitiveImplementations: true,
+		Diagnostics:               DiagnosticsSettings{Enabled: true},
+		InlayHints:                InlayHintsSettings{VariableTypes: true, ReturnTypes: true, ParameterNames: true},
+		LogLevel:                  slog.LevelDebug,
+	}
+	if options == nil {
+		return settings
+	}
+	settings.VirtualEnvPath = options.VirtualEnvPath
+	settings.VirtualEnvPaths = options.VirtualEnvPaths
+	settings.SourceRoots = options.SourceRoots
+	settings.ExtraPaths = options.ExtraPaths
+	settings.Include = options.Include
+	settings.Exclude = options.Exclude
+	if options.WorkspaceSymbolLimit != nil {
+		settings.WorkspaceSymbolLimit = *options.WorkspaceSymbolLimit
+	}
+	if SymbolScope(options.WorkspaceSymbolScope) == SymbolScopeAll {
+		settings.WorkspaceSymbolScope = SymbolScopeAll
+	}
+	settings.TransitiveImplementations = options.Implementations != "direct"
+	if diagnostics := options.Diagnostics; diagnostics != nil {
+		if diagnostics.Enabled != nil {
+			settings.Diagnostics.Enabled = *diagnostics.Enabled
+		}
+		settings.Diagnostics.Disabled = diagnostics.Disabled
+	}
+	if hints := options.InlayHints; hints != nil {
+		setIfPresent(&settings.InlayHints.VariableTypes, hints.VariableTypes)
+		setIfPresent(&settings.InlayHints.ReturnTypes, hints.ReturnTypes)
+		setIfPresent(&settings.InlayHints.ParameterNames, hints.ParameterNames)
+	}
+	switch strings.ToLower(options.LogLevel) {
+	case "info":
+		settings.LogLevel = slog.LevelInfo
+	case "warn", "warning":
+		settings.LogLevel = slog.LevelWarn
+	case "error":
+		settings.LogLevel = slog.LevelError
+	}
+	return settings
+}
+
+func setIfPresent(value *bool, option *bool) {
+	if option != nil {
+		*value = *option
+	}
+}
+
+// Settings returns the current settings of the workspace.
+func (w *Workspace) Settings() Settings {
+	w.settingsMutex.RLock()
+	defer w.settingsMutex.RUnlock()
+	return w.settings.Settings
+}
+
+// UpdateSettings applies settings changed while the server runs, with
+// diagnostics published again when their options changed.
+func (w *Workspace) UpdateSettings(settings Settings) {
+	previous := w.Settings()
+	w.settingsMutex.Lock()
+	w.settings.Settings = settings
+	w.settingsMutex.Unlock()
+
+	republished := w.reloadFolders(w.Folders(), false)
+	if !republished && (previous.Diagnostics.Enabled != settings.Diagnostics.Enabled ||
+		!slices.Equal(previous.Diagnostics.Disabled, settings.Diagnostics.Disabled)) {
+		w.republishDiagnostics()
+	}
+}
+
+// reloadFolders resolves the options of the folders again, from the settings
+// and their project config, doing only the work the changes need: imports of
+// the folders whose module paths changed are resolved again, files are
+// indexed or dropped when the exclusions changed, and diagnostics published
+// again when any of this happened, as it reports. Folders not indexed yet
+// only get their new options. With ignoresChanged the .gitignore files of the
+// folders are read again too.
+func (w *Workspace) reloadFolders(folders []*WorkspaceFolder, ignoresChanged bool) bool {
+	settings := ClientSettingsType{Settings: w.Settings()}
+	replaced := map[*WorkspaceFolder]*WorkspaceFolder{}
+	modulesChanged := []*WorkspaceFolder{}
+	excludeChanged := false
+	for _, folder := range folders {
+		updated := resolveWorkspaceFolder(&settings, folder.Name, folder.Root)
+		sameModulesPath := updated.sameModulesPath(folder)
+		sameExclude := !ignoresChanged && slices.Equal(updated.Include, folder.Include) &&
+			slices.Equal(updated.Exclude, folder.Exclude)
+		if sameModulesPath && sameExclude {
+			continue
+		}
+		if sameModulesPath {
+			updated.ModulesPath = folder.ModulesPath
+		} else {
+			updated.ModulesPath = updated.modulesPath()
+			modulesChanged = append(modulesChanged, updated)
+		}
+		excludeChanged = excludeChanged || !sameExclude
+		updated.indexed.Store(folder.indexed.Load())
+		replaced[folder] = updated
+	}
+	if len(replaced) == 0 {
+		return false
+	}
+
+	w.settingsMutex.Lock()
+	current := slices.Clone(w.settings.Folders)
+	for i, folder := range current {
+		if updated, ok := replaced[folder]; ok {
+			current[i] = updated
+		}
+	}
+	w.settings.Folders = current
+	w.settingsMutex.Unlock()
+
+	if len(modulesChanged) > 0 {
+		w.libraries.Store(newLibraryIndex())
+	}
+	// Virtualenvs and excluded folders aren't watched. Restarted in the
+	// background, the reload can come from the watcher itself.
+	go func() {
+		if err := w.RestartFileWatcher(); err != nil {
+			slog.Error("Unable to restart file watcher", slog.Any("error", err))
+		}
+	}()
+	republish := false
+	if len(modulesChanged) > 0 {
+		republish = w.resolveFolderImports(modulesChanged)
+	}
+	if excludeChanged {
+		republish = w.applyExclusions() || republish
+	}
+	if republish {
+		w.republishDiagnostics()
+	}
+	return republish
+}
+
+// resolveFolderImports resolves the imports of the project files of the
+// folders again, with their new module paths. It reports whether the folders
+// had any.
+func (w *Workspace) resolveFolderImports(folders []*WorkspaceFolder) bool {
+	w.fileEventsMutex.Lock()
+	defer w.fileEventsMutex.Unlock()
+	files := []*PythonFile{}
+	w.Files.Range(func(key, value any) bool {
+		file := value.(*PythonFile)
+		if !file.External && slices.Contains(folders, w.FolderOf(strings.TrimPrefix(file.Url, "file://"))) {
+			files = append(files, file)
+		}
+		return true
+	})
+	for _, file := range files {
+		file.ParseImports()
+		file.relinkSymbols()
+	}
+	slog.Info("Imports resolved with the new module paths", slog.Int("folders", len(folders)), slog.Int("files", len(files)))
+	return len(files) > 0
+}
+
+// applyExclusions drops the project files excluded by the settings and
+// indexes the ones no longer excluded, in the indexed folders. It reports
+// whether any file was dropped or indexed.
+func (w *Workspace) applyExclusions() bool {
+	w.fileEventsMutex.Lock()
+	excluded := []*PythonFile{}
+	w.Files.Range(func(key, value any) bool {
+		file := value.(*PythonFile)
+		path := strings.TrimPrefix(file.Url, "file://")
+		if folder := w.FolderOf(path); !file.External && folder != nil && folder.indexed.Load() && w.isExcludedPath(path) {
+			excluded = append(excluded, file)
+		}
+		return true
+	})
+	w.dropProjectFiles(excluded)
+	w.fileEventsMutex.Unlock()
+
+	events := []messages.FileEvent{}
+	for _, folder := range w.Folders() {
+		if !folder.indexed.Load() {
+			continue
+		}
+		for _, path := range w.discoverProjectFiles(folder.Root, folder.VirtualEnvPath) {
+			if file, err := w.GetPythonFile("file://" + path); err != nil || file.External {
+				events = append(events, messages.FileEvent{URI: "file://" + path, Type: messages.FileChangeTypeCreated})
+			}
+		}
+	}
+	w.ApplyFileEvents(events)
+	slog.Info("Exclusions applied", slog.Int("dropped", len(excluded)), slog.Int("indexed", len(events)))
+	return len(excluded) > 0 || len(events) > 0
+}
+
+// republishDiagnostics publishes the diagnostics of every project file,
+// including empty ones to clear those not reported anymore, and asks pulling
+// clients to pull them again.
+func (w *Workspace) republishDiagnostics() {
+	w.Files.Range(func(key, value any) bool {
+		value.(*PythonFile).PublishDiagnostics()
+		return true
+	})
+	w.RefreshDiagnostics()
+}
+
+func newLibraryIndex() *libraryIndex {
+	return &libraryIndex{byUUID: map[uuid.UUID]*Symbol{}}
+}
@@ -0,0 +1,127 @@
+package workspace
+
+import (
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"snakelsp/internal/messages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSettings(t *testing.T) {
+	settings := NewSettings(nil)
+	assert.Equal(t, 100, settings.WorkspaceSymbolLimit)
+	assert.Equal(t, SymbolScopeProject, settings.WorkspaceSymbolScope)
+	assert.True(t, settings.TransitiveImplementations)
+	assert.True(t, settings.Diagnostics.Enabled)
+	assert.True(t, settings.InlayHints.ReturnTypes)
+	assert.Equal(t, slog.LevelDebug, settings.LogLevel)
+
+	options := &messages.InitializationOptionsParams{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"virtualenv_path": "/venv",
+		"extra_paths": ["src"],
+		"workspace_symbol_limit": 0,
+		"implementations": "direct",
+		"diagnostics": {"disabled": ["unused-import"]},
+		"inlay_hints": {"return_types": false},
+		"log_level": "warning"
+	}`), options))
+	settings = NewSettings(options)
+	assert.Equal(t, "/venv", settings.VirtualEnvPath)
+	assert.Equal(t, []string{"src"}, settings.ExtraPaths)
+	assert.Equal(t, 0, settings.WorkspaceSymbolLimit)
+	assert.False(t, settings.TransitiveImplementations)
+	assert.True(t, settings.Diagnostics.Enabled)
+	assert.False(t, settings.Diagnostics.reports(DiagnosticCodeUnusedImport))
+	assert.True(t, settings.Diagnostics.reports(DiagnosticCodeUnresolvedImport))
+	assert.False(t, settings.InlayHints.ReturnTypes)
+	assert.True(t, settings.InlayHints.VariableTypes)
+	assert.Equal(t, slog.LevelWarn, settings.LogLevel)
+
+	// Configuration sections override the initialization options
+	overridden, err := options.Override(json.RawMessage(`{"log_level": "error", "diagnostics": {"enabled": false}}`))
+	require.NoError(t, err)
+	settings = NewSettings(overridden)
+	assert.Equal(t, "/venv", settings.VirtualEnvPath)
+	assert.Equal(t, slog.LevelError, settings.LogLevel)
+	assert.False(t, settings.Diagnostics.Enabled)
+	assert.Equal(t, "warning", options.LogLevel)
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		relative string
+		expected bool
+	}{
+		{"migrations", "app/migrations/0001.py", true},
+		{"migrations", "app/models.py", false},
+		{"*_pb2.py", "proto/api_pb2.py", true},
+		{"tests/*.py", "tests/test_app.py", true},
+		{"tests/*.py", "app/tests/test_app.py", false},
+		{"**/tests", "app/tests/test_app.py", true},
+		{"app/**/generated", "app/a/b/generated/x.py", true},
+		{"/build", "build/lib/x.py", true},
+		{"build/", "build/lib/x.py", true},
+		{"./docs", "docs/conf.py", true},
+		{"docs", "src/docs.py", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, matchGlob(test.pattern, test.relative), "%s %s", test.pattern, test.relative)
+	}
+}
+
+func TestUpdateSettingsExclude(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"app/models.py":              "class Model:\n    pass\n",
+		"app/migrations/__init__.py": "",
+		"app/migrations/0001.py":     "from app.models import Model\n",
+	})
+	settings := w.Settings()
+	settings.Exclude = []string{"migrations"}
+	w.UpdateSettings(settings)
+	require.NoError(t, w.IndexProject(root, "", nil))
+
+	migration := "file://" + filepath.Join(root, "app/migrations/0001.py")
+	_, err := w.GetPythonFile(migration)
+	assert.Error(t, err)
+
+	settings.Exclude = nil
+	w.UpdateSettings(settings)
+	file, err := w.GetPythonFile(migration)
+	require.NoError(t, err)
+	assert.False(t, file.External)
+	require.Len(t, file.Imports, 1)
+	assert.NoError(t, file.Imports[0].ResolveError)
+
+	settings.Exclude = []string{"app/migrations/*.py"}
+	w.UpdateSettings(settings)
+	_, err = w.GetPythonFile(migration)
+	assert.Error(t, err)
+}
+
+func TestDisabledDiagnostics(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"pkg/__init__.py": "",
+	})
+	mockFile := &PythonFile{
+		workspace: w,
+		Url:       "file://" + filepath.Join(root, "main.py"),
+		Text:      "import missing_module\nimport pkg\n\nprint(missing_module)\n",
+	}
+	assert.ElementsMatch(t, []string{DiagnosticCodeUnresolvedImport, DiagnosticCodeUnusedImport}, diagnosticCodes(mockFile))
+
+	settings := w.Settings()
+	settings.Diagnostics.Disabled = []string{DiagnosticCodeUnusedImport}
+	w.UpdateSettings(settings)
+	assert.Equal(t, []string{DiagnosticCodeUnresolvedImport}, diagnosticCodes(mockFile))
+
+	settings.Diagnostics.Enabled = false
+	w.UpdateSettings(settings)
+	assert.Empty(t, diagnosticCodes(mockFile))
+}
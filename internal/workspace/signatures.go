@@ -0,0 +1,121 @@
+package workspace
+
+import (
+	"strings"
+
+	"snakelsp/internal/messages"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+type ParameterKind int
+
+const (
+	ParameterPositionalOnly ParameterKind = iota // Declared before `/`
+	ParameterPositional                          // Positional or keyword
+	ParameterVarPositional                       // *args
+	ParameterKeywordOnly                         // Declared after `*` or *args
+	ParameterVarKeyword                          // **kwargs
+)
+
+type Parameter struct {
+	Name       string
+	Kind       ParameterKind
+	HasDefault bool
+	Range      messages.Range
+}
+
+// IsRequired reports whether every call has to pass the parameter.
+func (p Parameter) IsRequired() bool {
+	return !p.HasDefault && p.Kind != ParameterVarPositional && p.Kind != ParameterVarKeyword
+}
+
+// parseParameters turns a `parameters` node into a parameter list.
+func parseParameters(node *tree_sitter.Node, source []byte, encoding messages.PositionEncodingKind) []Parameter {
+	parameters := []Parameter{}
+	if node == nil {
+		return parameters
+	}
+	keywordOnly := false
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		child := node.NamedChild(i)
+		parameter := Parameter{Kind: ParameterPositional, Range: nodeRange(child, source, encoding)}
+		nameNode := child
+		switch child.Kind() {
+		case "positional_separator":
+			for j := range parameters {
+				parameters[j].Kind = ParameterPositionalOnly
+			}
+			continue
+		case "keyword_separator":
+			keywordOnly = true
+			continue
+		case "default_parameter", "typed_default_parameter":
+			parameter.HasDefault = true
+			nameNode = child.ChildByFieldName("name")
+		case "typed_parameter":
+			nameNode = child.NamedChild(0)
+		}
+		if nameNode == nil {
+			continue
+		}
+		switch nameNode.Kind() {
+		case "list_splat_pattern":
+			parameter.Kind = ParameterVarPositional
+			keywordOnly = true
+			nameNode = nameNode.NamedChild(0)
+		case "dictionary_splat_pattern":
+			parameter.Kind = ParameterVarKeyword
+			nameNode = nameNode.NamedChild(0)
+		default:
+			if keywordOnly {
+				parameter.Kind = ParameterKeywordOnly
+			}
+		}
+		if nameNode != nil {
+			parameter.Name = nameNode.Utf8Text(source)
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
+
+// functionDecorators returns decorator names of a function definition without
+// the `@` and call arguments, e.g. `functools.wraps` for `@functools.wraps(f)`.
+func functionDecorators(definition *tree_sitter.Node, source []byte) []string {
+	decorators := []string{}
+	parent := definition.Parent()
+	if parent == nil || parent.Kind() != "decorated_definition" {
+		return decorators
+	}
+	for i := uint(0); i < parent.NamedChildCount(); i++ {
+		child := parent.NamedChild(i)
+		if child.Kind() != "decorator" || child.NamedChildCount() == 0 {
+			continue
+		}
+		expression := child.NamedChild(0)
+		if expression.Kind() == "call" {
+			expression = expression.ChildByFieldName("function")
+		}
+		if expression != nil {
+			decorators = append(decorators, expression.Utf8Text(source))
+		}
+	}
+	return decorators
+}
+
+func isAsyncFunction(definition *tree_sitter.Node) bool {
+	first := definition.Child(0)
+	return first != nil && first.Kind() == "async"
+}
+
+// HasDecorator reports whether the symbol is decorated with name, matching
+// both `@name` and qualified forms like `@abc.name`.
+func (s *Symbol) HasDecorator(name string) bool {
+	for _, decorator := range s.Decorators {
+		if decorator == name || strings.HasSuffix(decorator, "."+name) {
+			return true
+		}
+	}
+	return false
+}
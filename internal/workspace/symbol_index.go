@@ -0,0 +1,86 @@
+package workspace
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+
+	"snakelsp/internal/messages"
+)
+
+// symbolIndex finds the symbols of a file by position: name ranges sorted by
+// start, with the furthest end seen so far, so ranges containing a position
+// are found without going through the whole file.
+type symbolIndex struct {
+	symbols []*Symbol
+	maxEnds []messages.Position
+}
+
+func comparePositions(a, b messages.Position) int {
+	if c := cmp.Compare(a.Line, b.Line); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Character, b.Character)
+}
+
+func newSymbolIndex(symbols []*Symbol) *symbolIndex {
+	index := &symbolIndex{}
+	var add func(symbols []*Symbol)
+	add = func(symbols []*Symbol) {
+		for _, symbol := range symbols {
+			index.symbols = append(index.symbols, symbol)
+			add(symbol.Children)
+		}
+	}
+	add(symbols)
+	slices.SortStableFunc(index.symbols, func(a, b *Symbol) int {
+		return comparePositions(a.NameRange.Start, b.NameRange.Start)
+	})
+	index.maxEnds = make([]messages.Position, len(index.symbols))
+	for i, symbol := range index.symbols {
+		index.maxEnds[i] = symbol.NameRange.End
+		if i > 0 && comparePositions(index.maxEnds[i-1], symbol.NameRange.End) > 0 {
+			index.maxEnds[i] = index.maxEnds[i-1]
+		}
+	}
+	return index
+}
+
+// find returns the innermost symbol whose name range contains the position,
+// its end included so a cursor right after the name still finds it.
+func (index *symbolIndex) find(position messages.Position) *Symbol {
+	i := sort.Search(len(index.symbols), func(i int) bool {
+		return comparePositions(index.symbols[i].NameRange.Start, position) > 0
+	})
+	// The candidates start before the position, the latest starting one
+	// containing it is the innermost.
+	for i--; i >= 0 && comparePositions(index.maxEnds[i], position) >= 0; i-- {
+		if comparePositions(index.symbols[i].NameRange.End, position) >= 0 {
+			return index.symbols[i]
+		}
+	}
+	return nil
+}
+
+// symbolIndex returns the position index of the file symbols, built again
+// after they changed.
+func (f *PythonFile) symbolIndex() (*symbolIndex, error) {
+	symbols, err := f.FileSymbols("")
+	if err != nil {
+		return nil, err
+	}
+	f.symbolsMutex.Lock()
+	defer f.symbolsMutex.Unlock()
+	if f.positionIndex == nil {
+		f.positionIndex = newSymbolIndex(symbols)
+	}
+	return f.positionIndex, nil
+}
+
+// invalidateSymbolIndex drops the position index, when symbols are added,
+// removed or moved.
+func (f *PythonFile) invalidateSymbolIndex() {
+	f.symbolsMutex.Lock()
+	f.positionIndex = nil
+	f.symbolsMutex.Unlock()
+}
@@ -0,0 +1,266 @@
+package workspace
+
+import (
+	"cmp"
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"snakelsp/internal/messages"
+)
+
+// How well a name matches a query, best last.
+type matchTier int
+
+const (
+	noMatch matchTier = iota
+	subsequenceMatch
+	wordBoundaryMatch // Every character starts a word of the name or follows one matched in it
+	prefixMatch
+	exactMatch
+)
+
+// Kind filters of symbol queries, e.g. `#class User`.
+var symbolKindFilters = map[string][]messages.SymbolKind{
+	"class":    {messages.SymbolKindClass},
+	"def":      {messages.SymbolKindFunction, messages.SymbolKindMethod},
+	"func":     {messages.SymbolKindFunction, messages.SymbolKindMethod},
+	"function": {messages.SymbolKindFunction, messages.SymbolKindMethod},
+	"method":   {messages.SymbolKindMethod},
+}
+
+// Scope modifiers of symbol queries, e.g. `#lib QuerySet`.
+var symbolScopeModifiers = map[string]SymbolScope{
+	"project": SymbolScopeProject,
+	"all":     SymbolScopeAll,
+	"lib":     SymbolScopeAll,
+}
+
+// symbolQuery is a parsed symbol search: `#kind` filters, a name pattern
+// and the modules or class qualifying it, `pkg.mod.Name` or `Class.method`.
+type symbolQuery struct {
+	name      string
+	qualifier []string
+	kinds     []messages.SymbolKind
+	scope     SymbolScope
+}
+
+func parseSymbolQuery(query string, scope SymbolScope) symbolQuery {
+	parsed := symbolQuery{scope: scope}
+	var words []string
+	for _, word := range strings.Fields(query) {
+		if scope, ok := symbolScopeModifiers[strings.ToLower(strings.TrimPrefix(word, "#"))]; ok && strings.HasPrefix(word, "#") {
+			parsed.scope = scope
+			continue
+		}
+		if kinds, ok := symbolKindFilters[strings.ToLower(strings.TrimPrefix(word, "#"))]; ok && strings.HasPrefix(word, "#") {
+			parsed.kinds = append(parsed.kinds, kinds...)
+			continue
+		}
+		words = append(words, word)
+	}
+	parts := strings.Split(strings.Join(words, ""), ".")
+	parsed.name = parts[len(parts)-1]
+	for _, part := range parts[:len(parts)-1] {
+		if part != "" {
+			parsed.qualifier = append(parsed.qualifier, part)
+		}
+	}
+	return parsed
+}
+
+// symbolContainers lists what qualifies a symbol: the folders and module of
+// its file, then its class.
+func symbolContainers(s *Symbol) []string {
+	var containers []string
+	if s.File != nil {
+		path := strings.TrimSuffix(strings.TrimPrefix(s.File.Url, "file://"), filepath.Ext(s.File.Url))
+		for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+			if part != "" && part != "__init__" {
+				containers = append(containers, part)
+			}
+		}
+	}
+	if s.Parent != nil {
+		containers = append(containers, s.Parent.Name)
+	}
+	return containers
+}
+
+// matchesQualifier reports whether the qualifier parts prefix containers of
+// the symbol, in order. Containers can be skipped, packages expose names
+// of their modules: `django.db.models.QuerySet` is in `django/db/models/query.py`.
+func (q symbolQuery) matchesQualifier(s *Symbol) bool {
+	if len(q.qualifier) == 0 {
+		return true
+	}
+	containers := symbolContainers(s)
+	remaining := q.qualifier
+	for i := len(containers) - 1; i >= 0 && len(remaining) > 0; i-- {
+		if hasPrefixFold(containers[i], remaining[len(remaining)-1]) {
+			remaining = remaining[:len(remaining)-1]
+		}
+	}
+	return len(remaining) == 0
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// matchName ranks how name matches pattern, case insensitively.
+func matchName(pattern, name string) matchTier {
+	switch {
+	case pattern == "":
+		return subsequenceMatch
+	case strings.EqualFold(pattern, name):
+		return exactMatch
+	case hasPrefixFold(name, pattern):
+		return prefixMatch
+	}
+	p, n := []rune(strings.ToLower(pattern)), []rune(name)
+	if matchWordBoundaries(p, n, wordStarts(n), 0, 0, false, map[[2]int]bool{}) {
+		return wordBoundaryMatch
+	}
+	if isSubsequence(p, []rune(strings.ToLower(name))) {
+		return subsequenceMatch
+	}
+	return noMatch
+}
+
+// wordStarts marks the characters starting a word: the first one, capitals
+// after lowercase letters or at the end of an acronym, and characters after
+// underscores or digits changes.
+func wordStarts(name []rune) []bool {
+	starts := make([]bool, len(name))
+	for i, r := range name {
+		if r == '_' {
+			continue
+		}
+		if i == 0 {
+			starts[i] = true
+			continue
+		}
+		previous := name[i-1]
+		switch {
+		case previous == '_':
+			starts[i] = true
+		case unicode.IsUpper(r) && !unicode.IsUpper(previous):
+			starts[i] = true
+		case unicode.IsUpper(r) && i+1 < len(name) && unicode.IsLower(name[i+1]):
+			starts[i] = true // `HTTPServer` starts `Server` at the S
+		case unicode.IsDigit(r) != unicode.IsDigit(previous):
+			starts[i] = true
+		}
+	}
+	return starts
+}
+
+// matchWordBoundaries matches pattern from p against name from n: each
+// character either starts a word or comes later in the word of the previous
+// one. The first character has to start a word. failed remembers the
+// positions already known not to match.
+func matchWordBoundaries(pattern, name []rune, starts []bool, p, n int, inWord bool, failed map[[2]int]bool) bool {
+	if p == len(pattern) {
+		return true
+	}
+	if failed[[2]int{p, n}] {
+		return false
+	}
+	for i := n; i < len(name); i++ {
+		if starts[i] {
+			inWord = false
+		}
+		if !starts[i] && !inWord {
+			continue
+		}
+		if unicode.ToLower(name[i]) == pattern[p] && matchWordBoundaries(pattern, name, starts, p+1, i+1, true, failed) {
+			return true
+		}
+	}
+	failed[[2]int{p, n}] = true
+	return false
+}
+
+func isSubsequence(pattern, name []rune) bool {
+	i := 0
+	for _, r := range name {
+		if i < len(pattern) && r == pattern[i] {
+			i++
+		}
+	}
+	return i == len(pattern)
+}
+
+type rankedSymbol struct {
+	symbol *Symbol
+	tier   matchTier
+}
+
+// rankSymbols keeps the symbols matching the query, best first: better
+// matches, then project symbols before library ones, then shorter names.
+func rankSymbols(symbols []*Symbol, query string) []*Symbol {
+	ranked, _ := parseSymbolQuery(query, SymbolScopeProject).rank(context.Background(), symbols)
+	return ranked
+}
+
+// Symbols matched between two checks of the context.
+const rankCancelCheckInterval = 1024
+
+// rank is rankSymbols for a parsed query, stopped with the context error
+// when it's canceled.
+func (parsed symbolQuery) rank(ctx context.Context, symbols []*Symbol) ([]*Symbol, error) {
+	ranked := []rankedSymbol{}
+	for i, symbol := range symbols {
+		if i%rankCancelCheckInterval == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if len(parsed.kinds) > 0 && !slices.Contains(parsed.kinds, symbol.Kind) {
+			continue
+		}
+		if !parsed.matchesQualifier(symbol) {
+			continue
+		}
+		if tier := matchName(parsed.name, symbol.Name); tier != noMatch {
+			ranked = append(ranked, rankedSymbol{symbol: symbol, tier: tier})
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b rankedSymbol) int {
+		if c := cmp.Compare(b.tier, a.tier); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(boolRank(isExternalSymbol(a.symbol)), boolRank(isExternalSymbol(b.symbol))); c != 0 {
+			return c
+		}
+		if a.tier == exactMatch {
+			// The exact case first
+			if c := cmp.Compare(boolRank(a.symbol.Name != parsed.name), boolRank(b.symbol.Name != parsed.name)); c != 0 {
+				return c
+			}
+		}
+		if c := cmp.Compare(utf8.RuneCountInString(a.symbol.Name), utf8.RuneCountInString(b.symbol.Name)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.symbol.Name, b.symbol.Name)
+	})
+	result := make([]*Symbol, len(ranked))
+	for i, r := range ranked {
+		result[i] = r.symbol
+	}
+	return result, nil
+}
+
+func isExternalSymbol(s *Symbol) bool {
+	return s.File != nil && s.File.External
+}
+
+// boolRank sorts false first.
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
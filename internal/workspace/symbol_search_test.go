@@ -0,0 +1,65 @@
+package workspace
+
+import (
+	"context"
+	"testing"
+
+	"snakelsp/internal/messages"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchName(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		expected      matchTier
+	}{
+		{"User", "User", exactMatch},
+		{"user", "User", exactMatch},
+		{"User", "UserSerializer", prefixMatch},
+		{"UsrSer", "UserSerializer", wordBoundaryMatch},
+		{"US", "UnusedSerializer", wordBoundaryMatch},
+		{"usrser", "UserSerializer", wordBoundaryMatch},
+		{"gau", "get_active_user", wordBoundaryMatch},
+		{"getuser", "get_active_user", wordBoundaryMatch},
+		{"hs", "HTTPServer", wordBoundaryMatch},
+		{"serial", "UserSerializer", wordBoundaryMatch},
+		{"erial", "UserSerializer", subsequenceMatch},
+		{"xyz", "UserSerializer", noMatch},
+		{"UsrSerq", "UserSerializer", noMatch},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, matchName(c.pattern, c.name), "%s in %s", c.pattern, c.name)
+	}
+}
+
+func TestRankSymbols(t *testing.T) {
+	project := &PythonFile{Url: "file:///project/app/serializers.py"}
+	library := &PythonFile{Url: "file:///venv/site-packages/rest/serializers.py", External: true}
+	userClass := &Symbol{Name: "User", Kind: messages.SymbolKindClass, File: project}
+	userSerializer := &Symbol{Name: "UserSerializer", Kind: messages.SymbolKindClass, File: project}
+	userSerializerMethod := &Symbol{Name: "user_serializer", Kind: messages.SymbolKindMethod, File: project, Parent: userClass}
+	libraryUserSerializer := &Symbol{Name: "UserSerializer", Kind: messages.SymbolKindClass, File: library}
+	unrelatedSerializer := &Symbol{Name: "UnusedResourceSerializer", Kind: messages.SymbolKindClass, File: project}
+	symbols := []*Symbol{unrelatedSerializer, libraryUserSerializer, userSerializerMethod, userSerializer, userClass}
+
+	assert.Equal(t, []*Symbol{userSerializer, userSerializerMethod, unrelatedSerializer, libraryUserSerializer}, rankSymbols(symbols, "UsrSer"))
+	assert.Equal(t, []*Symbol{userClass, userSerializer, userSerializerMethod, libraryUserSerializer, unrelatedSerializer}, rankSymbols(symbols, "User"))
+
+	// Kind filters
+	assert.Equal(t, []*Symbol{userSerializerMethod}, rankSymbols(symbols, "#method UsrSer"))
+	assert.Equal(t, []*Symbol{userSerializer, unrelatedSerializer, libraryUserSerializer}, rankSymbols(symbols, "UsrSer #Class"))
+
+	// Qualified names
+	assert.Equal(t, []*Symbol{libraryUserSerializer}, rankSymbols(symbols, "rest.serializers.UserSerializer"))
+	assert.Equal(t, []*Symbol{userSerializer, userSerializerMethod, unrelatedSerializer}, rankSymbols(symbols, "app.ser.Serializer"))
+	assert.Equal(t, []*Symbol{userSerializerMethod}, rankSymbols(symbols, "User.user"))
+	assert.Empty(t, rankSymbols(symbols, "serializers.app.UserSerializer"))
+}
+
+func TestRankSymbolsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := parseSymbolQuery("User", SymbolScopeProject).rank(ctx, []*Symbol{{Name: "User"}})
+	assert.ErrorIs(t, err, context.Canceled)
+}
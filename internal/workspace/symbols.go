@@ -1,6 +1,7 @@
 package workspace
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -8,11 +9,8 @@ import (
 	"sync"
 
 	"snakelsp/internal/messages"
-	"snakelsp/internal/progress"
 
-	"github.com/elliotchance/orderedmap/v3"
 	"github.com/google/uuid"
-	"github.com/lithammer/fuzzysearch/fuzzy"
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
 )
@@ -30,114 +28,72 @@ type Symbol struct {
 	Children   []*Symbol
 	Parent     *Symbol
 
+	// Parsed signature of functions and methods
+	Params     []Parameter
+	Decorators []string
+	Async      bool
+
 	// Base classes for class
 	// TODO: 1. Parse superclasses with attributes
 	// 2. Parse imports and superclasses from related files
-	SuperObjects      []*Symbol
-	superObjectsNames []string
+	SuperObjects       []*Symbol
+	superObjectsNames  []string
+	superObjectsRanges []messages.Range // Where each of superObjectsNames is written
 }
 
-var (
-	WorkspaceSymbols sync.Map
-	FlatSymbols      *orderedmap.OrderedMap[uuid.UUID, *Symbol] = orderedmap.NewOrderedMap[uuid.UUID, *Symbol]()
-)
-
-func SearchSymbolByUUID(uuid uuid.UUID) (*Symbol, error) {
-	symbol, exists := FlatSymbols.Get(uuid)
+func (w *Workspace) SearchSymbolByUUID(uuid uuid.UUID) (*Symbol, error) {
+	symbol, exists := w.flatSymbol(uuid)
 	if !exists {
+		if symbol, exists = w.searchLibrarySymbol(uuid); exists {
+			return symbol, nil
+		}
 		return nil, fmt.Errorf("symbol not found")
 	}
 	return symbol, nil
 }
 
-// It just parse symbols from the file and doesn't store them in the WorkspaceSymbols
-func (f *PythonFile) parseFileSymbols() ([]*Symbol, error) {
-	qc := tree_sitter.NewQueryCursor()
-	defer qc.Close()
+// symbolsQuery is compiled once, queries can be shared by the cursors of
+// concurrent workers.
+var symbolsQuery = sync.OnceValues(func() (*tree_sitter.Query, error) {
 	language := tree_sitter.NewLanguage(tree_sitter_python.Language())
 	query, err := tree_sitter.NewQuery(language, getTreeSitterQuery())
 	if err != nil {
 		slog.Error("Error creating query", "error", err)
 		return nil, err
 	}
-	symbols := processSymbols(f, qc, query)
-	return symbols, nil
-}
+	return query, nil
+})
 
-func BulkParseSymbols(pr *progress.WorkDone) error {
-	slog.Debug("Bulk parse symbols")
-	pr.Start("Parsing symbols")
+// It just parse symbols from the file and doesn't store them in the workspace symbols
+func (f *PythonFile) parseFileSymbols() ([]*Symbol, error) {
 	qc := tree_sitter.NewQueryCursor()
 	defer qc.Close()
-	language := tree_sitter.NewLanguage(tree_sitter_python.Language())
-	query, err := tree_sitter.NewQuery(language, getTreeSitterQuery())
+	query, err := symbolsQuery()
 	if err != nil {
-		slog.Error("Error creating query", "error", err)
-		return err
-	}
-	ProjectFiles.Range(func(key interface{}, value interface{}) bool {
-		pythonFile, ok := value.(*PythonFile)
-		if !ok {
-			slog.Error("Unexpected value type")
-			return true
-		}
-		if pythonFile.External {
-			return true
-		}
-
-		// Get existing symbols if they exist
-		existingSymbols, hasExisting := WorkspaceSymbols.Load(pythonFile)
-		newSymbols := processSymbols(pythonFile, qc, query)
-
-		if hasExisting {
-			// Update existing symbols in place to preserve references
-			updateSymbolsInPlace(existingSymbols.([]*Symbol), newSymbols)
-		} else {
-			// Store new symbols
-			WorkspaceSymbols.Store(pythonFile, newSymbols)
-			for _, symbol := range newSymbols {
-				FlatSymbols.Set(symbol.UUID, symbol)
-				for _, children := range symbol.Children {
-					FlatSymbols.Set(children.UUID, children)
-				}
-			}
-		}
-		return true
-	})
-	for symbol := range FlatSymbols.Values() {
-		resolveExternalSuperclassSymbol(symbol.File, symbol)
-		if symbol.Kind == messages.SymbolKindMethod {
-			resolveExternalSuperMethodSymbol(symbol.File, symbol)
-		}
+		return nil, err
 	}
-
-	slog.Debug("Bulk parse symbols done")
-	pr.End("Symbols parsed")
-	return nil
+	symbols := processSymbols(f, qc, query)
+	return symbols, nil
 }
 
 func (f *PythonFile) parseSymbols() ([]*Symbol, error) {
 	if f.External {
 		return nil, errors.New("cannot parse symbols for external files")
 	}
-	qc := tree_sitter.NewQueryCursor()
-	defer qc.Close()
-	language := tree_sitter.NewLanguage(tree_sitter_python.Language())
-	query, err := tree_sitter.NewQuery(language, getTreeSitterQuery())
+	symbols, err := f.parseFileSymbols()
 	if err != nil {
-		slog.Error("Error creating query", "error", err)
 		return nil, err
 	}
-	symbols := processSymbols(f, qc, query)
-	WorkspaceSymbols.Store(f, symbols)
+	f.forgetSymbols()
+	f.workspace.Symbols.Store(f, symbols)
+	f.invalidateSymbolIndex()
 	slog.Debug("Symbols for file parsed from the parseSymbols func", slog.String("file", f.Url), slog.Int("symbols", len(symbols)))
 	for _, symbol := range symbols {
 		resolveExternalSuperclassSymbol(f, symbol)
 	}
+	f.workspace.addFlatSymbols(symbols)
 	for _, symbol := range symbols {
-		FlatSymbols.Set(symbol.UUID, symbol)
 		for _, children := range symbol.Children {
-			FlatSymbols.Set(children.UUID, children)
 			resolveExternalSuperclassSymbol(f, children)
 			if children.Kind == messages.SymbolKindMethod {
 				resolveExternalSuperMethodSymbol(f, children)
@@ -147,26 +103,77 @@ func (f *PythonFile) parseSymbols() ([]*Symbol, error) {
 	return symbols, nil
 }
 
+// forgetSymbols drops the file symbols from the index, so a reparse doesn't
+// leave stale copies behind. UUIDs taken over by the copy of a moved file
+// stay.
+func (f *PythonFile) forgetSymbols() {
+	w := f.workspace
+	value, exists := w.Symbols.LoadAndDelete(f)
+	if !exists {
+		return
+	}
+	f.invalidateSymbolIndex()
+	for _, symbol := range value.([]*Symbol) {
+		w.forgetFlatSymbol(symbol)
+		w.unlinkSuperObjects(symbol)
+		w.forgetSubObjects(symbol)
+		for _, children := range symbol.Children {
+			w.forgetFlatSymbol(children)
+			w.unlinkSuperObjects(children)
+			w.forgetSubObjects(children)
+		}
+	}
+}
+
+// relinkSymbols resolves base classes and overridden methods of the file
+// symbols again, after the files they point to have changed.
+func (f *PythonFile) relinkSymbols() {
+	symbols, err := f.FileSymbols("")
+	if err != nil {
+		slog.Warn("Unable to get symbols for relinking", slog.String("file", f.Url), slog.Any("error", err))
+		return
+	}
+	for _, symbol := range symbols {
+		f.workspace.unlinkSuperObjects(symbol)
+		resolveExternalSuperclassSymbol(f, symbol)
+	}
+	for _, symbol := range symbols {
+		for _, child := range symbol.Children {
+			f.workspace.unlinkSuperObjects(child)
+			child.superObjectsNames = nil
+			resolveExternalSuperMethodSymbol(f, child)
+		}
+	}
+}
+
 func (f *PythonFile) FileSymbols(query string) ([]*Symbol, error) {
 	var symbols []*Symbol
 
-	value, exists := WorkspaceSymbols.Load(f)
+	// Imports resolved concurrently can reach the same file first.
+	f.symbolsMutex.Lock()
+	value, exists := f.workspace.Symbols.Load(f)
 	if !exists {
 		var err error
-		symbols, err = f.parseFileSymbols()
-		WorkspaceSymbols.Store(f, symbols)
-		if !f.External {
-			for _, symbol := range symbols {
-				FlatSymbols.Set(symbol.UUID, symbol)
-				for _, children := range symbol.Children {
-					FlatSymbols.Set(children.UUID, children)
-				}
+		cached := false
+		if f.External {
+			symbols, cached = cachedExternalSymbols(f)
+		}
+		if !cached {
+			symbols, err = f.parseFileSymbols()
+			if err == nil && f.External {
+				rememberExternalSymbols(f, symbols)
 			}
 		}
+		f.workspace.Symbols.Store(f, symbols)
+		if !f.External {
+			f.workspace.addFlatSymbols(symbols)
+		}
+		f.symbolsMutex.Unlock()
 		if err != nil {
 			return nil, err
 		}
 	} else {
+		f.symbolsMutex.Unlock()
 		var ok bool
 		symbols, ok = value.([]*Symbol)
 		if !ok {
@@ -183,27 +190,33 @@ func (f *PythonFile) FileSymbols(query string) ([]*Symbol, error) {
 	return symbols, nil
 }
 
-func GetWorkspaceSymbols(query string) ([]*Symbol, error) {
-	symbols := slices.Collect(FlatSymbols.Values())
+// GetWorkspaceSymbols searches the project symbols, also the library ones
+// when the query or the default scope asks for them. Library symbols are
+// indexed in the background, the first searches only get the project ones.
+func (w *Workspace) GetWorkspaceSymbols(ctx context.Context, query string) ([]*Symbol, error) {
+	parsed := parseSymbolQuery(query, w.Settings().WorkspaceSymbolScope)
+	symbols := w.ProjectSymbols()
+	if parsed.scope == SymbolScopeAll {
+		symbols = append(symbols, w.librarySymbols()...)
+	}
 	if query == "" {
 		return symbols, nil
 	}
-	symbols, err := filterSymbols(symbols, query)
-	if err != nil {
-		return nil, err
-	}
-	return symbols, nil
+	return parsed.rank(ctx, symbols)
 }
 
+// FindSymbolByPosition returns the innermost symbol of the file whose name
+// is at the position.
 func FindSymbolByPosition(file *PythonFile, line, character uint32) (*Symbol, error) {
-	for symbol := range FlatSymbols.Values() {
-		if symbol.File.Url == file.Url &&
-			(symbol.NameRange.Start.Line <= line && symbol.NameRange.End.Line >= line) &&
-			(symbol.NameRange.Start.Character <= character && symbol.NameRange.End.Character >= character) {
-			return symbol, nil
-		}
+	index, err := file.symbolIndex()
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("symbol not found")
+	symbol := index.find(messages.Position{Line: line, Character: character})
+	if symbol == nil {
+		return nil, fmt.Errorf("symbol not found")
+	}
+	return symbol, nil
 }
 
 func (s *Symbol) SymbolNameWithParent() string {
@@ -213,29 +226,9 @@ func (s *Symbol) SymbolNameWithParent() string {
 	return fmt.Sprintf("%s.%s", s.Parent.Name, s.FullName)
 }
 
+// filterSymbols keeps the symbols matching the query, ranked best first.
 func filterSymbols(symbols []*Symbol, query string) ([]*Symbol, error) {
-	var filteredSymbols []*Symbol
-
-	// Collect all symbol names into a slice
-	var names []string
-	for _, symbol := range symbols {
-		names = append(names, symbol.SymbolNameWithParent())
-	}
-	slog.Debug("Names", "names", names)
-	// Perform fuzzy matching on names
-	matchedNames := fuzzy.FindFold(query, names)
-
-	// Collect the symbols that match the names
-	for _, matchedName := range matchedNames {
-		for _, symbol := range symbols {
-			if symbol.SymbolNameWithParent() == matchedName {
-				filteredSymbols = append(filteredSymbols, symbol)
-				break
-			}
-		}
-	}
-
-	return filteredSymbols, nil
+	return rankSymbols(symbols, query), nil
 }
 
 func getTreeSitterQuery() string {
@@ -333,8 +326,8 @@ func resolveExternalSuperMethodSymbol(f *PythonFile, symbol *Symbol) *Symbol {
 	var superObject *Symbol
 	if len(classSymbol.SuperObjects) > 0 {
 		for _, superClassMethod := range classSymbol.SuperObjects[0].Children {
-			if superClassMethod.Name == symbol.Name {
-				symbol.SuperObjects = append(symbol.SuperObjects, superClassMethod)
+			if superClassMethod.Name == symbol.Name && !slices.Contains(symbol.SuperObjects, superClassMethod) {
+				f.workspace.linkSuperObject(symbol, superClassMethod)
 				symbol.superObjectsNames = append(symbol.superObjectsNames, superClassMethod.Name)
 				superObject = superClassMethod
 
@@ -345,62 +338,70 @@ func resolveExternalSuperMethodSymbol(f *PythonFile, symbol *Symbol) *Symbol {
 }
 
 func resolveExternalSuperclassSymbol(f *PythonFile, symbol *Symbol) *Symbol {
+	if symbol.Kind != messages.SymbolKindClass {
+		return symbol
+	}
+	for _, superClassName := range symbol.superObjectsNames {
+		superObject := resolveSuperclassName(f, superClassName)
+		if superObject != nil {
+			f.workspace.linkSuperObject(symbol, superObject)
+		}
+	}
+	return symbol
+}
+
+// resolveSuperclassName finds the class a base class name refers to, first in
+// the same file and then through the file imports.
+func resolveSuperclassName(f *PythonFile, superClassName string) *Symbol {
 	// Trey to find symbol inside the same file
 	fileSymbols, err := f.FileSymbols("")
 	if err != nil {
 		slog.Warn("Unable to get symbols for resolving superclass", slog.String("file", f.Url), "error", err)
-		return symbol
+		return nil
 	}
 	for _, fsymb := range fileSymbols {
-		for _, superClassName := range symbol.superObjectsNames {
-			if fsymb.Name == superClassName {
-				symbol.SuperObjects = append(symbol.SuperObjects, fsymb)
-				return symbol
-			}
+		if fsymb.Name == superClassName {
+			return fsymb
 		}
 	}
 	// Try to find symbol in the import
 	imports, err := f.GetImports()
 	if err != nil {
 		slog.Warn("Unable to get import for resolving superclass", slog.String("file", f.Url), "error", err)
-		return symbol
+		return nil
 	}
 	for _, imp := range imports {
-		for _, superClassName := range symbol.superObjectsNames {
-			if imp.ImportedName == superClassName && imp.Symbol != nil {
-				symbol.SuperObjects = append(symbol.SuperObjects, imp.Symbol)
-				return symbol
-			}
+		if imp.BoundName() == superClassName && imp.Symbol != nil {
+			return imp.Symbol
 		}
 	}
-	return symbol
+	return nil
 }
 
 func processSymbols(pythonFile *PythonFile, qc *tree_sitter.QueryCursor, query *tree_sitter.Query) []*Symbol {
 	classSymbols := map[string]*Symbol{} // Store classes by name and name range
 	moduleSymbols := []*Symbol{}         // Store standalone functions
-	matches := qc.Matches(query, pythonFile.GetOrCreateAst(), []byte(pythonFile.Text))
+	ast := pythonFile.GetOrCreateAst()
+	defer ast.Close()
+	source, encoding := ast.Source, pythonFile.positionEncoding()
+	matches := qc.Matches(query, ast.Root, source)
 	for match := matches.Next(); match != nil; match = matches.Next() {
 
 		var name, params, returnType string
 		var superClass string
+		var superClassRange messages.Range
+		var definition *tree_sitter.Node
 		var kind messages.SymbolKind
 		var startPos, endPos, nameStartPos, nameEndPos messages.Position
 		for _, capture := range match.Captures {
 			captureName := query.CaptureNames()[capture.Index]
-			captureText := capture.Node.Utf8Text([]byte(pythonFile.Text))
+			captureText := capture.Node.Utf8Text(source)
 
 			switch captureName {
 			case "class.name", "method.name", "function.name":
 				name = captureText
-				nameStartPos = messages.Position{
-					Line:      messages.UInteger(capture.Node.StartPosition().Row),
-					Character: messages.UInteger(capture.Node.StartPosition().Column),
-				}
-				nameEndPos = messages.Position{
-					Line:      messages.UInteger(capture.Node.EndPosition().Row),
-					Character: messages.UInteger(capture.Node.EndPosition().Column),
-				}
+				nameRange := nodeRange(&capture.Node, source, encoding)
+				nameStartPos, nameEndPos = nameRange.Start, nameRange.End
 				if captureName == "class.name" {
 					kind = messages.SymbolKindClass
 				} else if captureName == "method.name" {
@@ -408,21 +409,17 @@ func processSymbols(pythonFile *PythonFile, qc *tree_sitter.QueryCursor, query *
 				} else {
 					kind = messages.SymbolKindFunction
 				}
+				definition = capture.Node.Parent()
 			case "function.params", "method.params":
 				params = captureText
 			case "class.superclass":
 				superClass = captureText
+				superClassRange = nodeRange(&capture.Node, source, encoding)
 			case "function.return_type", "method.return_type":
 				returnType = captureText
 			case "function.body", "class.body", "method.body":
-				startPos = messages.Position{
-					Line:      messages.UInteger(capture.Node.StartPosition().Row),
-					Character: messages.UInteger(capture.Node.StartPosition().Column),
-				}
-				endPos = messages.Position{
-					Line:      messages.UInteger(capture.Node.EndPosition().Row),
-					Character: messages.UInteger(capture.Node.EndPosition().Column),
-				}
+				bodyRange := nodeRange(&capture.Node, source, encoding)
+				startPos, endPos = bodyRange.Start, bodyRange.End
 			}
 		}
 		if name == "" {
@@ -434,6 +431,7 @@ func processSymbols(pythonFile *PythonFile, qc *tree_sitter.QueryCursor, query *
 		}
 		if kind == messages.SymbolKindMethod {
 			newSymbol := createSymbol(name, kind, params, returnType, fullName, pythonFile, startPos, endPos, nameStartPos, nameEndPos, "")
+			setSignature(newSymbol, definition, source, encoding)
 			for _, classSymbol := range classSymbols {
 				if isChildOf(newSymbol, classSymbol) {
 					newSymbol.Parent = classSymbol
@@ -447,13 +445,18 @@ func processSymbols(pythonFile *PythonFile, qc *tree_sitter.QueryCursor, query *
 			// Create new symbol if it doesn't exist
 			if !exists {
 				newSymbol := createSymbol(name, kind, params, returnType, fullName, pythonFile, startPos, endPos, nameStartPos, nameEndPos, superClass)
+				if superClass != "" {
+					newSymbol.superObjectsRanges = append(newSymbol.superObjectsRanges, superClassRange)
+				}
 				classSymbols[key] = newSymbol
 			} else if superClass != "" {
 				// Update existing symbol with superclass
 				symbol.superObjectsNames = append(symbol.superObjectsNames, superClass)
+				symbol.superObjectsRanges = append(symbol.superObjectsRanges, superClassRange)
 			}
 		} else {
 			newSymbol := createSymbol(name, kind, params, returnType, fullName, pythonFile, startPos, endPos, nameStartPos, nameEndPos, "")
+			setSignature(newSymbol, definition, source, encoding)
 			moduleSymbols = append(moduleSymbols, newSymbol)
 		}
 	}
@@ -467,37 +470,15 @@ func processSymbols(pythonFile *PythonFile, qc *tree_sitter.QueryCursor, query *
 	return symbols
 }
 
-func updateSymbolsInPlace(existingSymbols []*Symbol, newSymbols []*Symbol) {
-	// Create a map of new symbols by name and position for quick lookup
-	newSymbolMap := make(map[string]*Symbol)
-	for _, newSymbol := range newSymbols {
-		key := fmt.Sprintf("%s:%d:%d", newSymbol.Name, newSymbol.NameRange.Start.Line, newSymbol.NameRange.Start.Character)
-		newSymbolMap[key] = newSymbol
-	}
-
-	// Update existing symbols with new data
-	for _, existingSymbol := range existingSymbols {
-		key := fmt.Sprintf("%s:%d:%d", existingSymbol.Name, existingSymbol.NameRange.Start.Line, existingSymbol.NameRange.Start.Character)
-		if newSymbol, found := newSymbolMap[key]; found {
-			// Update all fields except UUID to preserve references
-			existingSymbol.Name = newSymbol.Name
-			existingSymbol.Kind = newSymbol.Kind
-			existingSymbol.Parameters = newSymbol.Parameters
-			existingSymbol.ReturnType = newSymbol.ReturnType
-			existingSymbol.FullName = newSymbol.FullName
-			existingSymbol.Range = newSymbol.Range
-			existingSymbol.NameRange = newSymbol.NameRange
-			existingSymbol.superObjectsNames = newSymbol.superObjectsNames
-
-			// Update children recursively
-			updateSymbolsInPlace(existingSymbol.Children, newSymbol.Children)
-
-			// Update parent relationships for children
-			for _, child := range existingSymbol.Children {
-				child.Parent = existingSymbol
-			}
-		}
+// setSignature fills the parsed signature of a function or method symbol from
+// its function_definition node.
+func setSignature(symbol *Symbol, definition *tree_sitter.Node, source []byte, encoding messages.PositionEncodingKind) {
+	if definition == nil || definition.Kind() != "function_definition" {
+		return
 	}
+	symbol.Params = parseParameters(definition.ChildByFieldName("parameters"), source, encoding)
+	symbol.Decorators = functionDecorators(definition, source)
+	symbol.Async = isAsyncFunction(definition)
 }
 
 func isChildOf(symbol *Symbol, class *Symbol) bool {
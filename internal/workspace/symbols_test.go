@@ -1,11 +1,12 @@
 package workspace
 
 import (
+	"context"
 	"testing"
 
-	"github.com/elliotchance/orderedmap/v3"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"snakelsp/internal/messages"
 )
 
@@ -38,8 +39,9 @@ def multiple_decoration_function(param3):
 
 	// Create a mock PythonFile
 	mockFile := &PythonFile{
-		Text: pythonCode,
-		Url:  "mock_file.py",
+		workspace: newTestWorkspace(),
+		Text:      pythonCode,
+		Url:       "mock_file.py",
 	}
 
 	// Parse symbols
@@ -64,9 +66,10 @@ def multiple_decoration_function(param3):
 
 func TestParseSymbolsExternalFile(t *testing.T) {
 	mockFile := &PythonFile{
-		Text:     "def test(): pass",
-		Url:      "external.py",
-		External: true,
+		workspace: newTestWorkspace(),
+		Text:      "def test(): pass",
+		Url:       "external.py",
+		External:  true,
 	}
 
 	_, err := mockFile.parseSymbols()
@@ -76,8 +79,9 @@ func TestParseSymbolsExternalFile(t *testing.T) {
 
 func TestParseSymbolsEmptyFile(t *testing.T) {
 	mockFile := &PythonFile{
-		Text: "",
-		Url:  "empty.py",
+		workspace: newTestWorkspace(),
+		Text:      "",
+		Url:       "empty.py",
 	}
 
 	symbols, err := mockFile.parseSymbols()
@@ -101,8 +105,9 @@ class MultipleInheritance(BaseClass, object):
 `
 
 	mockFile := &PythonFile{
-		Text: pythonCode,
-		Url:  "inheritance.py",
+		workspace: newTestWorkspace(),
+		Text:      pythonCode,
+		Url:       "inheritance.py",
 	}
 
 	symbols, err := mockFile.parseSymbols()
@@ -135,8 +140,9 @@ class TypedClass:
 `
 
 	mockFile := &PythonFile{
-		Text: pythonCode,
-		Url:  "typed.py",
+		workspace: newTestWorkspace(),
+		Text:      pythonCode,
+		Url:       "typed.py",
 	}
 
 	symbols, err := mockFile.parseSymbols()
@@ -183,8 +189,9 @@ def module_function():
 `
 
 	mockFile := &PythonFile{
-		Text: pythonCode,
-		Url:  "complex.py",
+		workspace: newTestWorkspace(),
+		Text:      pythonCode,
+		Url:       "complex.py",
 	}
 
 	symbols, err := mockFile.parseSymbols()
@@ -225,8 +232,7 @@ def module_function():
 }
 
 func TestSearchSymbolByUUID(t *testing.T) {
-	// Clear FlatSymbols for clean test
-	FlatSymbols = orderedmap.NewOrderedMap[uuid.UUID, *Symbol]()
+	w := newTestWorkspace()
 
 	// Create test symbol
 	testSymbol := &Symbol{
@@ -234,46 +240,44 @@ func TestSearchSymbolByUUID(t *testing.T) {
 		Name: "TestSymbol",
 		Kind: messages.SymbolKindFunction,
 	}
-	FlatSymbols.Set(testSymbol.UUID, testSymbol)
+	w.addFlatSymbols([]*Symbol{testSymbol})
 
 	// Test successful search
-	found, err := SearchSymbolByUUID(testSymbol.UUID)
+	found, err := w.SearchSymbolByUUID(testSymbol.UUID)
 	assert.NoError(t, err)
 	assert.Equal(t, testSymbol.Name, found.Name)
 
 	// Test unsuccessful search
 	nonExistentUUID := uuid.New()
-	_, err = SearchSymbolByUUID(nonExistentUUID)
+	_, err = w.SearchSymbolByUUID(nonExistentUUID)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "symbol not found")
 }
 
 func TestGetWorkspaceSymbols(t *testing.T) {
-	// Clear FlatSymbols for clean test
-	FlatSymbols = orderedmap.NewOrderedMap[uuid.UUID, *Symbol]()
+	w := newTestWorkspace()
 
 	// Add test symbols
 	symbol1 := &Symbol{UUID: uuid.New(), Name: "TestFunction", Kind: messages.SymbolKindFunction}
 	symbol2 := &Symbol{UUID: uuid.New(), Name: "TestClass", Kind: messages.SymbolKindClass}
-	FlatSymbols.Set(symbol1.UUID, symbol1)
-	FlatSymbols.Set(symbol2.UUID, symbol2)
+	w.addFlatSymbols([]*Symbol{symbol1})
+	w.addFlatSymbols([]*Symbol{symbol2})
 
 	// Test without query
-	symbols, err := GetWorkspaceSymbols("")
+	symbols, err := w.GetWorkspaceSymbols(context.Background(), "")
 	assert.NoError(t, err)
 	assert.Len(t, symbols, 2)
 
 	// Test with query
-	symbols, err = GetWorkspaceSymbols("Test")
+	symbols, err = w.GetWorkspaceSymbols(context.Background(), "Test")
 	assert.NoError(t, err)
 	assert.GreaterOrEqual(t, len(symbols), 0)
 }
 
 func TestFindSymbolByPosition(t *testing.T) {
-	// Clear FlatSymbols for clean test
-	FlatSymbols = orderedmap.NewOrderedMap[uuid.UUID, *Symbol]()
+	w := newTestWorkspace()
 
-	mockFile := &PythonFile{Url: "test.py"}
+	mockFile := &PythonFile{workspace: w, Url: "test.py"}
 	testSymbol := &Symbol{
 		UUID: uuid.New(),
 		Name: "TestSymbol",
@@ -283,7 +287,8 @@ func TestFindSymbolByPosition(t *testing.T) {
 			End:   messages.Position{Line: 5, Character: 20},
 		},
 	}
-	FlatSymbols.Set(testSymbol.UUID, testSymbol)
+	w.addFlatSymbols([]*Symbol{testSymbol})
+	w.Symbols.Store(mockFile, []*Symbol{testSymbol})
 
 	// Test successful find
 	found, err := FindSymbolByPosition(mockFile, 5, 15)
@@ -296,6 +301,71 @@ func TestFindSymbolByPosition(t *testing.T) {
 	assert.Contains(t, err.Error(), "symbol not found")
 }
 
+func TestFindSymbolByPositionInnermost(t *testing.T) {
+	mockFile := &PythonFile{workspace: newTestWorkspace(), Url: "nested.py"}
+	multiLine := &Symbol{
+		Name: "MultiLine",
+		File: mockFile,
+		NameRange: messages.Range{
+			Start: messages.Position{Line: 1, Character: 30},
+			End:   messages.Position{Line: 3, Character: 5},
+		},
+	}
+	inner := &Symbol{
+		Name: "inner",
+		File: mockFile,
+		NameRange: messages.Range{
+			Start: messages.Position{Line: 2, Character: 4},
+			End:   messages.Position{Line: 2, Character: 9},
+		},
+	}
+	multiLine.Children = []*Symbol{inner}
+	other := &Symbol{
+		Name: "other",
+		File: mockFile,
+		NameRange: messages.Range{
+			Start: messages.Position{Line: 8, Character: 4},
+			End:   messages.Position{Line: 8, Character: 9},
+		},
+	}
+	mockFile.workspace.Symbols.Store(mockFile, []*Symbol{other, multiLine})
+
+	cases := []struct {
+		line, character uint32
+		expected        *Symbol
+	}{
+		{1, 30, multiLine},
+		{1, 2, nil},
+		{2, 0, multiLine}, // Left of the start column, on a line in between
+		{2, 6, inner},
+		{3, 5, multiLine},
+		{3, 6, nil},
+		{8, 9, other},
+		{9, 0, nil},
+	}
+	for _, c := range cases {
+		found, err := FindSymbolByPosition(mockFile, c.line, c.character)
+		if c.expected == nil {
+			assert.Error(t, err, "%d:%d", c.line, c.character)
+			continue
+		}
+		require.NoError(t, err, "%d:%d", c.line, c.character)
+		assert.Same(t, c.expected, found, "%d:%d", c.line, c.character)
+	}
+
+	// Symbols stored again are indexed again
+	moved := *other
+	moved.UUID = uuid.New()
+	moved.NameRange.Start.Line, moved.NameRange.End.Line = 10, 10
+	mockFile.forgetSymbols()
+	storeSymbols(mockFile, []*Symbol{&moved})
+	found, err := FindSymbolByPosition(mockFile, 10, 5)
+	require.NoError(t, err)
+	assert.Same(t, &moved, found)
+	_, err = FindSymbolByPosition(mockFile, 8, 5)
+	assert.Error(t, err)
+}
+
 func TestSymbolNameWithParent(t *testing.T) {
 	parentSymbol := &Symbol{Name: "ParentClass"}
 	childSymbol := &Symbol{
@@ -325,8 +395,9 @@ class TestClass:
 `
 
 	mockFile := &PythonFile{
-		Text: pythonCode,
-		Url:  "file_symbols_test.py",
+		workspace: newTestWorkspace(),
+		Text:      pythonCode,
+		Url:       "file_symbols_test.py",
 	}
 
 	// Test without query
@@ -341,28 +412,34 @@ class TestClass:
 }
 
 func TestFilterSymbols(t *testing.T) {
-	symbols := []*Symbol{
-		{Name: "TestFunction", Kind: messages.SymbolKindFunction},
-		{Name: "AnotherFunction", Kind: messages.SymbolKindFunction},
-		{Name: "TestClass", Kind: messages.SymbolKindClass},
-	}
+	testFunction := &Symbol{Name: "TestFunction", Kind: messages.SymbolKindFunction}
+	anotherFunction := &Symbol{Name: "AnotherFunction", Kind: messages.SymbolKindFunction}
+	testClass := &Symbol{Name: "TestClass", Kind: messages.SymbolKindClass}
+	symbols := []*Symbol{testFunction, anotherFunction, testClass}
 
-	// Test filtering with query
+	// Prefix matches, the shorter name first
 	filtered, err := filterSymbols(symbols, "Test")
 	assert.NoError(t, err)
-	assert.GreaterOrEqual(t, len(filtered), 2)
+	assert.Equal(t, []*Symbol{testClass, testFunction}, filtered)
 
-	// Test filtering with empty query - fuzzy search returns all items for empty query
+	// An empty query matches every name, shorter names first
 	filtered, err = filterSymbols(symbols, "")
 	assert.NoError(t, err)
-	assert.Len(t, filtered, 3) // fuzzy.FindFold returns all items for empty query
+	assert.Equal(t, []*Symbol{testClass, testFunction, anotherFunction}, filtered)
 
-	// Test filtering with specific match
+	// Only the exact match, the others don't contain the query
 	filtered, err = filterSymbols(symbols, "TestFunction")
 	assert.NoError(t, err)
-	if len(filtered) > 0 {
-		assert.Equal(t, "TestFunction", filtered[0].Name)
-	}
+	assert.Equal(t, []*Symbol{testFunction}, filtered)
+
+	// Word boundaries before other subsequences
+	filtered, err = filterSymbols(symbols, "tf")
+	assert.NoError(t, err)
+	assert.Equal(t, []*Symbol{testFunction, anotherFunction}, filtered)
+
+	filtered, err = filterSymbols(symbols, "xyz")
+	assert.NoError(t, err)
+	assert.Empty(t, filtered)
 }
 
 func TestCreateSymbol(t *testing.T) {
@@ -0,0 +1,169 @@
+package workspace
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"snakelsp/internal/messages"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+var identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// UnusedImport is an import whose name is never used in the file, together
+// with the edit removing it.
+type UnusedImport struct {
+	Import     Import
+	Diagnostic messages.Diagnostic
+	Edit       messages.TextEdit
+}
+
+// nameReferences holds names referenced in a file, split by where they
+// appear: in code, in string annotations and in any string.
+type nameReferences struct {
+	code        map[string]bool
+	annotations map[string]bool
+	strings     map[string]bool
+}
+
+// UnusedImports returns imports never referenced in the file. Names listed in
+// `__all__`, explicit re-exports (`import a as a`) and everything imported in
+// `__init__.py` count as used, and imports under `if TYPE_CHECKING:` are
+// also matched against quoted annotations.
+func (f *PythonFile) UnusedImports() []UnusedImport {
+	unused := []UnusedImport{}
+	if filepath.Base(f.Url) == "__init__.py" {
+		return unused
+	}
+	imports, err := f.GetImports()
+	if err != nil {
+		slog.Warn("Unable to get imports for unused imports", slog.String("file", f.Url), slog.Any("error", err))
+		return unused
+	}
+	ast := f.GetOrCreateAst()
+	defer ast.Close()
+	root, source := ast.Root, ast.Source
+	references := nameReferences{
+		code:        map[string]bool{},
+		annotations: map[string]bool{},
+		strings:     map[string]bool{},
+	}
+	collectReferences(root, source, &references, false)
+	exported := exportedNames(root, source)
+
+	for _, imp := range imports {
+		name := imp.BoundName()
+		if imp.SourceModule == "__future__" || isExplicitReexport(imp) {
+			continue
+		}
+		if exported[name] || references.code[name] || references.annotations[name] || (imp.TypeChecking && references.strings[name]) {
+			continue
+		}
+		unused = append(unused, UnusedImport{
+			Import: imp,
+			Diagnostic: messages.Diagnostic{
+				Range:    imp.BindingRange,
+				Severity: messages.DiagnosticSeverityHint,
+				Code:     DiagnosticCodeUnusedImport,
+				Source:   diagnosticsSource,
+				Message:  fmt.Sprintf("%q is imported but never used", name),
+				Tags:     []messages.DiagnosticTag{messages.DiagnosticTagUnnecessary},
+			},
+			Edit: messages.TextEdit{Range: imp.RemovalRange},
+		})
+	}
+	return unused
+}
+
+func unusedImportDiagnostics(f *PythonFile) []messages.Diagnostic {
+	diagnostics := []messages.Diagnostic{}
+	for _, unused := range f.UnusedImports() {
+		diagnostics = append(diagnostics, unused.Diagnostic)
+	}
+	return diagnostics
+}
+
+// isExplicitReexport matches the `import a as a` / `from x import a as a`
+// convention marking a name as re-exported.
+func isExplicitReexport(imp Import) bool {
+	if imp.Alias == "" {
+		return false
+	}
+	name := imp.ImportedName
+	if name == "" {
+		name = imp.SourceModule
+	}
+	return imp.Alias == name
+}
+
+func collectReferences(node *tree_sitter.Node, source []byte, references *nameReferences, inAnnotation bool) {
+	switch node.Kind() {
+	case "import_statement", "import_from_statement", "future_import_statement":
+		return
+	case "identifier":
+		references.code[node.Utf8Text(source)] = true
+		return
+	case "string":
+		for _, word := range identifierPattern.FindAllString(node.Utf8Text(source), -1) {
+			references.strings[word] = true
+			if inAnnotation {
+				references.annotations[word] = true
+			}
+		}
+		return
+	case "type":
+		inAnnotation = true
+	case "attribute":
+		// Only the object is a reference, `os` in `self.os` isn't.
+		if object := node.ChildByFieldName("object"); object != nil {
+			collectReferences(object, source, references, inAnnotation)
+		}
+		return
+	case "keyword_argument":
+		if value := node.ChildByFieldName("value"); value != nil {
+			collectReferences(value, source, references, inAnnotation)
+		}
+		return
+	}
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		collectReferences(node.NamedChild(i), source, references, inAnnotation)
+	}
+}
+
+// exportedNames returns the names listed in the module level `__all__`.
+func exportedNames(root *tree_sitter.Node, source []byte) map[string]bool {
+	exported := map[string]bool{}
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		statement := root.NamedChild(i)
+		if statement.Kind() != "expression_statement" || statement.NamedChildCount() == 0 {
+			continue
+		}
+		assignment := statement.NamedChild(0)
+		if assignment.Kind() != "assignment" && assignment.Kind() != "augmented_assignment" {
+			continue
+		}
+		left, right := assignment.ChildByFieldName("left"), assignment.ChildByFieldName("right")
+		if left == nil || right == nil || left.Utf8Text(source) != "__all__" {
+			continue
+		}
+		for j := uint(0); j < right.NamedChildCount(); j++ {
+			if item := right.NamedChild(j); item.Kind() == "string" {
+				exported[stringLiteralValue(item, source)] = true
+			}
+		}
+	}
+	return exported
+}
+
+func stringLiteralValue(node *tree_sitter.Node, source []byte) string {
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		if child := node.NamedChild(i); child.Kind() == "string_content" {
+			return child.Utf8Text(source)
+		}
+	}
+	return strings.Trim(node.Utf8Text(source), `'"`)
+}
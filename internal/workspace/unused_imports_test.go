@@ -0,0 +1,128 @@
+package workspace
+
+import (
+	"path/filepath"
+	"testing"
+
+	"snakelsp/internal/messages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnusedImports(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"pkg/__init__.py": "",
+		"pkg/mod.py":      "class Base:\n    pass\n\nclass Other:\n    pass\n",
+	})
+	mockFile := &PythonFile{
+		workspace: w,
+		Url:       "file://" + filepath.Join(root, "main.py"),
+		Text: `from __future__ import annotations
+import os
+import sys  # platform checks
+from typing import TYPE_CHECKING, Optional
+from pkg.mod import Base, Other
+from pkg import mod as mod
+import json
+
+if TYPE_CHECKING:
+    from pkg.mod import Other as Hinted
+
+__all__ = ["json"]
+
+
+def func(value: Optional["Hinted"]) -> None:
+    print(sys.argv, value.os)
+`,
+	}
+
+	unused := mockFile.UnusedImports()
+	names := []string{}
+	for _, u := range unused {
+		names = append(names, u.Import.BoundName())
+		assert.Equal(t, DiagnosticCodeUnusedImport, u.Diagnostic.Code)
+		assert.Equal(t, messages.DiagnosticSeverityHint, u.Diagnostic.Severity)
+		assert.Equal(t, []messages.DiagnosticTag{messages.DiagnosticTagUnnecessary}, u.Diagnostic.Tags)
+	}
+	assert.Equal(t, []string{"os", "Base", "Other"}, names)
+
+	require.Len(t, unused, 3)
+	// `import os` is alone on its line, so the whole line goes.
+	assert.Equal(t, messages.Range{
+		Start: messages.Position{Line: 1, Character: 0},
+		End:   messages.Position{Line: 2, Character: 0},
+	}, unused[0].Edit.Range)
+	assert.Equal(t, messages.Range{
+		Start: messages.Position{Line: 4, Character: 20},
+		End:   messages.Position{Line: 4, Character: 24},
+	}, unused[1].Diagnostic.Range)
+
+	initFile := &PythonFile{
+		workspace: w,
+		Url:       "file://" + filepath.Join(root, "pkg", "__init__.py"),
+		Text:      "from pkg.mod import Base\n",
+	}
+	assert.Empty(t, initFile.UnusedImports())
+}
+
+func TestDuplicateDefinitions(t *testing.T) {
+	mockFile := &PythonFile{
+		workspace: newTestWorkspace(),
+		Url:       "duplicates.py",
+		Text: `from typing import overload
+
+
+def func():
+    pass
+
+
+def func():
+    pass
+
+
+class Model:
+    def save(self):
+        pass
+
+    def save(self, force):
+        pass
+
+    @property
+    def name(self):
+        return ""
+
+    @name.setter
+    def name(self, value):
+        pass
+
+    @overload
+    def get(self, key: int) -> int: ...
+
+    @overload
+    def get(self, key: str) -> str: ...
+
+    def get(self, key):
+        pass
+
+
+class Model:
+    pass
+`,
+	}
+
+	diagnostics := duplicateDefinitionDiagnostics(mockFile)
+	lines := []uint32{}
+	for _, diagnostic := range diagnostics {
+		lines = append(lines, diagnostic.Range.Start.Line)
+		assert.Equal(t, DiagnosticCodeDuplicateDefinition, diagnostic.Code)
+		require.Len(t, diagnostic.RelatedInformation, 1)
+	}
+	assert.ElementsMatch(t, []uint32{3, 12, 11}, lines)
+
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Range.Start.Line == 3 {
+			assert.Equal(t, uint32(7), diagnostic.RelatedInformation[0].Location.Range.Start.Line)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package workspace
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"snakelsp/internal/messages"
+)
+
+// WatchedFilesGlobs are the patterns of files whose changes on disk have to
+// be reindexed, or the folders reloaded for project configs and ignore files.
+var WatchedFilesGlobs = []string{"**/*.py", "**/*.pyi", "**/" + pyprojectFile, "**/" + projectConfigFile, "**/" + gitignoreFile}
+
+func isPythonSource(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".py" || ext == ".pyi"
+}
+
+func isWatchedFile(path string) bool {
+	name := filepath.Base(path)
+	return isPythonSource(path) || name == pyprojectFile || name == projectConfigFile || name == gitignoreFile
+}
+
+// isExternalModulePath reports whether the path is in one of the module
+// paths other than the folder sources, e.g. site-packages.
+func (w *Workspace) isExternalModulePath(path string) bool {
+	for _, modulesPath := range w.allModulesPaths() {
+		if !w.isSourceRoot(modulesPath) && strings.HasPrefix(path, modulesPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isExcludedPath reports whether the path is outside of the workspace folders,
+// in their virtualenv or ignored by them.
+func (w *Workspace) isExcludedPath(path string) bool {
+	folder := w.FolderOf(path)
+	if folder == nil {
+		return true
+	}
+	if venv := folder.VirtualEnvPath; venv != "" && (path == venv || strings.HasPrefix(path, venv+"/")) {
+		return true
+	}
+	return folder.ignores(path, false)
+}
+
+// IsProjectFile reports whether the file is one of the project files of a
+// workspace folder, rather than an external one.
+func (w *Workspace) IsProjectFile(uri string) bool {
+	path := strings.TrimPrefix(uri, "file://")
+	return !w.isExternalModulePath(path) && !w.isExcludedPath(path)
+}
+
+// ApplyFileEvents brings the index in line with files created, changed or
+// deleted on disk. Files opened in the editor are left alone, their content
+// comes from the editor. Files importing the changed ones are relinked and
+// diagnostics of everything involved are published once at the end.
+//
+// Events in site-packages only refresh the external files already loaded,
+// and retry the imports that failed when something is installed. Changes of
+// a project config or of a .gitignore file reload its folder first.
+func (w *Workspace) ApplyFileEvents(events []messages.FileEvent) {
+	w.reloadProjectConfigs(events)
+	w.fileEventsMutex.Lock()
+	defer w.fileEventsMutex.Unlock()
+	changed := []*PythonFile{}
+	deleted := []string{}
+	affected := map[*PythonFile]bool{}
+	created := false
+	for _, event := range events {
+		path := strings.TrimPrefix(event.URI, "file://")
+		external := w.isExternalModulePath(path)
+		if !external && w.isExcludedPath(path) {
+			continue
+		}
+		switch event.Type {
+		case messages.FileChangeTypeDeleted:
+			for _, file := range w.filesUnderPath(path) {
+				dependents := file.Dependents()
+				if !file.removeClosed() {
+					continue
+				}
+				for _, dependent := range dependents {
+					affected[dependent] = true
+				}
+				deleted = append(deleted, file.Url)
+			}
+		case messages.FileChangeTypeCreated, messages.FileChangeTypeChanged:
+			if !isPythonSource(path) {
+				continue
+			}
+			created = created || event.Type == messages.FileChangeTypeCreated
+			file, err := w.reloadFromDisk(path, external)
+			if err != nil {
+				slog.Warn("Unable to reload file", slog.String("path", path), slog.Any("error", err))
+				continue
+			}
+			if file != nil {
+				changed = append(changed, file)
+			}
+		}
+	}
+	if len(changed) == 0 && len(deleted) == 0 {
+		return
+	}
+	slog.Debug("Reindexing files changed on disk", slog.Int("changed", len(changed)), slog.Int("deleted", len(deleted)))
+
+	for _, file := range changed {
+		file.parseAst()
+		if file.External {
+			// Parsed again lazily, when something resolves to the file.
+			file.setImports(nil)
+			file.forgetSymbols()
+			continue
+		}
+		file.ParseImports()
+		file.parseSymbols()
+	}
+	for _, file := range changed {
+		for _, dependent := range file.Dependents() {
+			affected[dependent] = true
+		}
+	}
+	if created {
+		// New files can satisfy imports that failed so far.
+		for _, file := range w.filesWithUnresolvedImports() {
+			affected[file] = true
+		}
+	}
+	for _, file := range changed {
+		delete(affected, file)
+	}
+	dependents := []*PythonFile{}
+	for file := range affected {
+		if _, err := w.GetPythonFile(file.Url); err != nil {
+			continue
+		}
+		dependents = append(dependents, file)
+	}
+	for _, dependent := range dependents {
+		dependent.ParseImports()
+		dependent.relinkSymbols()
+	}
+
+	for _, url := range deleted {
+		if !w.isExternalModulePath(strings.TrimPrefix(url, "file://")) {
+			w.clearDiagnostics(url)
+		}
+	}
+	for _, file := range changed {
+		file.PublishDiagnostics()
+	}
+	for _, dependent := range dependents {
+		dependent.PublishDiagnostics()
+	}
+	if len(dependents) > 0 || len(deleted) > 0 {
+		w.RefreshDiagnostics()
+	}
+}
+
+// reloadProjectConfigs reloads the folders whose project config or
+// .gitignore files are among the changed files.
+func (w *Workspace) reloadProjectConfigs(events []messages.FileEvent) {
+	configured := []*WorkspaceFolder{}
+	ignored := []*WorkspaceFolder{}
+	for _, event := range events {
+		path := strings.TrimPrefix(event.URI, "file://")
+		folder := w.FolderOf(path)
+		switch {
+		case folder == nil:
+		case w.isProjectConfigFile(path) && !slices.Contains(configured, folder):
+			configured = append(configured, folder)
+			slog.Info("Project config changed", slog.String("path", path))
+		case filepath.Base(path) == gitignoreFile && !slices.Contains(ignored, folder):
+			ignored = append(ignored, folder)
+			slog.Info("Ignore file changed", slog.String("path", path))
+		}
+	}
+	if len(configured) > 0 {
+		w.reloadFolders(configured, false)
+	}
+	if len(ignored) > 0 {
+		// Folders replaced by the reload of their config are read again.
+		w.reloadFolders(slices.DeleteFunc(ignored, func(f *WorkspaceFolder) bool { return !slices.Contains(w.Folders(), f) }), true)
+	}
+}
+
+// RemoveWorkspaceFolder drops a folder from the workspace with its project
+// files. Files of other folders importing the dropped ones are relinked.
+func (w *Workspace) RemoveWorkspaceFolder(uri string) {
+	folder := w.removeWorkspaceFolder(uri)
+	if folder == nil {
+		return
+	}
+	w.fileEventsMutex.Lock()
+	defer w.fileEventsMutex.Unlock()
+	files := []*PythonFile{}
+	for _, file := range w.filesUnderPath(folder.Root) {
+		// Files of a nested folder stay in the workspace.
+		if !file.External && w.FolderOf(strings.TrimPrefix(file.Url, "file://")) == nil {
+			files = append(files, file)
+		}
+	}
+	removed := w.dropProjectFiles(files)
+	slog.Info("Workspace folder removed", slog.String("root", folder.Root), slog.Int("files", removed))
+	w.RefreshDiagnostics()
+}
+
+// dropProjectFiles removes project files from the index and clears their
+// diagnostics. Files still opened in the editor are kept as external ones,
+// project files importing the dropped ones are relinked. It returns the
+// number of files removed.
+func (w *Workspace) dropProjectFiles(files []*PythonFile) int {
+	affected := map[*PythonFile]bool{}
+	removed := []*PythonFile{}
+	for _, file := range files {
+		for _, dependent := range file.Dependents() {
+			affected[dependent] = true
+		}
+		w.clearDiagnostics(file.Url)
+		if file.removeClosed() {
+			removed = append(removed, file)
+			continue
+		}
+		file.astMutex.Lock()
+		file.External = true
+		file.astMutex.Unlock()
+	}
+	for _, file := range files {
+		delete(affected, file)
+	}
+	for dependent := range affected {
+		if _, err := w.GetPythonFile(dependent.Url); err != nil || dependent.External {
+			continue
+		}
+		dependent.ParseImports()
+		dependent.relinkSymbols()
+		dependent.PublishDiagnostics()
+	}
+	return len(removed)
+}
+
+// reloadFromDisk reads the file content into the workspace files. A nil file without
+// an error means there is nothing to reindex: the file is opened in the
+// editor, its content didn't change or it's an external file nothing has
+// loaded yet.
+func (w *Workspace) reloadFromDisk(path string, external bool) (*PythonFile, error) {
+	url := "file://" + path
+	file, err := w.GetPythonFile(url)
+	if err != nil && external {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	created := file == nil
+	if created {
+		// Opened in the editor in the meantime when the stored file isn't
+		// the new one.
+		file = w.NewPythonFile(url, string(content), false, false)
+	}
+	file.astMutex.Lock()
+	defer file.astMutex.Unlock()
+	// The content of opened files comes from the editor, the disk one is
+	// only the saved version.
+	if file.isOpened {
+		return nil, nil
+	}
+	file.stamp = newFileStamp(info, content)
+	if created {
+		return file, nil
+	}
+	if file.Text == string(content) && file.External == external {
+		return nil, nil
+	}
+	// Project files first seen through an import are external, now they are
+	// part of the project.
+	file.External = external
+	file.replaceTextLocked(string(content))
+	return file, nil
+}
+
+// filesUnderPath returns the project file at path, or every project file in
+// it when path is a deleted folder.
+func (w *Workspace) filesUnderPath(path string) []*PythonFile {
+	files := []*PythonFile{}
+	url := "file://" + path
+	w.Files.Range(func(key, value any) bool {
+		if fileUrl := key.(string); fileUrl == url || strings.HasPrefix(fileUrl, url+"/") {
+			files = append(files, value.(*PythonFile))
+		}
+		return true
+	})
+	return files
+}
+
+func (w *Workspace) filesWithUnresolvedImports() []*PythonFile {
+	files := []*PythonFile{}
+	w.Files.Range(func(key, value any) bool {
+		file := value.(*PythonFile)
+		if file.External {
+			return true
+		}
+		for _, imp := range file.loadedImports() {
+			var moduleErr *ModuleNotFoundError
+			var nameErr *ImportedNameError
+			if errors.As(imp.ResolveError, &moduleErr) || errors.As(imp.ResolveError, &nameErr) {
+				files = append(files, file)
+				break
+			}
+		}
+		return true
+	})
+	return files
+}
+
+// remove drops the file and its symbols from the index.
+func (f *PythonFile) remove() {
+	f.astMutex.Lock()
+	f.removeLocked()
+	f.astMutex.Unlock()
+	f.forgetSymbols()
+	f.setImports(nil)
+}
+
+// removeClosed removes the file unless it's opened in the editor, checked
+// under the lock OpenFile marks it under. It reports whether it removed it.
+func (f *PythonFile) removeClosed() bool {
+	f.astMutex.Lock()
+	if f.isOpened {
+		f.astMutex.Unlock()
+		return false
+	}
+	f.removeLocked()
+	f.astMutex.Unlock()
+	f.forgetSymbols()
+	f.setImports(nil)
+	return true
+}
+
+// removeLocked drops the file from the workspace files and releases its
+// tree.
+func (f *PythonFile) removeLocked() {
+	f.workspace.Files.CompareAndDelete(f.Url, f)
+	f.astTree.Close()
+	f.astTree = nil
+	f.astRoot = nil
+}
+
+func (w *Workspace) clearDiagnostics(url string) {
+	if w.diagnosticsPublisher == nil {
+		return
+	}
+	w.diagnosticsPublisher(messages.PublishDiagnosticsParams{
+		URI:         url,
+		Diagnostics: []messages.Diagnostic{},
+	})
+}
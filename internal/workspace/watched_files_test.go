@@ -0,0 +1,124 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"snakelsp/internal/messages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyFileEvents(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"base.py":  "class Base:\n    pass\n",
+		"child.py": "from base import Base\n\nclass Child(Base):\n    pass\n",
+	})
+	basePath, childPath := filepath.Join(root, "base.py"), filepath.Join(root, "child.py")
+	base, err := w.ImportPythonFileFromFile(basePath, false)
+	require.NoError(t, err)
+	child, err := w.ImportPythonFileFromFile(childPath, false)
+	require.NoError(t, err)
+	_, err = base.parseSymbols()
+	require.NoError(t, err)
+	_, err = child.ParseImports()
+	require.NoError(t, err)
+	_, err = child.parseSymbols()
+	require.NoError(t, err)
+	require.Empty(t, child.Diagnostics())
+
+	published := map[string][]messages.Diagnostic{}
+	w.SetDiagnosticsPublisher(func(params messages.PublishDiagnosticsParams) {
+		published[params.URI] = params.Diagnostics
+	})
+
+	// Deleted
+	symbols, err := base.FileSymbols("")
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(basePath))
+	w.ApplyFileEvents([]messages.FileEvent{{URI: base.Url, Type: messages.FileChangeTypeDeleted}})
+	_, err = w.GetPythonFile(base.Url)
+	assert.Error(t, err)
+	_, exists := w.flatSymbol(symbols[0].UUID)
+	assert.False(t, exists)
+	assert.Empty(t, published[base.Url])
+	assert.Equal(t, []string{DiagnosticCodeUnresolvedImport, DiagnosticCodeUnresolvedBaseClass}, diagnosticCodes(child))
+	assert.Len(t, published[child.Url], 2)
+
+	// Created
+	require.NoError(t, os.WriteFile(basePath, []byte("class Base:\n    pass\n"), 0o644))
+	w.ApplyFileEvents([]messages.FileEvent{{URI: base.Url, Type: messages.FileChangeTypeCreated}})
+	created, err := w.GetPythonFile(base.Url)
+	require.NoError(t, err)
+	assert.False(t, created.External)
+	assert.Empty(t, child.Diagnostics())
+	assert.Empty(t, published[child.Url])
+
+	// Changed
+	require.NoError(t, os.WriteFile(basePath, []byte("class Renamed:\n    pass\n"), 0o644))
+	w.ApplyFileEvents([]messages.FileEvent{{URI: base.Url, Type: messages.FileChangeTypeChanged}})
+	assert.Equal(t, []string{DiagnosticCodeUnknownImportSymbol, DiagnosticCodeUnresolvedBaseClass}, diagnosticCodes(child))
+	renamed, err := created.FileSymbols("")
+	require.NoError(t, err)
+	require.Len(t, renamed, 1)
+	assert.Equal(t, "Renamed", renamed[0].Name)
+}
+
+func TestApplyFileEventsSkipsExcludedPaths(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		".venv/lib/module.py": "class Hidden:\n    pass\n",
+		"other.txt":           "",
+	})
+	w.ApplyFileEvents([]messages.FileEvent{
+		{URI: "file://" + filepath.Join(root, ".venv/lib/module.py"), Type: messages.FileChangeTypeCreated},
+		{URI: "file://" + filepath.Join(root, "other.txt"), Type: messages.FileChangeTypeCreated},
+		{URI: "file:///elsewhere/module.py", Type: messages.FileChangeTypeCreated},
+	})
+	_, err := w.GetPythonFile("file://" + filepath.Join(root, ".venv/lib/module.py"))
+	assert.Error(t, err)
+	_, err = w.GetPythonFile("file://" + filepath.Join(root, "other.txt"))
+	assert.Error(t, err)
+}
+
+func TestApplyFileEventsKeepsOpenedFiles(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{
+		"app.py": "class App:\n    pass\n",
+	})
+	path := filepath.Join(root, "app.py")
+	file := w.OpenFile("file://"+path, "class Unsaved:\n    pass\n", 1, false)
+
+	require.NoError(t, os.WriteFile(path, []byte("class Formatted:\n    pass\n"), 0o644))
+	w.ApplyFileEvents([]messages.FileEvent{{URI: file.Url, Type: messages.FileChangeTypeChanged}})
+	assert.Equal(t, "class Unsaved:\n    pass\n", file.Text)
+}
+
+func TestDeleteEventsWhileOpening(t *testing.T) {
+	w, root := setupModulesPath(t, map[string]string{})
+	url := "file://" + filepath.Join(root, "app.py")
+	for range 50 {
+		w.NewPythonFile(url, "x = 1\n", false, false)
+		var wg sync.WaitGroup
+		var opened *PythonFile
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			opened = w.OpenFile(url, "x = 2\n", 1, false)
+		}()
+		go func() {
+			defer wg.Done()
+			w.ApplyFileEvents([]messages.FileEvent{{URI: url, Type: messages.FileChangeTypeDeleted}})
+		}()
+		wg.Wait()
+
+		// Whichever came first, the opened file stays in the workspace
+		stored, err := w.GetPythonFile(url)
+		require.NoError(t, err)
+		assert.Same(t, opened, stored)
+		assert.Equal(t, "x = 2\n", stored.Text)
+		stored.CloseFile()
+		stored.remove()
+	}
+}
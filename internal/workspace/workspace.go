@@ -0,0 +1,123 @@
+package workspace
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"snakelsp/internal/messages"
+	"snakelsp/internal/progress"
+	"snakelsp/pkg/watcher"
+
+	"github.com/elliotchance/orderedmap/v3"
+	"github.com/google/uuid"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Workspace is the state of a client session: the files of its folders and
+// of the libraries they import, their symbols and imports, and the settings
+// sent by the client. Workspaces share nothing but the index caches.
+type Workspace struct {
+	Files   sync.Map // Project files, also 3rd libraries files, by URL
+	Symbols sync.Map // Symbols of each *PythonFile
+
+	// flatSymbols are the project symbols and their children, by UUID, in
+	// the order they were indexed. Indexing, reparsing and requests use it
+	// concurrently, it's only read and written through the methods holding
+	// flatSymbolsMutex.
+	flatSymbols      *orderedmap.OrderedMap[uuid.UUID, *Symbol]
+	flatSymbolsMutex sync.RWMutex
+
+	// How the client counts characters in positions, negotiated in
+	// `initialize`. Tree-sitter columns are bytes, every position sent or
+	// received goes through it.
+	positionEncoding messages.PositionEncodingKind
+	indexCacheDir    string // Where the index cache is written, caching is disabled when empty
+
+	indexWorkers int // Workers indexing at once
+
+	// Parser shared by the parses of single files, index workers have their
+	// own.
+	parser      *tree_sitter.Parser
+	parserMutex sync.Mutex
+
+	// Folders are replaced, never modified, so readers can keep the slice
+	// they got while folders are added or removed.
+	settings      ClientSettingsType
+	settingsMutex sync.RWMutex
+
+	// subObjects is the reverse of SuperObjects: the classes directly
+	// deriving from a class and the methods directly overriding a method.
+	// It's kept in line by linkSuperObject and unlinkSuperObjects.
+	subObjects      map[*Symbol][]*Symbol
+	subObjectsMutex sync.RWMutex
+
+	libraries            atomic.Pointer[libraryIndex]
+	libraryIndexProgress func() *progress.WorkDone
+
+	diagnosticsPublisher DiagnosticsPublisher
+	diagnosticsRefresher DiagnosticsRefresher
+
+	// Serializes reindexing, events come from both the editor and the
+	// watcher.
+	fileEventsMutex sync.Mutex
+
+	fileWatcher      *watcher.Watcher
+	fileWatcherMutex sync.Mutex // Held while the watcher starts or stops
+	collectorDone    chan struct{}
+}
+
+// NewWorkspace creates the workspace of the folders, each one with the
+// module paths of its virtualenv.
+func NewWorkspace(settings Settings, folders []messages.WorkspaceFolder) *Workspace {
+	w := &Workspace{
+		flatSymbols:      orderedmap.NewOrderedMap[uuid.UUID, *Symbol](),
+		positionEncoding: messages.PositionEncodingKindUTF16,
+		indexWorkers:     runtime.GOMAXPROCS(0),
+		settings:         ClientSettingsType{Settings: settings},
+		subObjects:       map[*Symbol][]*Symbol{},
+	}
+	w.libraries.Store(newLibraryIndex())
+	for _, folder := range folders {
+		w.settings.Folders = append(w.settings.Folders, newWorkspaceFolder(&w.settings, folder.Name, strings.TrimPrefix(folder.URI, "file://")))
+	}
+	return w
+}
+
+// ProjectSymbols returns the project symbols and their children, in the
+// order they were indexed.
+func (w *Workspace) ProjectSymbols() []*Symbol {
+	w.flatSymbolsMutex.RLock()
+	defer w.flatSymbolsMutex.RUnlock()
+	return slices.Collect(w.flatSymbols.Values())
+}
+
+func (w *Workspace) flatSymbol(uuid uuid.UUID) (*Symbol, bool) {
+	w.flatSymbolsMutex.RLock()
+	defer w.flatSymbolsMutex.RUnlock()
+	return w.flatSymbols.Get(uuid)
+}
+
+// addFlatSymbols indexes the symbols of a file and their children.
+func (w *Workspace) addFlatSymbols(symbols []*Symbol) {
+	w.flatSymbolsMutex.Lock()
+	defer w.flatSymbolsMutex.Unlock()
+	for _, symbol := range symbols {
+		w.flatSymbols.Set(symbol.UUID, symbol)
+		for _, children := range symbol.Children {
+			w.flatSymbols.Set(children.UUID, children)
+		}
+	}
+}
+
+// forgetFlatSymbol drops the symbol from the index, unless its UUID was
+// taken over by another symbol, e.g. the copy of a moved file.
+func (w *Workspace) forgetFlatSymbol(symbol *Symbol) {
+	w.flatSymbolsMutex.Lock()
+	defer w.flatSymbolsMutex.Unlock()
+	if current, ok := w.flatSymbols.Get(symbol.UUID); ok && current == symbol {
+		w.flatSymbols.Delete(symbol.UUID)
+	}
+}
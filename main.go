@@ -56,7 +56,6 @@ func initializeSentry() {
 
 func main() {
 	initializeSentry()
-	go debug_server.StartHTTPServer("127.0.0.1:8051")
 	stdio := server.NewStdio(nil, nil)
 	f, err := initializeLogs()
 	if err != nil {
@@ -65,5 +64,6 @@ func main() {
 	}
 	defer f.Close()
 	srv := server.NewServer(f)
+	go debug_server.StartHTTPServer("127.0.0.1:8051", srv.Workspace)
 	srv.RunStdio(stdio)
 }
@@ -34,6 +34,15 @@ func (m *Debouncer) Debounce(callback func()) {
 	m.timer.Reset(m.timeout)
 }
 
+// Stop cancels the pending call and reports whether there was one. The next
+// Debounce schedules the call again.
+func (m *Debouncer) Stop() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.timer != nil && m.timer.Stop()
+}
+
 func (m *Debouncer) UpdateDebounceCallback(callback func()) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -0,0 +1,27 @@
+// Package watcher provides a recursive file system watcher reporting
+// created, written and removed files under a set of root directories.
+// It's backed by inotify and only available on Linux.
+package watcher
+
+import "errors"
+
+type Op int
+
+const (
+	Create Op = iota
+	Write
+	Remove
+	// Overflow means events were dropped, watched trees have to be rescanned.
+	Overflow
+)
+
+type Event struct {
+	Path string
+	Op   Op
+}
+
+// SkipFunc tells the watcher to leave a directory, and everything in it,
+// unwatched. It's never called for the roots.
+type SkipFunc func(path string) bool
+
+var ErrUnsupported = errors.New("file watching is not supported on this platform")
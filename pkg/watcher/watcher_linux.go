@@ -0,0 +1,173 @@
+//go:build linux
+
+package watcher
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const watchMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ONLYDIR
+
+type Watcher struct {
+	Events chan Event
+
+	fd    int
+	file  *os.File
+	skip  SkipFunc
+	mutex sync.Mutex
+	paths map[int32]string // Watch descriptors to directories
+	done  chan struct{}
+}
+
+// New watches roots recursively. Events is buffered with queueSize entries,
+// when the consumer falls behind the kernel queue fills up and an Overflow
+// event is sent instead of the lost ones.
+func New(roots []string, skip SkipFunc, queueSize int) (*Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		Events: make(chan Event, queueSize),
+		fd:     fd,
+		// Non-blocking, so reads go through the runtime poller and Close
+		// interrupts them.
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		skip:  skip,
+		paths: map[int32]string{},
+		done:  make(chan struct{}),
+	}
+	for _, root := range roots {
+		w.addTree(root, false)
+	}
+	go w.readEvents()
+	return w, nil
+}
+
+func (w *Watcher) Close() error {
+	select {
+	case <-w.done:
+		return nil
+	default:
+	}
+	close(w.done)
+	return w.file.Close()
+}
+
+// addTree watches dir and its subdirectories. Files found in directories
+// created after the watch started are reported, they could have been written
+// before their directory was watched.
+func (w *Watcher) addTree(dir string, created bool) {
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !entry.IsDir() {
+			if created {
+				w.send(Event{Path: path, Op: Create})
+			}
+			return nil
+		}
+		if path != dir && w.skip != nil && w.skip(path) {
+			return filepath.SkipDir
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, path, watchMask)
+		if err != nil {
+			if errors.Is(err, syscall.ENOSPC) {
+				slog.Warn("Out of inotify watches, raise fs.inotify.max_user_watches", slog.String("path", path))
+				return filepath.SkipAll
+			}
+			return nil
+		}
+		w.mutex.Lock()
+		w.paths[int32(wd)] = path
+		w.mutex.Unlock()
+		return nil
+	})
+}
+
+// removeTree forgets the watches of dir and its subdirectories, after it
+// was moved away.
+func (w *Watcher) removeTree(dir string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for wd, path := range w.paths {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.paths, wd)
+		}
+	}
+}
+
+func (w *Watcher) send(event Event) {
+	select {
+	case w.Events <- event:
+	case <-w.done:
+	}
+}
+
+func (w *Watcher) readEvents() {
+	defer close(w.Events)
+	buffer := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buffer)
+		if err != nil {
+			select {
+			case <-w.done:
+			default:
+				slog.Error("Error reading inotify events", slog.Any("error", err))
+			}
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			name := strings.TrimRight(string(buffer[offset+syscall.SizeofInotifyEvent:offset+syscall.SizeofInotifyEvent+int(raw.Len)]), "\x00")
+			offset += syscall.SizeofInotifyEvent + int(raw.Len)
+			w.handle(raw.Wd, raw.Mask, name)
+		}
+	}
+}
+
+func (w *Watcher) handle(wd int32, mask uint32, name string) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		w.send(Event{Op: Overflow})
+		return
+	}
+	w.mutex.Lock()
+	dir, ok := w.paths[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(w.paths, wd)
+	}
+	w.mutex.Unlock()
+	if !ok || name == "" {
+		return
+	}
+	path := filepath.Join(dir, name)
+	isDir := mask&syscall.IN_ISDIR != 0
+	switch {
+	case mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		if isDir {
+			if w.skip == nil || !w.skip(path) {
+				w.addTree(path, true)
+			}
+			return
+		}
+		w.send(Event{Path: path, Op: Create})
+	case mask&syscall.IN_CLOSE_WRITE != 0:
+		w.send(Event{Path: path, Op: Write})
+	case mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+		if isDir {
+			w.removeTree(path)
+		}
+		w.send(Event{Path: path, Op: Remove})
+	}
+}
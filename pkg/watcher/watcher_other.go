@@ -0,0 +1,15 @@
+//go:build !linux
+
+package watcher
+
+type Watcher struct {
+	Events chan Event
+}
+
+func New(roots []string, skip SkipFunc, queueSize int) (*Watcher, error) {
+	return nil, ErrUnsupported
+}
+
+func (w *Watcher) Close() error {
+	return nil
+}
@@ -10,9 +10,9 @@ import (
 func (s *Server) newStreamConnection(stream io.ReadWriteCloser) *jsonrpc2.Conn {
 	handler := s.newHandler()
 	connectionOptions := s.newConnectionOptions()
-	context, cancel := context.WithTimeout(context.Background(), s.timeout)
-	defer cancel()
-	return jsonrpc2.NewConn(context, jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}), handler, connectionOptions...)
+	// The context lives as long as the connection: handlers keep using it to
+	// send requests to the client after they have returned.
+	return jsonrpc2.NewConn(context.Background(), jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}), handler, connectionOptions...)
 }
 
 func (s *Server) newConnectionOptions() []jsonrpc2.ConnOpt {
@@ -2,8 +2,8 @@ package server
 
 import (
 	"context"
-	"log"
 
+	"snakelsp/internal/messages"
 	"snakelsp/internal/protocol"
 	"snakelsp/internal/request"
 
@@ -11,15 +11,24 @@ import (
 )
 
 func (s *Server) handle(ctx context.Context, c *jsonrpc2.Conn, r *jsonrpc2.Request) (any, error) {
+	// The client context outlives the request, for the work it starts.
+	requestCtx := ctx
+	if !r.Notif {
+		var done func()
+		requestCtx, done = s.inFlight.Start(ctx, r.ID)
+		defer done()
+	}
 	context := request.Request{
 		Method:  r.Method,
-		Context: ctx,
+		Context: requestCtx,
 		Client: &request.Client{
 			Conn:    c,
 			Request: r,
 			Context: ctx,
 		},
-		Logger: s.logger,
+		Logger:   s.logger,
+		InFlight: s.inFlight,
+		Session:  s.session,
 	}
 	if r.Params != nil {
 		context.Params = *r.Params
@@ -41,19 +50,45 @@ func (s *Server) handle(ctx context.Context, c *jsonrpc2.Conn, r *jsonrpc2.Reque
 			}
 			return nil, err
 		}
+		if r.Method != "initialize" && s.session.Workspace() == nil {
+			// Notifications sent before `initialize` are dropped.
+			return nil, &jsonrpc2.Error{Code: messages.ErrorCodeServerNotInitialized, Message: "Server not initialized"}
+		}
 		result, err := handler(&context)
+		if requestCtx.Err() != nil {
+			return nil, &jsonrpc2.Error{Code: messages.ErrorCodeRequestCancelled, Message: "Request cancelled"}
+		}
 		if err != nil {
-			err := &jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: err.Error()}
-			if err := c.ReplyWithError(ctx, r.ID, err); err != nil {
-				return nil, err
-			}
-			return result, nil
-
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInternalError, Message: err.Error()}
 		}
 		return result, nil
 	}
 }
 
+// Document synchronization notifications are handled in the order they
+// were sent, one change applied before the previous one would corrupt the
+// text. Other messages are handled concurrently.
+var orderedMethods = map[string]bool{
+	"textDocument/didOpen":   true,
+	"textDocument/didChange": true,
+	"textDocument/didSave":   true,
+	"textDocument/didClose":  true,
+}
+
+// orderedHandler handles document synchronization notifications before the
+// next message is read, and the others in their own goroutine.
+type orderedHandler struct {
+	jsonrpc2.Handler
+}
+
+func (h orderedHandler) Handle(ctx context.Context, c *jsonrpc2.Conn, r *jsonrpc2.Request) {
+	if orderedMethods[r.Method] {
+		h.Handler.Handle(ctx, c, r)
+		return
+	}
+	go h.Handler.Handle(ctx, c, r)
+}
+
 func (s *Server) newHandler() jsonrpc2.Handler {
-	return jsonrpc2.AsyncHandler(jsonrpc2.HandlerWithError(s.handle))
+	return orderedHandler{jsonrpc2.HandlerWithError(s.handle)}
 }
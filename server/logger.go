@@ -75,7 +75,7 @@ func (h *CustomTextHandler) WithGroup(name string) slog.Handler {
 	return h
 }
 
-func createLogger(writer io.Writer, debug bool) *slog.Logger {
+func createLogger(writer io.Writer, debug bool) (*slog.Logger, *slog.LevelVar) {
 	programLevel := new(slog.LevelVar)
 	handler := NewCustomTextHandler(writer, programLevel)
 	if debug {
@@ -83,5 +83,5 @@ func createLogger(writer io.Writer, debug bool) *slog.Logger {
 	}
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
-	return logger
+	return logger, programLevel
 }
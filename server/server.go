@@ -7,6 +7,9 @@ import (
 	"io"
 	"log/slog"
 	"time"
+
+	"snakelsp/internal/request"
+	"snakelsp/internal/workspace"
 )
 
 const (
@@ -20,16 +23,27 @@ type Server struct {
 	timeout      time.Duration
 	readTimeout  time.Duration
 	writeTimeout time.Duration
+
+	inFlight *request.InFlight
+	session  *request.Session
 }
 
 func NewServer(log_writter io.Writer) *Server {
 	debug := true
-	logger := createLogger(log_writter, debug)
+	logger, logLevel := createLogger(log_writter, debug)
 	return &Server{
 		debug:        true,
 		logger:       logger,
 		timeout:      defaultTimeout,
 		readTimeout:  defaultTimeout,
 		writeTimeout: defaultTimeout,
+		inFlight:     request.NewInFlight(),
+		session:      request.NewSession(logLevel),
 	}
 }
+
+// Workspace returns the workspace of the client session, nil until the
+// client sends `initialize`.
+func (s *Server) Workspace() *workspace.Workspace {
+	return s.session.Workspace()
+}